@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
@@ -38,6 +39,11 @@ func main() {
 				log.Logger = zerolog.Nop()
 				return nil
 			}
+			// A JSON report or SARIF log written to stdout must be the only
+			// thing there.
+			if machineOutput(c.Args().Slice()) {
+				return nil
+			}
 			fmt.Println(banner.Inline("ghat"))
 			fmt.Println("version:", version.Version)
 			return nil
@@ -69,6 +75,13 @@ func main() {
 					}
 
 					myFlags.Exclude = c.String("exclude")
+					myFlags.GitLabURL = c.String("gitlab-url")
+					myFlags.GitLabToken = c.String("gitlab-token")
+					myFlags.Report = c.String("report")
+					myFlags.ReportFile = c.String("report-file")
+					myFlags.LedgerPath = ledgerPath(c)
+					myFlags.FailOnMovedTag = c.Bool("fail-on-moved-tag")
+					myFlags.Offline = c.Bool("offline")
 
 					return myFlags.Action("stun")
 				},
@@ -85,6 +98,11 @@ func main() {
 						Usage:    "regex pattern; matching scanned paths are skipped",
 						Category: "files",
 					},
+					&cli.BoolFlag{
+						Name:    "offline",
+						Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+						EnvVars: []string{"GHAT_OFFLINE"},
+					},
 					&cli.UintFlag{
 						Name:        "stable",
 						Aliases:     []string{"s"},
@@ -99,6 +117,39 @@ func main() {
 						Usage:   "show but don't write changes",
 						Value:   false,
 					},
+					&cli.StringFlag{
+						Name:    "gitlab-url",
+						Usage:   "GitLab instance that include: project entries live on",
+						Value:   "https://gitlab.com",
+						EnvVars: []string{"CI_SERVER_URL"},
+					},
+					&cli.StringFlag{
+						Name:     "gitlab-token",
+						Usage:    "GitLab PAT for resolving include: refs",
+						Category: "authentication",
+						EnvVars:  []string{"GITLAB_TOKEN"},
+					},
+					&cli.StringFlag{
+						Name:  "report",
+						Usage: "emit a machine-readable change report (json)",
+					},
+					&cli.StringFlag{
+						Name:  "report-file",
+						Usage: "write the --report output to this file instead of stdout",
+					},
+					&cli.StringFlag{
+						Name:    "ledger",
+						Usage:   "tag→SHA ledger used to detect tags moved between runs (default: ghat's config dir)",
+						EnvVars: []string{"GHAT_LEDGER"},
+					},
+					&cli.BoolFlag{
+						Name:  "no-ledger",
+						Usage: "don't record or check resolved tags in the ledger",
+					},
+					&cli.BoolFlag{
+						Name:  "fail-on-moved-tag",
+						Usage: "fail instead of warning when a known tag now resolves to a different SHA",
+					},
 				},
 			},
 			{
@@ -131,12 +182,23 @@ func main() {
 						Destination: &myFlags.Exclude,
 						Category:    "files",
 					},
+					&cli.BoolFlag{
+						Name:        "offline",
+						Usage:       "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+						EnvVars:     []string{"GHAT_OFFLINE"},
+						Destination: &myFlags.Offline,
+					},
 					&cli.BoolFlag{
 						Name:        "update",
 						Usage:       "update to latest module available",
 						Destination: &myFlags.Update,
 						Value:       false,
 					},
+					&cli.BoolFlag{
+						Name:        "keep-constraints",
+						Usage:       "bump registry modules' version constraints in their own style instead of pinning them to a commit",
+						Destination: &myFlags.KeepConstraints,
+					},
 					&cli.BoolFlag{
 						Name:        "dry-run",
 						Aliases:     []string{"dryrun"},
@@ -152,6 +214,16 @@ func main() {
 						Category:    "authentication",
 						EnvVars:     []string{"GITHUB_TOKEN", "GITHUB_API"},
 					},
+					&cli.StringFlag{
+						Name:        "report",
+						Usage:       "emit a machine-readable change report (json)",
+						Destination: &myFlags.Report,
+					},
+					&cli.StringFlag{
+						Name:        "report-file",
+						Usage:       "write the --report output to this file instead of stdout",
+						Destination: &myFlags.ReportFile,
+					},
 				},
 			},
 			{
@@ -159,7 +231,8 @@ func main() {
 				Aliases:   []string{"p"},
 				Usage:     "updates pre-commit version with hashes",
 				UsageText: "ghat sift",
-				Action: func(*cli.Context) error {
+				Action: func(c *cli.Context) error {
+					myFlags.LedgerPath = ledgerPath(c)
 					return myFlags.Action("sift")
 				},
 				Flags: []cli.Flag{
@@ -176,6 +249,12 @@ func main() {
 						Destination: &myFlags.Exclude,
 						Category:    "files",
 					},
+					&cli.BoolFlag{
+						Name:        "offline",
+						Usage:       "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+						EnvVars:     []string{"GHAT_OFFLINE"},
+						Destination: &myFlags.Offline,
+					},
 					&cli.BoolFlag{
 						Name:        "dry-run",
 						Aliases:     []string{"dryrun"},
@@ -191,15 +270,46 @@ func main() {
 						Category:    "authentication",
 						EnvVars:     []string{"GITHUB_TOKEN", "GITHUB_API"},
 					},
+					&cli.StringFlag{
+						Name:        "report",
+						Usage:       "emit a machine-readable change report (json)",
+						Destination: &myFlags.Report,
+					},
+					&cli.StringFlag{
+						Name:        "report-file",
+						Usage:       "write the --report output to this file instead of stdout",
+						Destination: &myFlags.ReportFile,
+					},
+					&cli.StringFlag{
+						Name:    "ledger",
+						Usage:   "tag→SHA ledger used to detect tags moved between runs (default: ghat's config dir)",
+						EnvVars: []string{"GHAT_LEDGER"},
+					},
+					&cli.BoolFlag{
+						Name:  "no-ledger",
+						Usage: "don't record or check resolved tags in the ledger",
+					},
+					&cli.BoolFlag{
+						Name:        "fail-on-moved-tag",
+						Usage:       "fail instead of warning when a known tag now resolves to a different SHA",
+						Destination: &myFlags.FailOnMovedTag,
+					},
 				},
 			},
 			shakeCmd,
 			cacheCmd,
+			ledgerCmd,
 			swotCmd,
 			kubeCmd,
 			dockCmd,
 			subCmd,
 			sweepCmd,
+			lintCmd,
+			permsCmd,
+			fixCmd,
+			lockCmd,
+			verifyLockCmd,
+			verifyCmd,
 			auditCmd,
 			orgCmd,
 			lspCmd,
@@ -240,6 +350,11 @@ var swotCmd = &cli.Command{
 			Name:  "exclude",
 			Usage: "regex pattern; matching scanned paths are skipped",
 		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
 		&cli.BoolFlag{
 			Name:    "dry-run",
 			Aliases: []string{"dryrun"},
@@ -271,6 +386,10 @@ var swotCmd = &cli.Command{
 			Name:  "pin-only",
 			Usage: "pin current tag to SHA without checking for upgrades",
 		},
+		&cli.BoolFlag{
+			Name:  "transitive",
+			Usage: "warn about unpinned actions and images that used actions pull in through their own action.yml",
+		},
 		&cli.BoolFlag{
 			Name:  "pr",
 			Usage: "commit changes to a branch and open a pull request; exits non-zero if changes were needed",
@@ -294,9 +413,41 @@ var swotCmd = &cli.Command{
 			Usage: "HTTP timeout for GitHub API calls (e.g. 30s, 2m)",
 			Value: 30 * time.Second,
 		},
+		&cli.StringFlag{
+			Name:    "github-api-url",
+			Usage:   "GitHub API root, for GitHub Enterprise Server (e.g. https://ghes.example.com/api/v3)",
+			EnvVars: []string{"GITHUB_API_URL"},
+		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "emit a machine-readable change report (json)",
+		},
+		&cli.StringFlag{
+			Name:  "report-file",
+			Usage: "write the --report output to this file instead of stdout",
+		},
+		&cli.StringFlag{
+			Name:    "ledger",
+			Usage:   "tag→SHA ledger used to detect tags moved between runs (default: ghat's config dir)",
+			EnvVars: []string{"GHAT_LEDGER"},
+		},
+		&cli.BoolFlag{
+			Name:  "no-ledger",
+			Usage: "don't record or check resolved tags in the ledger",
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-moved-tag",
+			Usage: "fail instead of warning when a known tag now resolves to a different SHA",
+		},
 	},
 	Action: func(c *cli.Context) error {
 		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
+		myFlags.Transitive = c.Bool("transitive")
+		myFlags.Report = c.String("report")
+		myFlags.ReportFile = c.String("report-file")
+		myFlags.LedgerPath = ledgerPath(c)
+		myFlags.FailOnMovedTag = c.Bool("fail-on-moved-tag")
 		myFlags.Directory = c.String("directory")
 		myFlags.File = c.String("file")
 		myFlags.Exclude = c.String("exclude")
@@ -305,6 +456,7 @@ var swotCmd = &cli.Command{
 		myFlags.PinOnly = c.Bool("pin-only")
 		myFlags.GitHubToken = githubToken()
 		myFlags.HTTPTimeout = c.Duration("timeout")
+		myFlags.GitHubAPIURL = c.String("github-api-url")
 
 		stable := c.Uint("stable")
 		myFlags.Days = &stable
@@ -388,6 +540,11 @@ var shakeCmd = &cli.Command{
 			Name:  "exclude",
 			Usage: "regex pattern; matching scanned paths are skipped",
 		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
 		&cli.BoolFlag{
 			Name:    "dry-run",
 			Aliases: []string{"dryrun"},
@@ -397,14 +554,40 @@ var shakeCmd = &cli.Command{
 			Name:  "continue-on-error",
 			Usage: "Continue processing files even if errors occur",
 		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "emit a machine-readable change report (json)",
+		},
+		&cli.StringFlag{
+			Name:  "report-file",
+			Usage: "write the --report output to this file instead of stdout",
+		},
+		&cli.StringSliceFlag{
+			Name:  "platform",
+			Usage: "os_arch platform to hash into .terraform.lock.hcl, repeatable (default: the one ghat runs on)",
+		},
+		&cli.BoolFlag{
+			Name:  "verify-lock",
+			Usage: "check, without network calls, that .terraform.lock.hcl matches the declared provider constraints",
+		},
+		&cli.BoolFlag{
+			Name:  "keep-constraints",
+			Usage: "bump version constraints in their own style (~> 5.0 to ~> 5.80) instead of pinning exact versions",
+		},
 	},
 	Action: func(c *cli.Context) error {
 		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
+		myFlags.Report = c.String("report")
+		myFlags.ReportFile = c.String("report-file")
 		myFlags.Directory = c.String("directory")
 		myFlags.File = c.String("file")
 		myFlags.Exclude = c.String("exclude")
 		myFlags.DryRun = c.Bool("dry-run")
 		myFlags.ContinueOnError = c.Bool("continue-on-error")
+		myFlags.LockPlatforms = c.StringSlice("platform")
+		myFlags.VerifyProviderLock = c.Bool("verify-lock")
+		myFlags.KeepConstraints = c.Bool("keep-constraints")
 
 		return myFlags.Action("shake")
 	},
@@ -478,6 +661,120 @@ var cacheCmd = &cli.Command{
 				return nil
 			},
 		},
+		{
+			Name:      "export",
+			Usage:     "Write the cache to a tar.gz archive, to prime an --offline machine",
+			UsageText: "ghat cache export <file|->",
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return fmt.Errorf("usage: ghat cache export <file|->")
+				}
+				cache, err := core.NewCache(24*time.Hour, true)
+				if err != nil {
+					return err
+				}
+
+				var w io.Writer = os.Stdout
+				if target := c.Args().First(); target != "-" {
+					file, err := os.Create(target)
+					if err != nil {
+						return fmt.Errorf("failed to create %s: %w", target, err)
+					}
+					defer file.Close() //nolint:errcheck
+					w = file
+				}
+
+				count, err := cache.Export(w)
+				if err != nil {
+					return fmt.Errorf("failed to export cache: %w", err)
+				}
+				log.Info().Int("entries", count).Msg("✓ Cache exported")
+				return nil
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "Add the entries of a ghat cache export archive to the cache",
+			UsageText: "ghat cache import <file|->",
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return fmt.Errorf("usage: ghat cache import <file|->")
+				}
+				cache, err := core.NewCache(24*time.Hour, true)
+				if err != nil {
+					return err
+				}
+
+				var r io.Reader = os.Stdin
+				if source := c.Args().First(); source != "-" {
+					file, err := os.Open(source)
+					if err != nil {
+						return fmt.Errorf("failed to open %s: %w", source, err)
+					}
+					defer file.Close() //nolint:errcheck
+					r = file
+				}
+
+				count, err := cache.Import(r)
+				if err != nil {
+					return fmt.Errorf("failed to import cache: %w", err)
+				}
+				log.Info().Int("entries", count).Msg("✓ Cache imported")
+				return nil
+			},
+		},
+	},
+}
+
+var ledgerCmd = &cli.Command{
+	Name:  "ledger",
+	Usage: "Manage the tag→SHA ledger of every tag ghat has resolved",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "verify",
+			Usage:     "re-resolve every ledger entry and fail if a tag has moved",
+			UsageText: "ghat ledger verify [--update]",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "ledger",
+					Usage:   "ledger file (default: ghat's config dir)",
+					EnvVars: []string{"GHAT_LEDGER"},
+				},
+				&cli.BoolFlag{
+					Name:  "update",
+					Usage: "accept moved tags, recording the SHA upstream now returns",
+				},
+				&cli.StringFlag{
+					Name:     "token",
+					Aliases:  []string{"t"},
+					Usage:    "GitHub PAT token",
+					Category: "authentication",
+					EnvVars:  []string{"GITHUB_TOKEN", "GITHUB_API"},
+				},
+				&cli.StringFlag{
+					Name:     "gitlab-token",
+					Usage:    "GitLab PAT for resolving include: refs",
+					Category: "authentication",
+					EnvVars:  []string{"GITLAB_TOKEN"},
+				},
+				&cli.StringFlag{
+					Name:    "github-api-url",
+					Usage:   "GitHub API root, for GitHub Enterprise Server (e.g. https://ghes.example.com/api/v3)",
+					EnvVars: []string{"GITHUB_API_URL"},
+				},
+			},
+			Action: func(c *cli.Context) error {
+				myFlags := core.NewFlags()
+				myFlags.LedgerPath = ledgerPath(c)
+				myFlags.GitHubToken = c.String("token")
+				myFlags.GitLabToken = c.String("gitlab-token")
+				if u := c.String("github-api-url"); u != "" {
+					core.SetGitHubAPIURL(u)
+				}
+
+				return myFlags.VerifyLedger(os.Stdout, c.Bool("update"))
+			},
+		},
 	},
 }
 
@@ -501,6 +798,11 @@ var kubeCmd = &cli.Command{
 			Name:  "exclude",
 			Usage: "regex pattern; matching scanned paths are skipped",
 		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
 		&cli.BoolFlag{
 			Name:    "dry-run",
 			Aliases: []string{"dryrun"},
@@ -510,9 +812,35 @@ var kubeCmd = &cli.Command{
 			Name:  "continue-on-error",
 			Usage: "continue processing files even if errors occur",
 		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "emit a machine-readable change report (json)",
+		},
+		&cli.StringFlag{
+			Name:  "report-file",
+			Usage: "write the --report output to this file instead of stdout",
+		},
+		&cli.StringFlag{
+			Name:    "ledger",
+			Usage:   "tag→SHA ledger used to detect tags moved between runs (default: ghat's config dir)",
+			EnvVars: []string{"GHAT_LEDGER"},
+		},
+		&cli.BoolFlag{
+			Name:  "no-ledger",
+			Usage: "don't record or check resolved tags in the ledger",
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-moved-tag",
+			Usage: "fail instead of warning when a known tag now resolves to a different SHA",
+		},
 	},
 	Action: func(c *cli.Context) error {
 		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
+		myFlags.Report = c.String("report")
+		myFlags.ReportFile = c.String("report-file")
+		myFlags.LedgerPath = ledgerPath(c)
+		myFlags.FailOnMovedTag = c.Bool("fail-on-moved-tag")
 		myFlags.Directory = c.String("directory")
 		myFlags.File = c.String("file")
 		myFlags.Exclude = c.String("exclude")
@@ -552,6 +880,11 @@ var dockCmd = &cli.Command{
 			Name:  "exclude",
 			Usage: "regex pattern; matching scanned paths are skipped",
 		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
 		&cli.BoolFlag{
 			Name:    "dry-run",
 			Aliases: []string{"dryrun"},
@@ -561,9 +894,35 @@ var dockCmd = &cli.Command{
 			Name:  "continue-on-error",
 			Usage: "continue processing files even if errors occur",
 		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "emit a machine-readable change report (json)",
+		},
+		&cli.StringFlag{
+			Name:  "report-file",
+			Usage: "write the --report output to this file instead of stdout",
+		},
+		&cli.StringFlag{
+			Name:    "ledger",
+			Usage:   "tag→SHA ledger used to detect tags moved between runs (default: ghat's config dir)",
+			EnvVars: []string{"GHAT_LEDGER"},
+		},
+		&cli.BoolFlag{
+			Name:  "no-ledger",
+			Usage: "don't record or check resolved tags in the ledger",
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-moved-tag",
+			Usage: "fail instead of warning when a known tag now resolves to a different SHA",
+		},
 	},
 	Action: func(c *cli.Context) error {
 		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
+		myFlags.Report = c.String("report")
+		myFlags.ReportFile = c.String("report-file")
+		myFlags.LedgerPath = ledgerPath(c)
+		myFlags.FailOnMovedTag = c.Bool("fail-on-moved-tag")
 		myFlags.Directory = c.String("directory")
 		myFlags.File = c.String("file")
 		myFlags.Exclude = c.String("exclude")
@@ -608,6 +967,11 @@ var subCmd = &cli.Command{
 			Name:  "exclude",
 			Usage: "regex pattern; matching scanned paths are skipped",
 		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
 		&cli.StringFlag{
 			Name:     "token",
 			Aliases:  []string{"t"},
@@ -615,9 +979,20 @@ var subCmd = &cli.Command{
 			Category: "authentication",
 			EnvVars:  []string{"GITHUB_TOKEN", "GITHUB_API"},
 		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "emit a machine-readable change report (json)",
+		},
+		&cli.StringFlag{
+			Name:  "report-file",
+			Usage: "write the --report output to this file instead of stdout",
+		},
 	},
 	Action: func(c *cli.Context) error {
 		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
+		myFlags.Report = c.String("report")
+		myFlags.ReportFile = c.String("report-file")
 		myFlags.Directory = c.String("directory")
 		myFlags.DryRun = c.Bool("dry-run")
 		myFlags.ContinueOnError = c.Bool("continue-on-error")
@@ -653,6 +1028,11 @@ var sweepCmd = &cli.Command{
 			Name:  "exclude",
 			Usage: "regex pattern; matching scanned paths are skipped",
 		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
 		&cli.UintFlag{
 			Name:  "stable",
 			Usage: "use releases from N days ago (more stable)",
@@ -694,6 +1074,18 @@ var sweepCmd = &cli.Command{
 			Usage: "branch name for the pinning PR",
 			Value: "ghat/pin-dependencies",
 		},
+		&cli.StringFlag{
+			Name:    "gitlab-url",
+			Usage:   "GitLab instance that include: project entries live on",
+			Value:   "https://gitlab.com",
+			EnvVars: []string{"CI_SERVER_URL"},
+		},
+		&cli.StringFlag{
+			Name:     "gitlab-token",
+			Usage:    "GitLab PAT for resolving include: refs",
+			Category: "authentication",
+			EnvVars:  []string{"GITLAB_TOKEN"},
+		},
 		&cli.StringFlag{
 			Name:     "pr-token",
 			Usage:    "PAT for creating PRs (defaults to $GITHUB_TOKEN)",
@@ -704,9 +1096,40 @@ var sweepCmd = &cli.Command{
 			Usage: "HTTP timeout for GitHub API calls (e.g. 30s, 2m)",
 			Value: 30 * time.Second,
 		},
+		&cli.StringFlag{
+			Name:    "github-api-url",
+			Usage:   "GitHub API root, for GitHub Enterprise Server (e.g. https://ghes.example.com/api/v3)",
+			EnvVars: []string{"GITHUB_API_URL"},
+		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "emit a machine-readable change report (json)",
+		},
+		&cli.StringFlag{
+			Name:  "report-file",
+			Usage: "write the --report output to this file instead of stdout",
+		},
+		&cli.StringFlag{
+			Name:    "ledger",
+			Usage:   "tag→SHA ledger used to detect tags moved between runs (default: ghat's config dir)",
+			EnvVars: []string{"GHAT_LEDGER"},
+		},
+		&cli.BoolFlag{
+			Name:  "no-ledger",
+			Usage: "don't record or check resolved tags in the ledger",
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-moved-tag",
+			Usage: "fail instead of warning when a known tag now resolves to a different SHA",
+		},
 	},
 	Action: func(c *cli.Context) error {
 		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
+		myFlags.Report = c.String("report")
+		myFlags.ReportFile = c.String("report-file")
+		myFlags.LedgerPath = ledgerPath(c)
+		myFlags.FailOnMovedTag = c.Bool("fail-on-moved-tag")
 		myFlags.Directory = c.String("directory")
 		myFlags.DryRun = c.Bool("dry-run")
 		myFlags.ContinueOnError = c.Bool("continue-on-error")
@@ -715,6 +1138,9 @@ var sweepCmd = &cli.Command{
 		myFlags.PinOnly = c.Bool("pin-only")
 		myFlags.GitHubToken = c.String("token")
 		myFlags.HTTPTimeout = c.Duration("timeout")
+		myFlags.GitHubAPIURL = c.String("github-api-url")
+		myFlags.GitLabURL = c.String("gitlab-url")
+		myFlags.GitLabToken = c.String("gitlab-token")
 
 		stable := c.Uint("stable")
 		myFlags.Days = &stable
@@ -764,7 +1190,7 @@ var auditCmd = &cli.Command{
 	Name:      "audit",
 	Aliases:   []string{"sc"},
 	Usage:     "scores your dependencies (go.mod, GHA uses:, pre-commit, Terraform, npm, PyPI, Cargo, RubyGems) on supply-chain hygiene",
-	UsageText: "ghat audit -d . [--source go,gha,pre-commit,terraform,npm,pypi,cargo,gem] [--deep]",
+	UsageText: "ghat audit -d . [--source go,gha,pre-commit,terraform,npm,pypi,cargo,gem] [--deep] [--transitive]",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:    "directory",
@@ -776,6 +1202,10 @@ var auditCmd = &cli.Command{
 			Name:  "deep",
 			Usage: "audit transitive Go dependencies (go list -m all) instead of direct only",
 		},
+		&cli.BoolFlag{
+			Name:  "transitive",
+			Usage: "follow used actions into their own action.yml, scoring what they pull in; also reads in-repo action.yml files",
+		},
 		&cli.StringSliceFlag{
 			Name:    "source",
 			Aliases: []string{"s"},
@@ -785,6 +1215,11 @@ var auditCmd = &cli.Command{
 			Name:  "exclude",
 			Usage: "regex pattern; matching scanned paths are skipped",
 		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
 		&cli.StringFlag{
 			Name:     "token",
 			Aliases:  []string{"t"},
@@ -806,17 +1241,37 @@ var auditCmd = &cli.Command{
 			Usage: "HTTP timeout for GitHub API calls (e.g. 30s, 2m)",
 			Value: 30 * time.Second,
 		},
+		&cli.StringFlag{
+			Name:    "github-api-url",
+			Usage:   "GitHub API root, for GitHub Enterprise Server (e.g. https://ghes.example.com/api/v3)",
+			EnvVars: []string{"GITHUB_API_URL"},
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: text or sarif (sarif also includes workflow, GitLab CI and Dockerfile findings)",
+			Value: "text",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write --format output to this file instead of stdout",
+		},
 	},
 	Action: func(c *cli.Context) error {
 		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
 		myFlags.Directory = c.String("directory")
 		myFlags.Deep = c.Bool("deep")
+		myFlags.Transitive = c.Bool("transitive")
 		myFlags.Sources = c.StringSlice("source")
 		myFlags.Exclude = c.String("exclude")
 		myFlags.GitHubToken = c.String("token")
 		myFlags.CacheEnabled = !c.Bool("no-cache")
 		myFlags.CacheTTL = c.Duration("cache-ttl")
 		myFlags.HTTPTimeout = c.Duration("timeout")
+		myFlags.GitHubAPIURL = c.String("github-api-url")
+		myFlags.Format = c.String("format")
+		myFlags.Output = c.String("output")
 
 		if err := myFlags.InitializeCache(); err != nil {
 			return fmt.Errorf("failed to initialize cache: %w", err)
@@ -826,6 +1281,321 @@ var auditCmd = &cli.Command{
 	},
 }
 
+var lintCmd = &cli.Command{
+	Name:      "lint",
+	Usage:     "reports unpinned dependencies and risky workflow settings without changing files or calling the network",
+	UsageText: "ghat lint -d . [--fail-on error|warning|note|none] [--format text|sarif]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "directory",
+			Aliases: []string{"d"},
+			Usage:   "directory to scan",
+			Value:   ".",
+		},
+		&cli.StringFlag{
+			Name:    "file",
+			Aliases: []string{"f"},
+			Usage:   "specific file to lint",
+		},
+		&cli.StringFlag{
+			Name:  "exclude",
+			Usage: "regex pattern; matching scanned paths are skipped",
+		},
+		&cli.StringFlag{
+			Name:  "fail-on",
+			Usage: "exit non-zero when a finding is at or above this level: error, warning, note or none",
+			Value: "warning",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: text or sarif",
+			Value: "text",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write output to this file instead of stdout",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		myFlags := core.NewFlags()
+		myFlags.Directory = c.String("directory")
+		myFlags.File = c.String("file")
+		myFlags.Exclude = c.String("exclude")
+		myFlags.FailOn = c.String("fail-on")
+		myFlags.Format = c.String("format")
+		myFlags.Output = c.String("output")
+
+		return myFlags.Action(core.ActionLint)
+	},
+}
+
+var permsCmd = &cli.Command{
+	Name:      "perms",
+	Usage:     "gives each workflow job a least-privilege permissions: block inferred from the actions and commands it runs",
+	UsageText: "ghat perms -d . [--tighten] [--dry-run]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "directory",
+			Aliases: []string{"d"},
+			Usage:   "directory to scan for workflow files",
+			Value:   ".",
+		},
+		&cli.StringFlag{
+			Name:    "file",
+			Aliases: []string{"f"},
+			Usage:   "specific workflow file to update",
+		},
+		&cli.StringFlag{
+			Name:  "exclude",
+			Usage: "regex pattern; matching scanned paths are skipped",
+		},
+		&cli.BoolFlag{
+			Name:  "tighten",
+			Usage: "also replace permissions: blocks jobs already declare",
+		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Aliases: []string{"dryrun"},
+			Usage:   "show changes without modifying files",
+		},
+		&cli.BoolFlag{
+			Name:  "continue-on-error",
+			Usage: "continue processing files even if errors occur",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		myFlags := core.NewFlags()
+		myFlags.Directory = c.String("directory")
+		myFlags.File = c.String("file")
+		myFlags.Exclude = c.String("exclude")
+		myFlags.Tighten = c.Bool("tighten")
+		myFlags.DryRun = c.Bool("dry-run")
+		myFlags.ContinueOnError = c.Bool("continue-on-error")
+
+		return myFlags.Action(core.ActionPerms)
+	},
+}
+
+var fixCmd = &cli.Command{
+	Name:      "fix",
+	Usage:     "rewrites workflow and action files to fix a class of lint findings",
+	UsageText: "ghat fix --rule injection -d . [--dry-run]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "rule",
+			Usage:    "findings to fix: injection moves github.event expressions in run: steps into env:",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    "directory",
+			Aliases: []string{"d"},
+			Usage:   "directory to scan for workflow and action files",
+			Value:   ".",
+		},
+		&cli.StringFlag{
+			Name:    "file",
+			Aliases: []string{"f"},
+			Usage:   "specific workflow or action file to fix",
+		},
+		&cli.StringFlag{
+			Name:  "exclude",
+			Usage: "regex pattern; matching scanned paths are skipped",
+		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Aliases: []string{"dryrun"},
+			Usage:   "show changes without modifying files",
+		},
+		&cli.BoolFlag{
+			Name:  "continue-on-error",
+			Usage: "continue processing files even if errors occur",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		myFlags := core.NewFlags()
+		myFlags.FixRule = c.String("rule")
+		myFlags.Directory = c.String("directory")
+		myFlags.File = c.String("file")
+		myFlags.Exclude = c.String("exclude")
+		myFlags.DryRun = c.Bool("dry-run")
+		myFlags.ContinueOnError = c.Bool("continue-on-error")
+
+		return myFlags.Action(core.ActionFix)
+	},
+}
+
+var lockCmd = &cli.Command{
+	Name:      "lock",
+	Usage:     "writes ghat.lock, recording every dependency ref and the SHA or digest it resolves to",
+	UsageText: "ghat lock -d .",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "directory",
+			Aliases: []string{"d"},
+			Usage:   "directory to scan",
+			Value:   ".",
+		},
+		&cli.StringFlag{
+			Name:  "exclude",
+			Usage: "regex pattern; matching scanned paths are skipped",
+		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
+		&cli.StringFlag{
+			Name:  "lockfile",
+			Usage: "lockfile to write (default: ghat.lock in the scanned directory)",
+		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Aliases: []string{"dryrun"},
+			Usage:   "print the lock instead of writing it",
+		},
+		&cli.StringFlag{
+			Name:     "token",
+			Aliases:  []string{"t"},
+			Usage:    "GitHub PAT token",
+			Category: "authentication",
+			EnvVars:  []string{"GITHUB_TOKEN", "GITHUB_API"},
+		},
+		&cli.StringFlag{
+			Name:    "gitlab-url",
+			Usage:   "GitLab instance that include: project entries live on",
+			Value:   "https://gitlab.com",
+			EnvVars: []string{"CI_SERVER_URL"},
+		},
+		&cli.StringFlag{
+			Name:     "gitlab-token",
+			Usage:    "GitLab PAT for resolving include: refs",
+			Category: "authentication",
+			EnvVars:  []string{"GITLAB_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "github-api-url",
+			Usage:   "GitHub API root, for GitHub Enterprise Server (e.g. https://ghes.example.com/api/v3)",
+			EnvVars: []string{"GITHUB_API_URL"},
+		},
+	},
+	Action: func(c *cli.Context) error {
+		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
+		myFlags.Directory = c.String("directory")
+		myFlags.Exclude = c.String("exclude")
+		myFlags.LockPath = c.String("lockfile")
+		myFlags.DryRun = c.Bool("dry-run")
+		myFlags.GitHubToken = c.String("token")
+		myFlags.GitLabURL = c.String("gitlab-url")
+		myFlags.GitLabToken = c.String("gitlab-token")
+		myFlags.GitHubAPIURL = c.String("github-api-url")
+
+		if err := myFlags.InitializeCache(); err != nil {
+			return fmt.Errorf("failed to initialize cache: %w", err)
+		}
+
+		return myFlags.Action(core.ActionLock)
+	},
+}
+
+var verifyLockCmd = &cli.Command{
+	Name:      "verify-lock",
+	Usage:     "checks, without network calls, that every dependency ref still matches ghat.lock",
+	UsageText: "ghat verify-lock -d .",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "directory",
+			Aliases: []string{"d"},
+			Usage:   "directory to scan",
+			Value:   ".",
+		},
+		&cli.StringFlag{
+			Name:  "exclude",
+			Usage: "regex pattern; matching scanned paths are skipped",
+		},
+		&cli.StringFlag{
+			Name:  "lockfile",
+			Usage: "lockfile to check against (default: ghat.lock in the scanned directory)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		myFlags := core.NewFlags()
+		myFlags.Directory = c.String("directory")
+		myFlags.Exclude = c.String("exclude")
+		myFlags.LockPath = c.String("lockfile")
+
+		return myFlags.Action(core.ActionVerifyLock)
+	},
+}
+
+var verifyCmd = &cli.Command{
+	Name:      "verify",
+	Usage:     "checks that pinned SHAs are on a branch or tag of their repository and match their # tag comment",
+	UsageText: "ghat verify -d . [--format text|sarif]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "directory",
+			Aliases: []string{"d"},
+			Usage:   "directory to scan",
+			Value:   ".",
+		},
+		&cli.StringFlag{
+			Name:    "file",
+			Aliases: []string{"f"},
+			Usage:   "specific file to verify",
+		},
+		&cli.StringFlag{
+			Name:  "exclude",
+			Usage: "regex pattern; matching scanned paths are skipped",
+		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "resolve only from the cache, ignoring TTL; cache misses are left unresolved",
+			EnvVars: []string{"GHAT_OFFLINE"},
+		},
+		&cli.StringFlag{
+			Name:     "token",
+			Aliases:  []string{"t"},
+			Usage:    "GitHub PAT token",
+			Category: "authentication",
+			EnvVars:  []string{"GITHUB_TOKEN", "GITHUB_API"},
+		},
+		&cli.StringFlag{
+			Name:    "github-api-url",
+			Usage:   "GitHub API root, for GitHub Enterprise Server (e.g. https://ghes.example.com/api/v3)",
+			EnvVars: []string{"GITHUB_API_URL"},
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: text or sarif",
+			Value: "text",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write output to this file instead of stdout",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		myFlags := core.NewFlags()
+		myFlags.Offline = c.Bool("offline")
+		myFlags.Directory = c.String("directory")
+		myFlags.File = c.String("file")
+		myFlags.Exclude = c.String("exclude")
+		myFlags.GitHubToken = c.String("token")
+		myFlags.GitHubAPIURL = c.String("github-api-url")
+		myFlags.Format = c.String("format")
+		myFlags.Output = c.String("output")
+
+		if err := myFlags.InitializeCache(); err != nil {
+			return fmt.Errorf("failed to initialize cache: %w", err)
+		}
+
+		return myFlags.Action(core.ActionVerify)
+	},
+}
+
 var orgCmd = &cli.Command{
 	Name:      "org",
 	Usage:     "run ghat all across every non-fork repo for a GitHub/GitLab user, org or group",
@@ -899,11 +1669,21 @@ var orgCmd = &cli.Command{
 			Usage: "HTTP timeout for GitHub API calls (e.g. 30s, 2m)",
 			Value: 30 * time.Second,
 		},
+		&cli.StringFlag{
+			Name:    "github-api-url",
+			Usage:   "GitHub API root, for GitHub Enterprise Server (e.g. https://ghes.example.com/api/v3)",
+			EnvVars: []string{"GITHUB_API_URL"},
+		},
 	},
 	Action: func(c *cli.Context) error {
 		if t := c.Duration("timeout"); t > 0 {
 			core.SetHTTPTimeout(t)
 		}
+		apiURL := c.String("github-api-url")
+		if apiURL == "" {
+			apiURL = core.LoadConfig("").GitHubAPIURL
+		}
+		core.SetGitHubAPIURL(apiURL)
 		flags := &core.OrgFlags{
 			Provider:    c.String("provider"),
 			BaseURL:     c.String("base-url"),
@@ -965,6 +1745,36 @@ var orgCmd = &cli.Command{
 	},
 }
 
+// machineOutput reports whether args ask for a --report, a non-text --format
+// or a cache export to stdout, whose output would be corrupted by the banner.
+func machineOutput(args []string) bool {
+	for i, a := range args {
+		switch {
+		case a == "--report" || strings.HasPrefix(a, "--report="):
+			return true
+		case a == "--format" && i+1 < len(args) && args[i+1] != "text":
+			return true
+		case strings.HasPrefix(a, "--format=") && a != "--format=text":
+			return true
+		case a == "-" && i > 0 && args[i-1] == "export":
+			return true
+		}
+	}
+	return false
+}
+
+// ledgerPath returns the --ledger file, the default ledger when it is unset,
+// or "" (no ledger) with --no-ledger.
+func ledgerPath(c *cli.Context) string {
+	if c.Bool("no-ledger") {
+		return ""
+	}
+	if p := c.String("ledger"); p != "" {
+		return p
+	}
+	return core.DefaultLedgerPath()
+}
+
 // githubToken returns the first non-empty value of GITHUB_TOKEN or GITHUB_API.
 func githubToken() string {
 	if t := os.Getenv("GITHUB_TOKEN"); t != "" {
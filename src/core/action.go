@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sync"
 )
 
 // label tags a sweep sub-action error with its verb so errors.Join output is attributable.
@@ -16,17 +17,62 @@ func label(verb string, err error) error {
 	return fmt.Errorf("%s: %w", verb, err)
 }
 
+// sweep runs every pinner at once, sharing the process resolver so a
+// reference that appears in several ecosystems is looked up once. swipe and
+// shake both rewrite .tf files, so they run one after the other.
+func (f *Flags) sweep() error {
+	pinners := [][]struct {
+		verb string
+		run  func() error
+	}{
+		{{ActionSwot, f.UpdateGHAS}},
+		{{ActionStun, f.UpdateGitlab}},
+		{{ActionSift, f.UpdateHooks}},
+		{{ActionSwipe, f.UpdateModules}, {ActionShake, f.UpdateProviders}},
+		{{ActionKube, f.UpdateKubes}},
+		{{ActionDock, f.UpdateDockerfiles}},
+		{{ActionSub, f.UpdateSubmodules}},
+		{{"cpan", f.UpdateCpanfile}},
+	}
+
+	errs := make([][]error, len(pinners))
+	var wg sync.WaitGroup
+	for i, group := range pinners {
+		errs[i] = make([]error, len(group))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j, p := range pinners[i] {
+				errs[i][j] = label(p.verb, p.run())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var all []error
+	for _, group := range errs {
+		all = append(all, group...)
+	}
+	return errors.Join(all...)
+}
+
 const (
-	ActionSwipe = "swipe"
-	ActionSwot  = "swot"
-	ActionSift  = "sift"
-	ActionStun  = "stun"
-	ActionShake = "shake"
-	ActionKube  = "kube"
-	ActionDock  = "dock"
-	ActionSweep = "sweep"
-	ActionSub   = "sub"
-	ActionAudit = "audit"
+	ActionSwipe      = "swipe"
+	ActionSwot       = "swot"
+	ActionSift       = "sift"
+	ActionStun       = "stun"
+	ActionShake      = "shake"
+	ActionKube       = "kube"
+	ActionDock       = "dock"
+	ActionSweep      = "sweep"
+	ActionSub        = "sub"
+	ActionAudit      = "audit"
+	ActionLint       = "lint"
+	ActionLock       = "lock"
+	ActionVerifyLock = "verify-lock"
+	ActionVerify     = "verify"
+	ActionPerms      = "perms"
+	ActionFix        = "fix"
 )
 
 func (f *Flags) Action(action string) error {
@@ -36,6 +82,19 @@ func (f *Flags) Action(action string) error {
 		return &actionIsEmptyError{}
 	}
 
+	if err := f.validateReport(); err != nil {
+		return err
+	}
+
+	if err := f.applyConfig(); err != nil {
+		return err
+	}
+
+	if err := f.useResponseCache(); err != nil {
+		return err
+	}
+	useResolver()
+
 	if f.File != "" {
 		if _, err := os.Stat(f.File); err != nil {
 			pwd, err := os.Getwd()
@@ -61,7 +120,17 @@ func (f *Flags) Action(action string) error {
 		}
 	}
 
+	if err := f.openLedger(); err != nil {
+		return err
+	}
+
 	err = executeAction(action, f)
+	if ledgerErr := f.closeLedger(); ledgerErr != nil {
+		err = errors.Join(err, ledgerErr)
+	}
+	if reportErr := f.WriteReport(); reportErr != nil {
+		return errors.Join(reportErr, err)
+	}
 	if err != nil {
 		return &executeActionError{action: action, err: err}
 	}
@@ -97,7 +166,7 @@ func executeAction(action string, f *Flags) error {
 
 	switch action {
 	case ActionSwipe:
-		if f.File != "" {
+		if f.File != "" && !isTerragruntFile(f.File) {
 			return f.UpdateModule(f.File)
 		}
 
@@ -120,6 +189,9 @@ func executeAction(action string, f *Flags) error {
 		}
 	case ActionShake:
 		{
+			if f.VerifyProviderLock {
+				return f.VerifyProviderLocks(os.Stdout)
+			}
 			return f.UpdateProviders()
 		}
 	case ActionKube:
@@ -136,18 +208,27 @@ func executeAction(action string, f *Flags) error {
 		return f.UpdateSubmodules()
 	case ActionAudit:
 		return f.Audit()
+	case ActionLint:
+		return f.Lint()
+	case ActionLock:
+		return f.Lock()
+	case ActionVerifyLock:
+		return f.VerifyLock(os.Stdout)
+	case ActionVerify:
+		return f.Verify()
+	case ActionPerms:
+		if f.File != "" {
+			rules, err := loadPermissionRules(defaultPermissionsData)
+			if err != nil {
+				return err
+			}
+			return f.updatePermissions(f.File, rules)
+		}
+		return f.Perms()
+	case ActionFix:
+		return f.Fix()
 	case ActionSweep:
-		return errors.Join(
-			label(ActionSwot, f.UpdateGHAS()),
-			label(ActionStun, f.UpdateGitlab()),
-			label(ActionSift, f.UpdateHooks()),
-			label(ActionSwipe, f.UpdateModules()),
-			label(ActionShake, f.UpdateProviders()),
-			label(ActionKube, f.UpdateKubes()),
-			label(ActionDock, f.UpdateDockerfiles()),
-			label(ActionSub, f.UpdateSubmodules()),
-			label("cpan", f.UpdateCpanfile()),
-		)
+		return f.sweep()
 	}
 
 	return nil
@@ -22,7 +22,7 @@ type WorkflowAnalysis struct {
 	// HasDangerousTrigger is true when a dangerous trigger combination is
 	// detected:
 	//   - pull_request_target with a checkout of the PR head, OR
-	//   - github.event.* interpolated directly into a run: shell block.
+	//   - untrusted input reaching a script (the first of TaintPaths).
 	HasDangerousTrigger  bool
 	DangerousTriggerDesc string
 	// HasConcurrency is true when the workflow declares a top-level
@@ -41,6 +41,20 @@ type WorkflowAnalysis struct {
 	Steps []StepAnalysis
 	// Jobs is the per-job analysis, sorted by job name.
 	Jobs []JobAnalysis
+	// Triggers are the event names in the workflow's on: block.
+	Triggers []string
+	// ArtifactPoisoning lists workflow_run steps that download or extract
+	// the triggering run's artifacts into the workspace.
+	ArtifactPoisoning []WorkflowRisk
+	// CachePoisoning lists cache restores in a privileged workflow keyed on
+	// values an outsider controls.
+	CachePoisoning []WorkflowRisk
+	// SelfHostedRunners lists the jobs whose runs-on: requests a
+	// self-hosted runner.
+	SelfHostedRunners []WorkflowRisk
+	// TaintPaths lists every flow of attacker-controlled github context
+	// data into a script or $GITHUB_ENV/$GITHUB_OUTPUT write, by line.
+	TaintPaths []TaintPath
 	// RunSteps is the ordered list of inline run: shell-script steps found in
 	// the workflow, one entry per step across all jobs. Steps with no run:
 	// key (uses: steps) are excluded — see StepAnalysis for those.
@@ -185,7 +199,7 @@ var usesExtractRe = regexp.MustCompile(`(?m)^\s*-?\s*uses:\s*(.+)`)
 // no I/O is performed and no network calls are made.
 //
 // The function reuses the regexes and helpers already present in this package
-// (permsRe, writeAllRe, prTargetRe, checkoutPRRe, runInjectRe, parsePinnedRef,
+// (permsRe, writeAllRe, prTargetRe, checkoutPRRe, TaintAnalysis, parsePinnedRef,
 // parseSuppression) so the analysis stays in sync with ghat's own checks.
 func AnalyzeWorkflow(filename string, content []byte) WorkflowAnalysis {
 	var a WorkflowAnalysis
@@ -208,14 +222,15 @@ func AnalyzeWorkflow(filename string, content []byte) WorkflowAnalysis {
 			a.DangerousTriggerLine = offsetToLine(content, loc[0])
 		}
 	}
-	if !a.HasDangerousTrigger {
-		if loc := runInjectRe.FindIndex(content); loc != nil {
-			a.HasDangerousTrigger = true
-			a.DangerousTriggerDesc = filename + ": github.event.* interpolated into run:"
-			a.DangerousTriggerLine = offsetToLine(content, loc[0])
-		}
+	a.TaintPaths = TaintAnalysis(content)
+	if !a.HasDangerousTrigger && len(a.TaintPaths) > 0 {
+		a.HasDangerousTrigger = true
+		a.DangerousTriggerDesc = filename + ": " + a.TaintPaths[0].Message()
+		a.DangerousTriggerLine = a.TaintPaths[0].Line
 	}
 
+	a.Triggers, a.ArtifactPoisoning, a.CachePoisoning, a.SelfHostedRunners = analyzeEscalation(content)
+
 	a.JobsLine = matchLine(jobsRe, content)
 	a.Steps = analyzeSteps(content)
 	a.Jobs = analyzeJobs(content)
@@ -254,12 +269,12 @@ func analyzeSteps(content []byte) []StepAnalysis {
 		parts := strings.SplitN(rawValue, "@", 2)
 		action := strings.TrimSpace(parts[0])
 
-		// Skip local/composite action paths, docker:// refs, and reusable
-		// workflow calls — these have no version registry to pin against.
+		// Skip local/composite action paths and docker:// refs — these have
+		// no version registry to pin against. Reusable workflow calls are
+		// pinned like actions, so they are analysed like them.
 		if strings.HasPrefix(action, ".") ||
 			strings.HasPrefix(action, "/") ||
-			strings.HasPrefix(action, "docker://") ||
-			strings.Contains(action, "/.github/workflows/") {
+			strings.HasPrefix(action, "docker://") {
 			continue
 		}
 
@@ -320,12 +320,16 @@ jobs:
       - uses: actions/checkout@v4
 `)
 	a := AnalyzeWorkflow("test.yml", content)
-	// Only actions/checkout should appear — local, docker, and reusable workflow refs are excluded
-	if len(a.Steps) != 1 {
-		t.Errorf("expected 1 step (only external), got %d: %v", len(a.Steps), a.Steps)
+	// Local and docker refs are excluded; the reusable workflow call is pinned
+	// like an action, so it is analysed like one.
+	if len(a.Steps) != 2 {
+		t.Fatalf("expected 2 steps (only external), got %d: %v", len(a.Steps), a.Steps)
+	}
+	if a.Steps[0].Action != "owner/repo/.github/workflows/reusable.yml" || a.Steps[0].Tag != "main" {
+		t.Errorf("expected the reusable workflow call, got %+v", a.Steps[0])
 	}
-	if len(a.Steps) > 0 && a.Steps[0].Action != "actions/checkout" {
-		t.Errorf("expected actions/checkout, got %q", a.Steps[0].Action)
+	if a.Steps[1].Action != "actions/checkout" {
+		t.Errorf("expected actions/checkout, got %q", a.Steps[1].Action)
 	}
 }
 
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -38,10 +37,48 @@ var allSources = []string{SourceGo, SourceGHA, SourcePreCommit, SourceTerraform,
 type dep struct {
 	source    string
 	label     string
+	host      string // set for host-qualified uses: refs; "" means the configured GitHub API
 	owner     string
 	repo      string
 	pinnedSHA string // the SHA *we* have pinned this dep to, if any
+	pinnedTag string // the tag in the pin's "# tag" comment, if any
 	skip      string
+	file      string // manifest declaring the dep, for SARIF locations
+	line      int
+	uses      string // the uses: ref as written, walked by --transitive; "" for deps it found
+}
+
+// auditTransitive walks everything d's uses: ref pulls in, returning the
+// transitive-pinned check, the unpinned refs and the deps to audit in turn.
+// Each dep gets a walk of its own, so its result doesn't depend on which
+// deps reached a shared action first; resolve memoises the fetches.
+func auditTransitive(d dep, token string) (checkResult, []string, []dep) {
+	trans := walkTransitive(d.uses, token, map[string]bool{})
+	var unpinned []string
+	var found []dep
+	for _, t := range trans {
+		if !t.pinned {
+			unpinned = append(unpinned, "transitive: "+t.String())
+		}
+		if td, ok := transitiveDep(t); ok {
+			td.file, td.line = d.file, d.line
+			found = append(found, td)
+		}
+	}
+	return checkTransitivePinned(trans), unpinned, found
+}
+
+// slug returns owner/repo, prefixed with the host for host-qualified refs.
+func (d dep) slug() string {
+	if d.host != "" {
+		return d.host + "/" + d.owner + "/" + d.repo
+	}
+	return d.owner + "/" + d.repo
+}
+
+// repoAPI returns the /repos/{owner}/{repo} URL for d on its GitHub host.
+func (d dep) repoAPI() string {
+	return repoAPI(d.slug())
 }
 
 type auditResult struct {
@@ -55,9 +92,15 @@ type auditResult struct {
 	suppressed int // refs skipped via # ghat:suppress in the dep's own workflows
 	checks     []checkResult
 	bucket     string
+	file       string
+	line       int
 }
 
 func (f *Flags) Audit() error {
+	if err := validateFormat(f.Format); err != nil {
+		return err
+	}
+
 	sources := f.Sources
 	if len(sources) == 0 {
 		sources = allSources
@@ -87,8 +130,10 @@ func (f *Flags) Audit() error {
 		}
 	}
 
+	f.locateDeps(deps)
+
 	local := f.scanLocalRunInstalls()
-	if len(local) > 0 {
+	if len(local) > 0 && f.Format != FormatSARIF {
 		fmt.Printf("local unpinned installs (%d):\n", len(local))
 		for _, l := range local {
 			fmt.Printf("  %s\n", l)
@@ -99,25 +144,27 @@ func (f *Flags) Audit() error {
 	var results []auditResult
 	seen := map[string]bool{}
 
-	for _, d := range deps {
+	// deps grows as --transitive finds the actions each one pulls in.
+	for i := 0; i < len(deps); i++ {
+		d := deps[i]
 		if d.skip != "" {
-			results = append(results, auditResult{source: d.source, label: d.label, skipped: d.skip})
+			results = append(results, auditResult{source: d.source, label: d.label, skipped: d.skip, file: d.file, line: d.line})
 			continue
 		}
-		key := d.owner + "/" + d.repo
+		key := d.slug()
 		if seen[key] {
 			continue
 		}
 		seen[key] = true
 
-		files, err := fetchWorkflows(d.owner, d.repo, f.GitHubToken)
+		files, err := fetchWorkflows(d.repoAPI(), f.GitHubToken)
 		if err != nil {
 			log.Warn().Str("dep", d.label).Err(err).Msg("failed to fetch workflows")
-			results = append(results, auditResult{source: d.source, label: d.label, repo: key, skipped: "fetch failed"})
+			results = append(results, auditResult{source: d.source, label: d.label, repo: key, skipped: "fetch failed", file: d.file, line: d.line})
 			continue
 		}
 
-		res := auditResult{source: d.source, label: d.label, repo: key, scanned: len(files)}
+		res := auditResult{source: d.source, label: d.label, repo: key, scanned: len(files), file: d.file, line: d.line}
 		var agg refScan
 		for name, body := range files {
 			refs := findUnpinned(body)
@@ -135,10 +182,20 @@ func (f *Flags) Audit() error {
 		res.suppressed = agg.suppressed
 		res.unpinned = agg.unpinned
 		res.checks = runChecks(d, files, agg, f.GitHubToken)
+		if f.Transitive && d.uses != "" {
+			check, unpinned, found := auditTransitive(d, f.GitHubToken)
+			res.checks = append(res.checks, check)
+			res.unpinned = append(res.unpinned, unpinned...)
+			deps = append(deps, found...)
+		}
 		res.bucket = bucket(res.checks)
 		results = append(results, res)
 	}
 
+	if f.Format == FormatSARIF {
+		return f.writeAuditSARIF(results, len(local))
+	}
+
 	if err := reportAudit(results); err != nil {
 		if len(local) > 0 {
 			return fmt.Errorf("%w; %d local unpinned installs", err, len(local))
@@ -201,7 +258,7 @@ func resolveRepo(modulePath string) (owner, repo string, err error) {
 		return splitGithubPath(rest)
 	}
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := lookupClient(15 * time.Second)
 	resp, err := client.Get("https://" + modulePath + "?go-get=1")
 	if err != nil {
 		return "", "", fmt.Errorf("vanity lookup failed: %w", err)
@@ -230,8 +287,10 @@ func splitGithubPath(p string) (string, string, error) {
 	return parts[0], parts[1], nil
 }
 
-func fetchWorkflows(owner, repo, token string) (map[string][]byte, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/.github/workflows", owner, repo)
+// fetchWorkflows downloads a repo's .github/workflows files; repoURL is the
+// /repos/{owner}/{repo} API URL from repoAPI.
+func fetchWorkflows(repoURL, token string) (map[string][]byte, error) {
+	url := repoURL + "/contents/.github/workflows"
 	listing, err := GetGithubBody(token, url)
 	if err != nil {
 		if strings.Contains(err.Error(), "status 404") {
@@ -408,7 +467,9 @@ func (f *Flags) collectGHADeps() []dep {
 	var deps []dep
 	for _, file := range f.Entries {
 		abs, _ := filepath.Abs(file)
-		if !strings.Contains(abs, githubWorkflowPath) {
+		// --transitive also reads the repo's own composite actions.
+		isActionFile := f.Transitive && (filepath.Base(file) == "action.yml" || filepath.Base(file) == "action.yaml")
+		if !strings.Contains(abs, githubWorkflowPath) && !isActionFile {
 			continue
 		}
 		if ext := filepath.Ext(file); ext != yamlExtension && ext != yamlAltExtension {
@@ -431,18 +492,22 @@ func (f *Flags) collectGHADeps() []dep {
 				continue
 			}
 			path, ver, _ := strings.Cut(ref, "@")
+			host, path := splitUsesHost(path)
 			parts := strings.SplitN(path, "/", 3)
 			if len(parts) < 2 {
 				continue
 			}
-			key := parts[0] + "/" + parts[1]
+			d := dep{source: SourceGHA, host: host, owner: parts[0], repo: parts[1]}
+			key := d.slug()
 			if seen[key] {
 				continue
 			}
 			seen[key] = true
-			d := dep{source: SourceGHA, label: key, owner: parts[0], repo: parts[1]}
+			d.label = key
+			d.uses = ref
 			if shaRe.MatchString(ver) {
 				d.pinnedSHA = ver
+				d.pinnedTag = refTagComment(line)
 			}
 			deps = append(deps, d)
 		}
@@ -507,6 +572,26 @@ func (f *Flags) collectTerraformDeps() []dep {
 	return deps
 }
 
+// transitiveDep returns the action a transitive ref names as a dep, so the
+// audit scores it like a direct one. Images are only counted against the
+// action that pulls them in.
+func transitiveDep(t transitiveRef) (dep, bool) {
+	if strings.HasPrefix(t.ref, "docker://") {
+		return dep{}, false
+	}
+	path, ver, _ := strings.Cut(t.ref, "@")
+	host, path := splitUsesHost(path)
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) < 2 {
+		return dep{}, false
+	}
+	d := dep{source: SourceGHA, label: t.String(), host: host, owner: parts[0], repo: parts[1]}
+	if shaRe.MatchString(ver) {
+		d.pinnedSHA = ver
+	}
+	return d, true
+}
+
 func githubOwnerRepo(s string) (string, string, bool) {
 	m := ghRepoRe.FindStringSubmatch(s)
 	if m == nil {
@@ -3,6 +3,8 @@ package core
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -23,12 +25,17 @@ type checkResult struct {
 // severity classifies which bucket a failed check pushes the dep into.
 // "risk" → active attack surface; "stale" → maintenance concern.
 var checkSeverity = map[string]string{
-	"signed-pin":        "stale",
-	"ci-pinned":         "risk",
-	"permissions":       "risk",
-	"dangerous-trigger": "risk",
-	"maintained":        "stale",
-	"alive":             "stale",
+	"signed-pin":         "stale",
+	"reachable-pin":      "risk",
+	"ci-pinned":          "risk",
+	"permissions":        "risk",
+	"dangerous-trigger":  "risk",
+	"maintained":         "stale",
+	"alive":              "stale",
+	"transitive-pinned":  "risk",
+	"artifact-poisoning": "risk",
+	"cache-poisoning":    "risk",
+	"self-hosted-runner": "risk",
 }
 
 var (
@@ -36,20 +43,20 @@ var (
 	// pull_request_target on its own is fine; the danger is checking out PR head.
 	prTargetRe   = regexp.MustCompile(`(?m)^\s*pull_request_target\s*:`)
 	checkoutPRRe = regexp.MustCompile(`actions/checkout@.*\n(?:.*\n){0,6}?.*ref:\s*\$\{\{\s*github\.event\.pull_request`)
-	// ${{ github.event.* }} interpolated into a run: shell block.
-	// Matches both the compact form ("- run: cmd") and the expanded form
-	// ("run:\n  cmd") by making the list-item dash optional.
-	runInjectRe = regexp.MustCompile(`(?m)^\s*(?:-\s+)?run:\s*.*\$\{\{\s*github\.event\.`)
 )
 
 func runChecks(d dep, workflows map[string][]byte, rs refScan, token string) []checkResult {
 	var out []checkResult
 	out = append(out, checkSignedPin(d, token))
+	out = append(out, checkReachablePin(d, token))
 	out = append(out, checkCIPinned(rs))
 	out = append(out, checkPermissions(workflows))
 	out = append(out, checkDangerousTrigger(workflows))
-	repoBody, _ := GetGithubBody(token, "https://api.github.com/repos/"+d.owner+"/"+d.repo)
+	out = append(out, checkArtifactPoisoning(workflows))
+	out = append(out, checkCachePoisoning(workflows))
+	repoBody, _ := GetGithubBody(token, d.repoAPI())
 	repo, _ := repoBody.(map[string]interface{})
+	out = append(out, checkSelfHostedRunner(workflows, repo))
 	out = append(out, checkMaintained(d, repo, token))
 	out = append(out, checkAlive(repo))
 	return out
@@ -59,7 +66,7 @@ func checkSignedPin(d dep, token string) checkResult {
 	if d.pinnedSHA == "" {
 		return checkResult{"signed-pin", checkSkip, "no SHA pin"}
 	}
-	body, err := GetGithubBody(token, "https://api.github.com/repos/"+d.owner+"/"+d.repo+"/commits/"+d.pinnedSHA)
+	body, err := GetGithubBody(token, d.repoAPI()+"/commits/"+d.pinnedSHA)
 	if err != nil {
 		return checkResult{"signed-pin", checkSkip, "lookup failed"}
 	}
@@ -77,6 +84,25 @@ func checkSignedPin(d dep, token string) checkResult {
 	return checkResult{"signed-pin", checkFail, reason}
 }
 
+// checkReachablePin fails when the pinned SHA is on no branch or tag of the
+// dep's repository (an impostor commit from a fork), or when the pin's
+// "# tag" comment resolves to a different SHA.
+func checkReachablePin(d dep, token string) checkResult {
+	if d.pinnedSHA == "" {
+		return checkResult{"reachable-pin", checkSkip, "no SHA pin"}
+	}
+	c := newPinVerifier(token).verifyPin(d.slug(), d.pinnedSHA, d.pinnedTag)
+	switch {
+	case c.err != nil:
+		return checkResult{"reachable-pin", checkSkip, "lookup failed"}
+	case c.reachableFrom == "":
+		return checkResult{"reachable-pin", checkFail, "impostor commit"}
+	case d.pinnedTag != "" && c.tagSHA != d.pinnedSHA:
+		return checkResult{"reachable-pin", checkFail, "# " + d.pinnedTag + " is " + shortSHA(c.tagSHA)}
+	}
+	return checkResult{"reachable-pin", checkPass, c.reachableFrom}
+}
+
 func checkCIPinned(rs refScan) checkResult {
 	if rs.total == 0 {
 		return checkResult{"ci-pinned", checkSkip, "no workflows"}
@@ -107,20 +133,74 @@ func checkDangerousTrigger(workflows map[string][]byte) checkResult {
 	if len(workflows) == 0 {
 		return checkResult{"dangerous-trigger", checkSkip, "no workflows"}
 	}
-	for name, body := range workflows {
+	var found []string
+	for _, name := range workflowNames(workflows) {
+		body := workflows[name]
 		if prTargetRe.Match(body) && checkoutPRRe.Match(body) {
-			return checkResult{"dangerous-trigger", checkFail, name + ": pull_request_target + PR checkout"}
+			found = append(found, name+": pull_request_target + PR checkout")
 		}
-		if runInjectRe.Match(body) {
-			return checkResult{"dangerous-trigger", checkFail, name + ": github.event in run:"}
+		for _, path := range TaintAnalysis(body) {
+			found = append(found, name+": "+path.String())
+		}
+	}
+	switch len(found) {
+	case 0:
+		return checkResult{"dangerous-trigger", checkPass, ""}
+	case 1:
+		return checkResult{"dangerous-trigger", checkFail, found[0]}
+	}
+	return checkResult{"dangerous-trigger", checkFail, fmt.Sprintf("%d paths: %s", len(found), strings.Join(found, "; "))}
+}
+
+// workflowNames returns the workflow file names in sorted order, so checks
+// report the same file on every run.
+func workflowNames(workflows map[string][]byte) []string {
+	names := make([]string, 0, len(workflows))
+	for name := range workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkWorkflowRisks fails with the first risk pick finds in any workflow.
+func checkWorkflowRisks(name string, workflows map[string][]byte, pick func(WorkflowAnalysis) []WorkflowRisk) checkResult {
+	if len(workflows) == 0 {
+		return checkResult{name, checkSkip, "no workflows"}
+	}
+	for _, file := range workflowNames(workflows) {
+		if risks := pick(AnalyzeWorkflow(file, workflows[file])); len(risks) > 0 {
+			return checkResult{name, checkFail, file + ": " + risks[0].Desc}
 		}
 	}
-	return checkResult{"dangerous-trigger", checkPass, ""}
+	return checkResult{name, checkPass, ""}
+}
+
+func checkArtifactPoisoning(workflows map[string][]byte) checkResult {
+	return checkWorkflowRisks("artifact-poisoning", workflows, func(a WorkflowAnalysis) []WorkflowRisk { return a.ArtifactPoisoning })
+}
+
+func checkCachePoisoning(workflows map[string][]byte) checkResult {
+	return checkWorkflowRisks("cache-poisoning", workflows, func(a WorkflowAnalysis) []WorkflowRisk { return a.CachePoisoning })
+}
+
+// checkSelfHostedRunner fails when a public repository's workflows run on
+// self-hosted runners, which any fork pull request can then reach. It skips
+// when the repository lookup failed, as visibility is then unknown.
+func checkSelfHostedRunner(workflows map[string][]byte, repo map[string]interface{}) checkResult {
+	private, ok := repo["private"].(bool)
+	if !ok {
+		return checkResult{"self-hosted-runner", checkSkip, "visibility unknown"}
+	}
+	if private {
+		return checkResult{"self-hosted-runner", checkSkip, "private repository"}
+	}
+	return checkWorkflowRisks("self-hosted-runner", workflows, func(a WorkflowAnalysis) []WorkflowRisk { return a.SelfHostedRunners })
 }
 
 func checkMaintained(d dep, repo map[string]interface{}, token string) checkResult {
 	var t time.Time
-	body, err := GetGithubBody(token, "https://api.github.com/repos/"+d.owner+"/"+d.repo+"/releases/latest")
+	body, err := GetGithubBody(token, d.repoAPI()+"/releases/latest")
 	if err == nil {
 		if m, _ := body.(map[string]interface{}); m != nil {
 			if s, _ := m["published_at"].(string); s != "" {
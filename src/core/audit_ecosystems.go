@@ -29,7 +29,7 @@ var (
 )
 
 func getJSON(u string, out any) error {
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := lookupClient(30 * time.Second)
 	req, err := http.NewRequest(http.MethodGet, u, nil)
 	if err != nil {
 		return err
@@ -37,9 +37,9 @@ func AuditOne(eco, name, version, token string, c *Cache) (AuditScore, error) {
 		return AuditScore{}, err
 	}
 
-	files, err := fetchWorkflows(d.owner, d.repo, token)
+	files, err := fetchWorkflows(d.repoAPI(), token)
 	if err != nil {
-		return AuditScore{}, fmt.Errorf("fetch workflows for %s/%s: %w", d.owner, d.repo, err)
+		return AuditScore{}, fmt.Errorf("fetch workflows for %s: %w", d.slug(), err)
 	}
 
 	var agg refScan
@@ -115,11 +115,12 @@ func resolveDep(eco, name, version string) (dep, error) {
 		}
 
 	case SourceGHA:
-		parts := strings.SplitN(name, "/", 2)
+		host, path := splitUsesHost(name)
+		parts := strings.SplitN(path, "/", 2)
 		if len(parts) < 2 {
 			return dep{}, fmt.Errorf("invalid GHA dep %q", name)
 		}
-		d.owner, d.repo = parts[0], parts[1]
+		d.host, d.owner, d.repo = host, parts[0], parts[1]
 		if shaRe.MatchString(version) {
 			d.pinnedSHA = version
 		}
@@ -57,7 +57,22 @@ var looseInstallRules = []looseInstallRule{
 // one label per hit, e.g. "go-install: golang.org/x/vuln/cmd/govulncheck@latest".
 func findRunInstalls(body []byte) []string {
 	var out []string
-	for _, line := range strings.Split(string(body), "\n") {
+	for _, hit := range runInstallHits(body) {
+		out = append(out, hit.label)
+	}
+	return out
+}
+
+// runInstallHit is one loose install found by runInstallHits.
+type runInstallHit struct {
+	label string
+	line  int // 1-indexed
+}
+
+// runInstallHits is findRunInstalls with the source line of each hit kept.
+func runInstallHits(body []byte) []runInstallHit {
+	var out []runInstallHit
+	for i, line := range strings.Split(string(body), "\n") {
 		if t := strings.TrimSpace(line); t == "" || strings.HasPrefix(t, "#") {
 			continue
 		}
@@ -76,7 +91,7 @@ func findRunInstalls(body []byte) []string {
 			if detail == "" {
 				detail = strings.TrimSpace(m[0])
 			}
-			out = append(out, r.name+": "+detail)
+			out = append(out, runInstallHit{label: r.name + ": " + detail, line: i + 1})
 		}
 	}
 	return out
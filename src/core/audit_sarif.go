@@ -0,0 +1,113 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// depSite is where a dependency is declared.
+type depSite struct {
+	file string
+	line int
+}
+
+// locateDeps records the manifest and line declaring each dep, using the same
+// ParseManifest the editor integration uses. Deps whose exact line can't be
+// found (e.g. transitive Go modules) point at their ecosystem's manifest.
+func (f *Flags) locateDeps(deps []dep) {
+	sites := map[string]depSite{}
+	manifests := map[string]string{}
+
+	for _, file := range f.Entries {
+		kind, ok := ClassifyManifest(file)
+		if !ok {
+			continue
+		}
+		content, err := os.ReadFile(file) // #nosec G304
+		if err != nil {
+			continue
+		}
+		for _, ref := range ParseManifest(kind, content) {
+			if _, ok := manifests[ref.Ecosystem]; !ok {
+				manifests[ref.Ecosystem] = file
+			}
+			key := ref.Ecosystem + "\x00" + ref.Name
+			if _, ok := sites[key]; !ok {
+				sites[key] = depSite{file: file, line: ref.Line}
+			}
+		}
+	}
+
+	for i := range deps {
+		if site, ok := sites[deps[i].source+"\x00"+deps[i].label]; ok {
+			deps[i].file, deps[i].line = site.file, site.line
+			continue
+		}
+		deps[i].file = manifests[deps[i].source]
+	}
+}
+
+// auditFindings converts RISK and STALE audit results into findings.
+func auditFindings(results []auditResult) []Finding {
+	var findings []Finding
+	for _, r := range results {
+		if r.skipped != "" || r.file == "" {
+			continue
+		}
+		var rule, level string
+		switch r.bucket {
+		case "RISK":
+			rule, level = RuleAuditRisk, LevelError
+		case "STALE":
+			rule, level = RuleAuditStale, LevelWarning
+		default:
+			continue
+		}
+		pass, total := score(r.checks)
+		findings = append(findings, Finding{
+			Rule: rule, Level: level, File: r.file, Line: r.line,
+			Message: fmt.Sprintf("%s (%s) scored %d/%d: %s", r.label, r.repo, pass, total, formatChecks(r.checks)),
+		})
+	}
+	return findings
+}
+
+// writeAuditSARIF writes the static findings for the scanned files plus the
+// audit results as one SARIF log. It fails the same way the text report does.
+func (f *Flags) writeAuditSARIF(results []auditResult, local int) error {
+	findings := append(f.StaticFindings(), auditFindings(results)...)
+
+	var w io.Writer = os.Stdout
+	if f.Output != "" {
+		file, err := os.Create(f.Output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", f.Output, err)
+		}
+		defer file.Close() //nolint:errcheck
+		w = file
+	}
+	if err := WriteSARIF(w, f.Directory, findings); err != nil {
+		return err
+	}
+
+	var risk, stale int
+	for _, r := range results {
+		switch r.bucket {
+		case "RISK":
+			risk++
+		case "STALE":
+			stale++
+		}
+	}
+	if risk > 0 {
+		if local > 0 {
+			return fmt.Errorf("%d RISK, %d STALE; %d local unpinned installs", risk, stale, local)
+		}
+		return fmt.Errorf("%d RISK, %d STALE", risk, stale)
+	}
+	if local > 0 {
+		return fmt.Errorf("%d local unpinned installs", local)
+	}
+	return nil
+}
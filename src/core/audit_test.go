@@ -2,6 +2,7 @@ package core
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -163,7 +164,8 @@ jobs:
 		{"clean", map[string][]byte{"a.yml": []byte("on: push\njobs:\n")}, checkPass},
 		{"prt-alone", map[string][]byte{"a.yml": []byte("on:\n  pull_request_target:\njobs:\n")}, checkPass},
 		{"prt+checkout", map[string][]byte{"a.yml": []byte(prtCheckout)}, checkFail},
-		{"run-inject", map[string][]byte{"a.yml": []byte("    run: echo ${{ github.event.issue.title }}\n")}, checkFail},
+		{"run-inject", map[string][]byte{"a.yml": []byte("jobs:\n  x:\n    steps:\n      - run: echo ${{ github.event.issue.title }}\n")}, checkFail},
+		{"env-var", map[string][]byte{"a.yml": []byte("jobs:\n  x:\n    steps:\n      - run: echo \"$TITLE\"\n        env:\n          TITLE: ${{ github.event.issue.title }}\n")}, checkPass},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -174,6 +176,21 @@ jobs:
 	}
 }
 
+func TestCheckDangerousTrigger_ReportsEveryPath(t *testing.T) {
+	t.Parallel()
+
+	inject := []byte("jobs:\n  x:\n    steps:\n      - run: echo ${{ github.event.issue.title }}\n      - run: echo ${{ github.head_ref }}\n")
+	wf := map[string][]byte{"b.yml": inject, "a.yml": inject}
+
+	got := checkDangerousTrigger(wf)
+	if got.outcome != checkFail {
+		t.Fatalf("outcome = %v, want fail", got.outcome)
+	}
+	if !strings.HasPrefix(got.detail, "4 paths: a.yml: ") || strings.Count(got.detail, "b.yml: ") != 2 {
+		t.Errorf("detail = %q, want all 4 paths with a.yml first", got.detail)
+	}
+}
+
 func TestBucketAndScore(t *testing.T) {
 	cs := []checkResult{
 		{"signed-pin", checkSkip, ""},
@@ -1,11 +1,16 @@
 package core
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -36,10 +41,14 @@ func NewCache(ttl time.Duration, enabled bool) (*Cache, error) {
 	if err != nil {
 		base = os.TempDir()
 	}
-	cacheDir := filepath.Join(base, "ghat")
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+	return newCacheIn(filepath.Join(base, "ghat"), ttl), nil
+}
+
+// newCacheIn creates a cache rooted at cacheDir.
+func newCacheIn(cacheDir string, ttl time.Duration) *Cache {
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
 		log.Warn().Err(err).Msg("Failed to create cache directory, caching disabled")
-		return &Cache{enabled: false}, nil
+		return &Cache{enabled: false}
 	}
 
 	log.Debug().Str("dir", cacheDir).Dur("ttl", ttl).Msg("Cache initialized")
@@ -48,7 +57,7 @@ func NewCache(ttl time.Duration, enabled bool) (*Cache, error) {
 		dir:     cacheDir,
 		ttl:     ttl,
 		enabled: true,
-	}, nil
+	}
 }
 
 // getCacheKey generates a cache key from a URL
@@ -91,6 +100,28 @@ func (c *Cache) Get(url string) (interface{}, bool) {
 	return entry.Data, true
 }
 
+// getRaw returns a cached entry's data whether or not it has expired, for
+// offline mode. Unlike Get it never removes the file.
+func (c *Cache) getRaw(url string) (json.RawMessage, bool) {
+	if !c.enabled {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, c.getCacheKey(url)))
+	if err != nil {
+		log.Debug().Str("url", url).Msg("Cache miss")
+		return nil, false
+	}
+
+	var entry struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return entry.Data, true
+}
+
 // Set stores a response in the cache
 func (c *Cache) Set(url string, data interface{}) error {
 	if !c.enabled {
@@ -111,7 +142,7 @@ func (c *Cache) Set(url string, data interface{}) error {
 		return err
 	}
 
-	if err := os.WriteFile(cachePath, jsonData, 0644); err != nil {
+	if err := os.WriteFile(cachePath, jsonData, 0600); err != nil {
 		log.Warn().Err(err).Str("url", url).Msg("Failed to write cache")
 		return err
 	}
@@ -131,7 +162,7 @@ func (c *Cache) Clear() error {
 	}
 
 	// Recreate the directory
-	return os.MkdirAll(c.dir, 0755)
+	return os.MkdirAll(c.dir, 0700)
 }
 
 // ClearExpired removes expired cache entries
@@ -204,3 +235,94 @@ func (c *Cache) Stats() (int, int64, error) {
 func (c *Cache) IsEnabled() bool {
 	return c.enabled
 }
+
+// cacheFileRe matches the names getCacheKey gives cache files; import
+// accepts nothing else, so an archive cannot write outside the cache.
+var cacheFileRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Export writes every cache entry to w as a gzipped tar, for priming an
+// offline machine with ghat cache import.
+func (c *Cache) Export(w io.Writer) (int, error) {
+	if !c.enabled {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	count := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !cacheFileRe.MatchString(entry.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.dir, entry.Name())) // #nosec G304
+		if err != nil {
+			return count, err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return count, err
+		}
+		hdr := &tar.Header{Name: entry.Name(), Mode: 0600, Size: int64(len(data)), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return count, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := tw.Close(); err != nil {
+		return count, err
+	}
+	return count, gz.Close()
+}
+
+// Import adds the entries of an archive written by Export to the cache,
+// replacing any with the same key.
+func (c *Cache) Import(r io.Reader) (int, error) {
+	if !c.enabled {
+		return 0, nil
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("not a ghat cache export: %w", err)
+	}
+	defer gz.Close() //nolint:errcheck
+
+	tr := tar.NewReader(gz)
+	count := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !cacheFileRe.MatchString(hdr.Name) {
+			log.Warn().Str("name", hdr.Name).Msg("Skipping unexpected cache archive entry")
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return count, err
+		}
+		var entry CacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Warn().Str("name", hdr.Name).Msg("Skipping corrupt cache archive entry")
+			continue
+		}
+		path := filepath.Join(c.dir, hdr.Name)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return count, err
+		}
+		_ = os.Chtimes(path, hdr.ModTime, hdr.ModTime)
+		count++
+	}
+}
@@ -28,13 +28,25 @@ type InputUpgrade struct {
 type GhatConfig struct {
 	Substitutions []Substitution `yaml:"substitutions"`
 	InputUpgrades []InputUpgrade `yaml:"input_upgrades"`
+	GitHubAPIURL  string         `yaml:"github_api_url"` // e.g. https://ghes.example.com/api/v3
+	// Cooldown is the minimum age in days of a release the pinners will
+	// pick, keyed by ecosystem (gha, pre-commit, ...) or "default".
+	Cooldown map[string]uint `yaml:"cooldown"`
+	// Policies limit the versions the pinners move matching dependencies to.
+	Policies []Policy `yaml:"policies"`
+	// LockPlatforms are the os_arch platforms shake hashes into
+	// .terraform.lock.hcl, e.g. linux_amd64.
+	LockPlatforms []string `yaml:"lock_platforms"`
 }
 
 //go:embed substitutions.yml
 var defaultSubstitutionsData []byte
 
 // LoadConfig merges built-in substitutions.yml, ~/.ghat.yml (global),
-// and <dir>/.ghat.yml (local). Later entries win on duplicate From values.
+// and <dir>/.ghat.yml (local). Later entries win on duplicate From values
+// and, for policies, on dependencies more than one matches; the last
+// non-empty github_api_url and lock_platforms, and the last value of each
+// cooldown key, win.
 func LoadConfig(dir string) GhatConfig {
 	var merged GhatConfig
 	_ = yaml.Unmarshal(defaultSubstitutionsData, &merged)
@@ -43,6 +55,19 @@ func LoadConfig(dir string) GhatConfig {
 		if cfg, err := loadConfigFile(path); err == nil {
 			merged.Substitutions = append(merged.Substitutions, cfg.Substitutions...)
 			merged.InputUpgrades = append(merged.InputUpgrades, cfg.InputUpgrades...)
+			merged.Policies = append(merged.Policies, cfg.Policies...)
+			if len(cfg.LockPlatforms) > 0 {
+				merged.LockPlatforms = cfg.LockPlatforms
+			}
+			if cfg.GitHubAPIURL != "" {
+				merged.GitHubAPIURL = cfg.GitHubAPIURL
+			}
+			for ecosystem, days := range cfg.Cooldown {
+				if merged.Cooldown == nil {
+					merged.Cooldown = map[string]uint{}
+				}
+				merged.Cooldown[ecosystem] = days
+			}
 		}
 	}
 	if home, err := os.UserHomeDir(); err == nil {
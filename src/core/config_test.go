@@ -250,3 +250,35 @@ func TestRewritePreCommitRevs_WithSubstitution(t *testing.T) {
 		t.Errorf("rewritePreCommitRevs with substitution mismatch\n--- want ---\n%s\n--- got ---\n%s", want, got)
 	}
 }
+
+func TestLoadConfig_GitHubAPIURL(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	content := "github_api_url: https://ghes.example.com/api/v3\n"
+	if err := os.WriteFile(filepath.Join(dir, ".ghat.yml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := LoadConfig(dir).GitHubAPIURL; got != "https://ghes.example.com/api/v3" {
+		t.Errorf("GitHubAPIURL = %q, want https://ghes.example.com/api/v3", got)
+	}
+}
+
+func TestFlags_ApplyConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	content := "cooldown:\n  default: 5\npolicies:\n  - name: nginx\n    freeze: true\n"
+	if err := os.WriteFile(filepath.Join(dir, ".ghat.yml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Flags{Directory: dir, Cooldown: map[string]uint{CooldownDefault: 1}}
+	if err := f.applyConfig(); err != nil {
+		t.Fatalf("applyConfig() error = %v", err)
+	}
+	if f.Cooldown[CooldownDefault] != 1 || len(f.Policies) != 1 || !f.Policies[0].Freeze {
+		t.Errorf("applyConfig() gave Cooldown %v, Policies %+v", f.Cooldown, f.Policies)
+	}
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// versionConstraint is one comma-separated part of a Terraform version
+// constraint, such as "~> 5.1" or "< 6.0".
+type versionConstraint struct {
+	op      string // as written; "" for a bare version, which means =
+	version string // as written, without a leading v
+}
+
+type constraintError struct {
+	constraint string
+}
+
+func (e *constraintError) Error() string {
+	return fmt.Sprintf("%q is not a version constraint", e.constraint)
+}
+
+type boundError struct {
+	name  string
+	bound string
+}
+
+func (e *boundError) Error() string {
+	return fmt.Sprintf("no release of %s meets %q", e.name, e.bound)
+}
+
+// constraintOperators are tried longest first, so >= is not read as >.
+var constraintOperators = []string{"~>", ">=", "<=", "!=", ">", "<", "="}
+
+// parseConstraints splits a Terraform version constraint string such as
+// ">= 4.0, < 6.0" into its parts.
+func parseConstraints(s string) ([]versionConstraint, error) {
+	var cs []versionConstraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		var c versionConstraint
+		for _, o := range constraintOperators {
+			if rest, ok := strings.CutPrefix(part, o); ok {
+				c.op, part = o, strings.TrimSpace(rest)
+				break
+			}
+		}
+		c.version = strings.TrimPrefix(part, "v")
+		if !semver.IsValid("v" + c.version) {
+			return nil, &constraintError{constraint: s}
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+func (c versionConstraint) String() string {
+	if c.op == "" {
+		return c.version
+	}
+	return c.op + " " + c.version
+}
+
+// renderConstraints joins constraints the way terraform fmt leaves them.
+func renderConstraints(cs []versionConstraint) string {
+	parts := make([]string, len(cs))
+	for i, c := range cs {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// allows reports whether v, a semver string with a leading v, meets c.
+func (c versionConstraint) allows(v string) bool {
+	want := "v" + c.version
+	cmp := semver.Compare(v, want)
+	switch c.op {
+	case "~>":
+		// ~> 5.1 allows 5.y >= 5.1, ~> 5.1.2 allows 5.1.z >= 5.1.2: every
+		// segment but the last must match.
+		segments, written := versionSegments(want)
+		have, _ := versionSegments(v)
+		for i := 0; i < written-1; i++ {
+			if have[i] != segments[i] {
+				return false
+			}
+		}
+		return cmp >= 0
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
+
+// satisfiesConstraints reports whether version meets a Terraform version
+// constraint string such as ">= 4.0, < 6.0" or "~> 5.1".
+func satisfiesConstraints(version, constraints string) (bool, error) {
+	v := "v" + strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if !semver.IsValid(v) {
+		return false, fmt.Errorf("%q is not a version", version)
+	}
+	cs, err := parseConstraints(constraints)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range cs {
+		if !c.allows(v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// pessimisticBound returns the < bound a ~> constraint implies: ~> 5.1 caps
+// at < 6.0 and ~> 5.1.2 at < 5.2.0. A single-segment ~> 5 has none.
+func (c versionConstraint) pessimisticBound() (versionConstraint, bool) {
+	segments, written := versionSegments("v" + c.version)
+	switch {
+	case c.op != "~>" || written < 2:
+		return versionConstraint{}, false
+	case written == 2:
+		return versionConstraint{op: "<", version: fmt.Sprintf("%d.0", segments[0]+1)}, true
+	}
+	return versionConstraint{op: "<", version: fmt.Sprintf("%d.%d.0", segments[0], segments[1]+1)}, true
+}
+
+// upperBounds returns the parts of a constraint string that cap a version,
+// its <, <= and != parts and the < bound each ~> implies, which an upgrade
+// that keeps the constraint must still meet. It returns "" if there are none
+// or constraints don't parse.
+func upperBounds(constraints string) string {
+	cs, err := parseConstraints(constraints)
+	if err != nil {
+		return ""
+	}
+	var bounds []versionConstraint
+	for _, c := range cs {
+		switch c.op {
+		case "<", "<=", "!=":
+			bounds = append(bounds, c)
+		case "~>":
+			if bound, ok := c.pessimisticBound(); ok {
+				bounds = append(bounds, bound)
+			}
+		}
+	}
+	return renderConstraints(bounds)
+}
+
+// bumpConstraints moves a constraint string up to version, keeping its style:
+// ~> 5.0 becomes ~> 5.80, the lower bound of >= 4.0, < 6.0 becomes 5.80 and
+// an exact version becomes version. Upper bounds are left as they are, as is
+// a ~> that version is past the implied bound of.
+func bumpConstraints(constraints, version string) (string, error) {
+	cs, err := parseConstraints(constraints)
+	if err != nil {
+		return "", err
+	}
+	version = strings.TrimPrefix(version, "v")
+	for i, c := range cs {
+		if bound, ok := c.pessimisticBound(); ok && !bound.allows("v"+version) {
+			continue
+		}
+		switch c.op {
+		case "~>", ">=", ">":
+			_, written := versionSegments("v" + c.version)
+			cs[i] = versionConstraint{op: c.op, version: truncateVersion(version, written)}
+			if c.op == ">" {
+				cs[i].op = ">="
+			}
+		case "", "=":
+			cs[i].version = version
+		}
+	}
+	return renderConstraints(cs), nil
+}
+
+// sameConstraints reports whether two constraint strings say the same thing,
+// ignoring spacing.
+func sameConstraints(a, b string) bool {
+	ca, errA := parseConstraints(a)
+	cb, errB := parseConstraints(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return renderConstraints(ca) == renderConstraints(cb)
+}
+
+// truncateVersion keeps the first n dot-separated parts of version.
+func truncateVersion(version string, n int) string {
+	parts := strings.SplitN(version, ".", 3)
+	if n < len(parts) {
+		parts = parts[:n]
+	}
+	return strings.Join(parts, ".")
+}
+
+// versionSegments returns the numeric major, minor and patch of a valid
+// semver string, with zero for the ones left out, and how many were written,
+// so ~> can tell 5.1 from 5.1.0.
+func versionSegments(v string) (segments [3]int, written int) {
+	core, _, _ := strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	core, _, _ = strings.Cut(core, "+")
+	parts := strings.Split(core, ".")
+	for i := 0; i < len(parts) && i < 3; i++ {
+		segments[i], _ = strconv.Atoi(parts[i])
+	}
+	return segments, len(parts)
+}
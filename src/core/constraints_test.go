@@ -0,0 +1,128 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSatisfiesConstraints(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		version, constraints string
+		want                 bool
+	}{
+		{"5.80.0", "~> 5.0", true},
+		{"6.0.0", "~> 5.0", false},
+		{"5.1.9", "~> 5.1.2", true},
+		{"5.2.0", "~> 5.1.2", false},
+		{"7.0.0", "~> 5", true},
+		{"4.67.0", ">= 4.0, < 5.0", true},
+		{"5.0.0", ">= 4.0, < 5.0", false},
+		{"3.2.0", "3.2.0", true},
+		{"3.2.1", "= 3.2.0", false},
+		{"3.2.1", "!= 3.2.0", true},
+	}
+	for _, tt := range tests {
+		got, err := satisfiesConstraints(tt.version, tt.constraints)
+		if err != nil || got != tt.want {
+			t.Errorf("satisfiesConstraints(%q, %q) = %v, %v, want %v", tt.version, tt.constraints, got, err, tt.want)
+		}
+	}
+	if _, err := satisfiesConstraints("1.0.0", "~> latest"); err == nil {
+		t.Error("satisfiesConstraints() should reject a constraint that is not a version")
+	}
+}
+
+func TestBumpConstraints(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		constraints, version, want string
+	}{
+		{"~> 5.0", "5.80.2", "~> 5.80"},
+		{"~>5.0.1", "5.0.7", "~> 5.0.7"},
+		{"~>5.0.1", "6.1.0", "~> 5.0.1"},
+		{"~> 5.0", "6.1.0", "~> 5.0"},
+		{"~> 5", "6.1.0", "~> 6"},
+		{">= 4.0, < 6.0", "5.80.2", ">= 5.80, < 6.0"},
+		{"> 4", "5.80.2", ">= 5"},
+		{"= 3.2.0", "3.4.1", "= 3.4.1"},
+		{"3.2.0", "3.4.1", "3.4.1"},
+	}
+	for _, tt := range tests {
+		got, err := bumpConstraints(tt.constraints, tt.version)
+		if err != nil || got != tt.want {
+			t.Errorf("bumpConstraints(%q, %q) = %q, %v, want %q", tt.constraints, tt.version, got, err, tt.want)
+		}
+	}
+}
+
+func TestUpperBounds(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"~> 5.0":                   "< 6.0",
+		"~>5.0.1":                  "< 5.1.0",
+		"~> 5":                     "",
+		">= 4.0, < 6.0":            "< 6.0",
+		">=1.0,<=1.9, != 1.5.0":    "<= 1.9, != 1.5.0",
+		"not a constraint, really": "",
+	}
+	for constraints, want := range tests {
+		if got := upperBounds(constraints); got != want {
+			t.Errorf("upperBounds(%q) = %q, want %q", constraints, got, want)
+		}
+	}
+}
+
+func TestFlags_UpdateProvider_KeepConstraints(t *testing.T) {
+	fakeProviderRegistry(t)
+
+	tests := []struct {
+		current, want string
+	}{
+		{current: "~> 1.0", want: "~> 1.4"},
+		{current: ">= 1.0, < 2.0", want: ">= 1.4, < 2.0"},
+		{current: "~> 2.1", want: "~> 2.1"},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "versions.tf")
+		content := "terraform {\n  required_providers {\n    widget = {\n      source  = \"ghattest/widget\"\n" +
+			"      version = \"" + tt.current + "\"\n    }\n  }\n}\n"
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		f := &Flags{Silent: true, KeepConstraints: true, LockPlatforms: []string{"linux_amd64"}}
+		if err := f.UpdateProvider(file); err != nil {
+			t.Fatalf("UpdateProvider() error = %v", err)
+		}
+		got, _ := os.ReadFile(file)
+		if !strings.Contains(string(got), "version = \""+tt.want+"\"") {
+			t.Errorf("UpdateProvider(%q) wrote\n%s\nwant version %q", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestFlags_UpdateModule_KeepConstraints(t *testing.T) {
+	fakeProviderRegistry(t)
+
+	file := filepath.Join(t.TempDir(), "main.tf")
+	content := "module \"network\" {\n  source  = \"ghattest/network/aws\"\n  version = \">= 1.2, < 2.0\"\n}\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var days uint
+	f := &Flags{Silent: true, KeepConstraints: true, Days: &days}
+	if err := f.UpdateModule(file); err != nil {
+		t.Fatalf("UpdateModule() error = %v", err)
+	}
+	got, _ := os.ReadFile(file)
+	if !strings.Contains(string(got), "source  = \"ghattest/network/aws\"") || !strings.Contains(string(got), "version = \">= 1.10, < 2.0\"") {
+		t.Errorf("UpdateModule() wrote\n%s", got)
+	}
+}
@@ -0,0 +1,313 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/mod/semver"
+)
+
+// CooldownDefault is the .ghat.yml cooldown key that applies to every
+// ecosystem without its own.
+const CooldownDefault = "default"
+
+// maxCooldownLookups bounds how many versions a cooldown checks the age of,
+// newest first, where the source has no dated list of releases.
+const maxCooldownLookups = 10
+
+type cooldownError struct {
+	name string
+	days uint
+}
+
+func (e *cooldownError) Error() string {
+	return fmt.Sprintf("no release of %s is older than %d days", e.name, e.days)
+}
+
+// cooldown returns the minimum age, in days, a release in ecosystem must
+// reach before a pinner picks it: a non-zero --stable, else the .ghat.yml
+// cooldown for ecosystem, else the cooldown default.
+func (f *Flags) cooldown(ecosystem string) uint {
+	if f.Days != nil && *f.Days > 0 {
+		return *f.Days
+	}
+	if days, ok := f.Cooldown[ecosystem]; ok {
+		return days
+	}
+	return f.Cooldown[CooldownDefault]
+}
+
+// cooldownLimit is the newest publish time a release days old can have.
+func cooldownLimit(days uint) time.Time {
+	return time.Now().Add(-time.Duration(int64(days) * dayInNanos))
+}
+
+// stableVersionsDesc returns the names that coerce to a stable semver,
+// highest first.
+func stableVersionsDesc(names []string) []string {
+	var out []string
+	for _, n := range names {
+		if v := coerceSemver(n); v != "" && semver.Prerelease(v) == "" {
+			out = append(out, n)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return semver.Compare(coerceSemver(out[i]), coerceSemver(out[j])) > 0
+	})
+	return out
+}
+
+// newestPublishedBefore returns the first of candidates, which are newest
+// first, that published dates before limit. Only the first
+// maxCooldownLookups candidates are checked.
+func newestPublishedBefore(subject string, days uint, candidates []string, published func(string) (time.Time, error)) (string, error) {
+	limit := cooldownLimit(days)
+	for i, c := range candidates {
+		if i == maxCooldownLookups {
+			break
+		}
+		t, err := published(c)
+		if err != nil {
+			log.Debug().Str("name", subject).Str("version", c).Err(err).Msg("no publish date, skipping")
+			continue
+		}
+		if t.Before(limit) {
+			return c, nil
+		}
+	}
+	return "", &cooldownError{name: subject, days: days}
+}
+
+// githubTagOlderThan returns the commit and name of the newest release of
+// action at least days old. A repository whose releases are all newer, or
+// which has none, falls back to its tags, dated by their commits.
+func githubTagOlderThan(action, token string, days uint) (sha, tag string, err error) {
+	release, err := GetReleases(action, token, &days)
+	if err != nil {
+		return "", "", err
+	}
+	if released, ok := release["tag_name"].(string); ok {
+		sha, err := resolveTagSHA(action, released, token)
+		return sha, released, err
+	}
+
+	tagged, err := githubTags(action, token)
+	if err != nil {
+		return "", "", err
+	}
+	shas, names := tagCommits(tagged)
+	tag, err = newestPublishedBefore(action, days, stableVersionsDesc(names), func(t string) (time.Time, error) {
+		return githubCommitDate(action, shas[t], token)
+	})
+	return shas[tag], tag, err
+}
+
+// tagCommits maps the names in a GitHub tags list to their commits, and
+// returns the names in list order.
+func tagCommits(tagged []interface{}) (map[string]string, []string) {
+	shas := map[string]string{}
+	var names []string
+	for _, t := range tagged {
+		m, _ := t.(map[string]interface{})
+		tagName, _ := m["name"].(string)
+		commit, _ := m["commit"].(map[string]interface{})
+		if sha, ok := commit["sha"].(string); ok && tagName != "" {
+			shas[tagName] = sha
+			names = append(names, tagName)
+		}
+	}
+	return shas, names
+}
+
+// latestGithubTag returns the commit and name of the newest tag of action,
+// or with a cooldown for ecosystem, of its newest release old enough. A
+// policy that constrains the version limits it to the tags p lets current
+// move to.
+func (f *Flags) latestGithubTag(action, ecosystem string, p *Policy, current string) (sha, tag string, err error) {
+	days := f.cooldown(ecosystem)
+	if p.constrains() {
+		return githubTagWithin(action, f.GitHubToken, days, p, current)
+	}
+	if days > 0 {
+		return githubTagOlderThan(action, f.GitHubToken, days)
+	}
+	t, err := GetLatestTag(action, f.GitHubToken)
+	if err != nil {
+		return "", "", err
+	}
+	m, ok := t.(map[string]interface{})
+	if !ok {
+		return "", "", &castToMapError{object: "tag"}
+	}
+	commit, _ := m["commit"].(map[string]interface{})
+	sha, shaOK := commit["sha"].(string)
+	tag, tagOK := m["name"].(string)
+	if !shaOK || !tagOK {
+		return "", "", &castToStringError{"tag"}
+	}
+	return sha, tag, nil
+}
+
+// githubCommitDate returns when a commit of action was committed.
+func githubCommitDate(action, sha, token string) (time.Time, error) {
+	body, err := GetGithubBodyWithCache(token, repoAPI(action)+"/commits/"+sha, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	m, _ := body.(map[string]interface{})
+	commit, _ := m["commit"].(map[string]interface{})
+	committer, _ := commit["committer"].(map[string]interface{})
+	date, ok := committer["date"].(string)
+	if !ok {
+		return time.Time{}, &castToStringError{"commit.committer.date"}
+	}
+	return time.Parse(time.RFC3339, date)
+}
+
+// providerVersionsV2 is the part of the registry's v2 provider document,
+// with its versions included, that carries publish dates.
+type providerVersionsV2 struct {
+	Included []providerVersionV2 `json:"included"`
+}
+
+type providerVersionV2 struct {
+	Type       string `json:"type"`
+	Attributes struct {
+		Version     string    `json:"version"`
+		PublishedAt time.Time `json:"published-at"`
+	} `json:"attributes"`
+}
+
+// providerVersionDoc reads a provider's versions with their publish dates
+// from registry.terraform.io, or without them from any other registry.
+func providerVersionDoc(host, namespace, providerType string) (providerVersionsV2, error) {
+	if host != defaultProviderHost {
+		var doc providerVersionsV2
+		list, err := providerVersions(host, namespace, providerType)
+		for _, v := range list.Versions {
+			entry := providerVersionV2{Type: "provider-versions"}
+			entry.Attributes.Version = v.Version
+			doc.Included = append(doc.Included, entry)
+		}
+		return doc, err
+	}
+	return resolve(fmt.Sprintf("terraform-provider-v2 %s/%s", namespace, providerType), func() (providerVersionsV2, error) {
+		var doc providerVersionsV2
+		url := fmt.Sprintf("%s/v2/providers/%s/%s?include=provider-versions", registryRoot(host), namespace, providerType)
+		client := lookupClient(30 * time.Second)
+		resp, err := client.Get(url)
+		if err != nil {
+			return doc, fmt.Errorf("failed to query registry: %w", err)
+		}
+		defer func(Body io.ReadCloser) { _ = Body.Close() }(resp.Body)
+		if resp.StatusCode != 200 {
+			return doc, fmt.Errorf("registry returned status %d", resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&doc)
+		return doc, err
+	})
+}
+
+// pickProviderVersion returns the highest stable version in a v2 provider
+// document published before limit that p lets current move to and, if bound
+// is set, that meets that constraint.
+func pickProviderVersion(doc providerVersionsV2, limit time.Time, p *Policy, current, bound string) string {
+	var latest string
+	for _, v := range doc.Included {
+		if v.Type != "provider-versions" || !v.Attributes.PublishedAt.Before(limit) ||
+			!p.permits(current, v.Attributes.Version) {
+			continue
+		}
+		if ok, _ := satisfiesConstraints(v.Attributes.Version, bound); bound != "" && !ok {
+			continue
+		}
+		version := "v" + v.Attributes.Version
+		if !semver.IsValid(version) || semver.Prerelease(version) != "" {
+			continue
+		}
+		if latest == "" || semver.Compare(version, latest) > 0 {
+			latest = version
+		}
+	}
+	return strings.TrimPrefix(latest, "v")
+}
+
+// providerVersionWithin returns the highest stable version of a provider
+// published at least days ago that p lets current move to and that meets
+// bound, if set. The v1 versions list has no dates, so for
+// registry.terraform.io this reads the v2 document; other registries have
+// no v2 API, so their versions are taken undated and the cooldown is skipped.
+func providerVersionWithin(host, namespace, providerType string, days uint, p *Policy, current, bound string) (string, error) {
+	doc, err := providerVersionDoc(host, namespace, providerType)
+	if err != nil {
+		return "", err
+	}
+	if host != defaultProviderHost && days > 0 {
+		log.Warn().Str("provider", host+"/"+namespace+"/"+providerType).Msg("registry has no publish dates, cooldown not applied")
+		days = 0
+	}
+	if version := pickProviderVersion(doc, cooldownLimit(days), p, current, bound); version != "" {
+		return version, nil
+	}
+	if days == 0 && !p.constrains() {
+		return "", &boundError{name: namespace + "/" + providerType, bound: bound}
+	}
+	if days == 0 {
+		return "", &policyError{name: namespace + "/" + providerType}
+	}
+	return "", &cooldownError{name: namespace + "/" + providerType, days: days}
+}
+
+// dockerEpoch is when Docker was first released. Reproducible builds set an
+// image's created time to the Unix epoch, or leave it zero, so a time before
+// this says nothing about when the image was built.
+var dockerEpoch = time.Date(2013, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+type unknownCreatedError struct {
+	image   string
+	created time.Time
+}
+
+func (e *unknownCreatedError) Error() string {
+	return fmt.Sprintf("%s records no build time (created %s)", e.image, e.created.UTC().Format(time.RFC3339))
+}
+
+// imageCreated returns the creation time in an image's config, or an
+// unknownCreatedError when the image predates dockerEpoch.
+func imageCreated(repoStr, tag string, opts []remote.Option) (time.Time, error) {
+	return recorded("registry:created "+repoStr+":"+tag, func() (time.Time, error) {
+		ref, err := name.ParseReference(repoStr + ":" + tag)
+		if err != nil {
+			return time.Time{}, err
+		}
+		img, err := remote.Image(ref, opts...)
+		if err != nil {
+			return time.Time{}, err
+		}
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if cfg.Created.Time.Before(dockerEpoch) {
+			return time.Time{}, &unknownCreatedError{image: repoStr + ":" + tag, created: cfg.Created.Time}
+		}
+		return cfg.Created.Time, nil
+	})
+}
+
+// imageTagOlderThan returns the highest stable tag of an image built at least
+// days ago.
+func imageTagOlderThan(repoStr string, tags []string, days uint) (string, error) {
+	opts := []remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)}
+	return newestPublishedBefore(repoStr, days, stableVersionsDesc(tags), func(tag string) (time.Time, error) {
+		return imageCreated(repoStr, tag, opts)
+	})
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const (
+	cooledSHA  = "3333333333333333333333333333333333333333"
+	freshSHA   = "4444444444444444444444444444444444444444"
+	agedTagSHA = "5555555555555555555555555555555555555555"
+)
+
+// fakeCooldownGitHub serves org/hook, whose newest release is a day old and
+// whose v1.5.0 is a month old, and org/tagged, which has no releases and
+// whose tags are dated by their commits.
+func fakeCooldownGitHub(t *testing.T) {
+	t.Helper()
+	ago := func(days int) string { return time.Now().AddDate(0, 0, -days).UTC().Format(time.RFC3339) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/org/hook/releases", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `[{"tag_name":"v2.0.0","published_at":%q},{"tag_name":"v1.5.0","published_at":%q}]`, ago(1), ago(30))
+	})
+	mux.HandleFunc("/api/v3/repos/org/hook/git/ref/tags/v1.5.0", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"object":{"sha":"%s","type":"commit"}}`, cooledSHA)
+	})
+	mux.HandleFunc("/api/v3/repos/org/tagged/releases", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/api/v3/repos/org/tagged/tags", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `[{"name":"v3.1.0-rc1","commit":{"sha":"%s"}},{"name":"v2.9.0","commit":{"sha":"%s"}},`+
+			`{"name":"v3.0.0","commit":{"sha":"%s"}}]`, freshSHA, agedTagSHA, freshSHA)
+	})
+	mux.HandleFunc("/api/v3/repos/org/tagged/commits/"+freshSHA, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"commit":{"committer":{"date":%q}}}`, ago(2))
+	})
+	mux.HandleFunc("/api/v3/repos/org/tagged/commits/"+agedTagSHA, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"commit":{"committer":{"date":%q}}}`, ago(20))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL + "/api/v3")
+}
+
+func TestFlags_Cooldown(t *testing.T) {
+	t.Parallel()
+
+	var zero, stable uint = 0, 3
+	cfg := map[string]uint{CooldownDefault: 7, SourcePreCommit: 14}
+	tests := []struct {
+		name      string
+		days      *uint
+		cooldown  map[string]uint
+		ecosystem string
+		want      uint
+	}{
+		{name: "nothing set", ecosystem: SourceGHA},
+		{name: "ecosystem", days: &zero, cooldown: cfg, ecosystem: SourcePreCommit, want: 14},
+		{name: "default", days: &zero, cooldown: cfg, ecosystem: SourceGHA, want: 7},
+		{name: "--stable wins", days: &stable, cooldown: cfg, ecosystem: SourcePreCommit, want: 3},
+	}
+	for _, tt := range tests {
+		f := &Flags{Days: tt.days, Cooldown: tt.cooldown}
+		if got := f.cooldown(tt.ecosystem); got != tt.want {
+			t.Errorf("%s: cooldown(%q) = %d, want %d", tt.name, tt.ecosystem, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_Cooldown(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	content := "cooldown:\n  default: 7\n  terraform-provider: 30\n"
+	if err := os.WriteFile(filepath.Join(dir, ".ghat.yml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := LoadConfig(dir).Cooldown
+	if got[CooldownDefault] != 7 || got[SourceProvider] != 30 {
+		t.Errorf("Cooldown = %v, want default 7 and terraform-provider 30", got)
+	}
+}
+
+func TestStableVersionsDesc(t *testing.T) {
+	t.Parallel()
+
+	got := stableVersionsDesc([]string{"v1.2.0", "latest", "v1.10.0", "2.0.0-rc1", "v1.9", "3.19"})
+	want := []string{"3.19", "v1.10.0", "v1.9", "v1.2.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stableVersionsDesc() = %q, want %q", got, want)
+	}
+}
+
+func TestPickProviderVersion(t *testing.T) {
+	t.Parallel()
+
+	ago := func(days int) string { return time.Now().AddDate(0, 0, -days).UTC().Format(time.RFC3339) }
+	body := fmt.Sprintf(`{"included":[
+		{"type":"provider-versions","attributes":{"version":"5.2.0","published-at":%q}},
+		{"type":"provider-versions","attributes":{"version":"5.1.0","published-at":%q}},
+		{"type":"provider-versions","attributes":{"version":"5.1.1-beta1","published-at":%q}},
+		{"type":"provider-versions","attributes":{"version":"4.67.0","published-at":%q}},
+		{"type":"provider-platforms","attributes":{"version":"9.0.0","published-at":%q}}]}`,
+		ago(2), ago(10), ago(12), ago(40), ago(40))
+	var doc providerVersionsV2
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pickProviderVersion(doc, cooldownLimit(7), nil, "", ""); got != "5.1.0" {
+		t.Errorf("pickProviderVersion(7 days) = %q, want 5.1.0", got)
+	}
+	if got := pickProviderVersion(doc, cooldownLimit(60), nil, "", ""); got != "" {
+		t.Errorf("pickProviderVersion(60 days) = %q, want none", got)
+	}
+}
+
+func TestGithubTagOlderThan(t *testing.T) {
+	fakeCooldownGitHub(t)
+
+	tests := []struct {
+		action string
+		sha    string
+		tag    string
+	}{
+		{action: "org/hook", sha: cooledSHA, tag: "v1.5.0"},
+		{action: "org/tagged", sha: agedTagSHA, tag: "v2.9.0"},
+	}
+	for _, tt := range tests {
+		sha, tag, err := githubTagOlderThan(tt.action, "", 7)
+		if err != nil || sha != tt.sha || tag != tt.tag {
+			t.Errorf("githubTagOlderThan(%s) = (%s, %s, %v), want (%s, %s)", tt.action, sha, tag, err, tt.sha, tt.tag)
+		}
+	}
+	if _, _, err := githubTagOlderThan("org/tagged", "", 90); err == nil {
+		t.Error("githubTagOlderThan(90 days) should fail when nothing is that old")
+	}
+}
+
+func TestFlags_UpdateHooks_Cooldown(t *testing.T) {
+	fakeCooldownGitHub(t)
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, PreCommitConfigFile)
+	content := "repos:\n  - repo: https://github.com/org/hook\n    rev: v1.0.0\n    hooks:\n      - id: lint\n" +
+		"  - repo: https://git.example.com/org/other\n    rev: v0.1.0\n    hooks:\n      - id: fmt\n"
+	if err := os.WriteFile(config, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var days uint
+	f := &Flags{Directory: dir, Days: &days, Cooldown: map[string]uint{SourcePreCommit: 7}, Silent: true}
+	if err := f.UpdateHooks(); err != nil {
+		t.Fatalf("UpdateHooks() error = %v", err)
+	}
+	got, _ := os.ReadFile(config)
+	if !strings.Contains(string(got), "rev: "+cooledSHA+" # v1.5.0") || !strings.Contains(string(got), "rev: v0.1.0") {
+		t.Errorf("UpdateHooks() wrote\n%s", got)
+	}
+}
+
+func TestImageTagOlderThan_EpochCreated(t *testing.T) {
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+	repo := strings.TrimPrefix(srv.URL, "http://") + "/team/app"
+
+	push := func(tag string, created time.Time) {
+		t.Helper()
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img, err = mutate.CreatedAt(img, v1.Time{Time: created}); err != nil {
+			t.Fatal(err)
+		}
+		ref, err := name.ParseReference(repo + ":" + tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push("1.2.0", time.Unix(0, 0))
+	push("1.1.0", time.Unix(315532800, 0)) // 1980-01-01, the zip epoch
+	push("1.0.0", time.Now().AddDate(0, 0, -60))
+
+	got, err := imageTagOlderThan(repo, []string{"1.0.0", "1.1.0", "1.2.0"}, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "1.0.0" {
+		t.Errorf("imageTagOlderThan() = %q, want 1.0.0, skipping the tags without a build time", got)
+	}
+}
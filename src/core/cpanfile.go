@@ -80,6 +80,14 @@ func parseCpanfile(data string) []cpanDep {
 // already pin with `==`, carry named options (`=>`), or are suppressed are
 // left alone.
 func rewriteCpanfile(data string, pins map[string]string) string {
+	out, _ := rewriteCpanfileChanges(data, pins)
+	return out
+}
+
+// rewriteCpanfileChanges is rewriteCpanfile that also returns a Change
+// (without File) for every line it pins.
+func rewriteCpanfileChanges(data string, pins map[string]string) (string, []Change) {
+	var changes []Change
 	lines := strings.Split(data, "\n")
 	for i, line := range lines {
 		m := cpanRequireRE.FindStringSubmatch(line)
@@ -98,12 +106,20 @@ func rewriteCpanfile(data string, pins map[string]string) string {
 			continue
 		}
 		lines[i] = m[1] + m[2] + m[3] + m[2] + ", '== " + ver + "';" + m[5]
+		changes = append(changes, Change{Line: i + 1, Ecosystem: SourceCpanfile, Name: m[3],
+			OldRef: ExtractCpanVersion(m[4]), NewRef: "== " + ver, Tag: ver})
 	}
-	return strings.Join(lines, "\n")
+	return strings.Join(lines, "\n"), changes
 }
 
 func getMetaCPANVersion(module string) (string, error) {
-	client := &http.Client{Timeout: 30 * time.Second}
+	return resolve("metacpan "+module, func() (string, error) {
+		return fetchMetaCPANVersion(module)
+	})
+}
+
+func fetchMetaCPANVersion(module string) (string, error) {
+	client := lookupClient(30 * time.Second)
 	req, err := http.NewRequest(http.MethodGet, metaCPANModuleURL+module, nil)
 	if err != nil {
 		return "", &requestFailedError{err: err}
@@ -173,7 +189,11 @@ func (f *Flags) UpdateCpanfile() error {
 		pins[d.module] = ver
 	}
 
-	replacement := rewriteCpanfile(string(data), pins)
+	replacement, changes := rewriteCpanfileChanges(string(data), pins)
+	for _, c := range changes {
+		c.File = config
+		f.recordChange(c)
+	}
 
 	f.printDiff(config, string(data), replacement)
 
@@ -9,7 +9,8 @@ import (
 
 // printDiff prints a coloured diff only when before and after differ.
 // When f.Silent is set (e.g. bulk org mode) diffs are suppressed and only
-// the log line is emitted so output stays readable at scale.
+// the log line is emitted so output stays readable at scale. The same
+// applies when a --report is being written to stdout.
 func (f *Flags) printDiff(file, before, after string) {
 	if before == after {
 		if !f.Silent {
@@ -17,7 +18,7 @@ func (f *Flags) printDiff(file, before, after string) {
 		}
 		return
 	}
-	if f.Silent {
+	if f.Silent || f.reportToStdout() {
 		log.Warn().Str("file", file).Msg("updated")
 		return
 	}
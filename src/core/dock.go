@@ -22,16 +22,16 @@ var argDefaultRe = regexp.MustCompile(`(?i)^ARG\s+(\w+)=(\S+)`)
 
 // UpdateDockerfiles pins FROM image references in all Dockerfiles found in the entries.
 func (f *Flags) UpdateDockerfiles() error {
-	for _, file := range f.GetDockerfiles() {
+	return eachFile(f.GetDockerfiles(), func(file string) error {
 		if err := f.UpdateDockerfile(file); err != nil {
 			if f.ContinueOnError {
 				log.Warn().Err(err).Str("file", file).Msg("skipping file")
-				continue
+				return nil
 			}
 			return err
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // UpdateDockerfile pins FROM image references in a single Dockerfile to SHA digests.
@@ -90,13 +90,18 @@ func (f *Flags) UpdateDockerfile(file string) error {
 		}
 
 		imgRef := parseImageReference(bareResolved)
+		if f.frozen(SourceImage, imageDisplayName(imgRef)) {
+			continue
+		}
 		digest, err := f.getImageDigest(&imgRef)
 		if err != nil {
 			log.Warn().Err(err).Str("image", bareResolved).Msg("failed to get digest, skipping")
 			continue
 		}
 
-		if cur, ok := pinned[imgRef.Tag]; ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag) {
+		cur, ok := pinned[imgRef.Tag]
+		mutation := ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag)
+		if mutation {
 			log.Warn().Msgf("SUSPICIOUS: %s — digest changed from %s to %s with the same tag. "+
 				"The image tag may have been repointed. Verify before accepting.", bareResolved, cur, digest)
 		}
@@ -109,7 +114,15 @@ func (f *Flags) UpdateDockerfile(file string) error {
 		} else {
 			newImageStr = formatDockerImage(imgRef, digest)
 		}
-		lines[i] = prefix + newImageStr + alias
+		if newLine := prefix + newImageStr + alias; newLine != line {
+			oldRef := imgRef.Tag
+			if at := strings.Index(resolvedStr, "@"); at >= 0 {
+				oldRef = resolvedStr[at+1:]
+			}
+			f.recordChange(Change{File: file, Line: i + 1, Ecosystem: SourceDockerfile, Name: imageDisplayName(imgRef),
+				OldRef: oldRef, NewRef: digest, Tag: imgRef.Tag, Mutation: mutation})
+			lines[i] = newLine
+		}
 	}
 
 	replacement := strings.Join(lines, "\n")
@@ -11,7 +11,6 @@ import (
 
 const (
 	dayInNanos int64 = 24 * 60 * 60 * 1000 * 1000 * 1000
-	apiBaseURL       = "https://api.github.com/repos/"
 )
 
 const (
@@ -52,7 +51,7 @@ func GetReleases(action string, gitHubToken string, days *uint) (map[string]inte
 	interval := time.Duration(int64(*days) * dayInNanos)
 	limit := now.Add(-interval)
 
-	url := apiBaseURL + action + "/releases"
+	url := repoAPI(action) + "/releases"
 
 	// Retry logic with exponential backoff
 	var temp interface{}
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Stable rule ids shared by the SARIF output and the editor diagnostics.
+const (
+	RulePin              = "ghat-pin"
+	RulePermissions      = "ghat-permissions"
+	RuleDangerousTrigger = "ghat-dangerous-trigger"
+	RuleUnpinnedInstall  = "ghat-unpinned-install"
+	RuleAuditRisk        = "ghat-audit-risk"
+	RuleAuditStale       = "ghat-audit-stale"
+	RuleImpostorCommit   = "ghat-impostor-commit"
+	RulePinComment       = "ghat-pin-comment"
+	RuleArtifactPoison   = "ghat-artifact-poisoning"
+	RuleCachePoison      = "ghat-cache-poisoning"
+	RuleSelfHostedRunner = "ghat-self-hosted-runner"
+	RuleActionInputs     = "ghat-action-inputs"
+	RuleRetiredRuntime   = "ghat-retired-runtime"
+)
+
+// Finding levels, named as SARIF names them.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Finding is a single result from ghat's static analyses or from audit.
+type Finding struct {
+	Rule    string
+	Level   string
+	File    string // path as scanned; reporters make it relative
+	Line    int    // 1-indexed; 0 means the whole file
+	Message string
+	// Suppressed is true when the finding's line carries # ghat:suppress;
+	// SuppressReason is its reason= value, if any.
+	Suppressed     bool
+	SuppressReason string
+}
+
+// AnalyzeFile runs the static checks that apply to path — the same rules the
+// editor diagnostics use — and returns their findings, including suppressed
+// ones. No network calls are made.
+func AnalyzeFile(path string, content []byte) []Finding {
+	var findings []Finding
+
+	kind, ok := ClassifyManifest(path)
+	if !ok && isYAMLFile(path) && HasKubeResource(content) {
+		kind, ok = ManifestKube, true
+	}
+	if ok {
+		if kind == ManifestGHA {
+			findings = WorkflowFindings(filepath.Base(path), content)
+		} else {
+			findings = ManifestFindings(kind, ParseManifest(kind, content))
+		}
+	}
+
+	if isRunInstallTarget(path) {
+		for _, hit := range runInstallHits(content) {
+			findings = append(findings, Finding{
+				Rule: RuleUnpinnedInstall, Level: LevelWarning, Line: hit.line,
+				Message: "unpinned install in script: " + hit.label,
+			})
+		}
+	}
+
+	for i := range findings {
+		findings[i].File = path
+	}
+	return markSuppressed(content, findings)
+}
+
+// StaticFindings runs AnalyzeFile over every scanned entry.
+func (f *Flags) StaticFindings() []Finding {
+	var findings []Finding
+	for _, file := range f.Entries {
+		content, err := os.ReadFile(file) // #nosec G304
+		if err != nil {
+			continue
+		}
+		findings = append(findings, AnalyzeFile(file, content)...)
+	}
+	return findings
+}
+
+func isYAMLFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == yamlExtension || ext == yamlAltExtension
+}
+
+// moduleRefSHARe matches a git module source already pinned to an immutable
+// commit SHA via ?ref=<40-hex>, the format ghat itself writes (with the tag
+// recorded as a trailing "# vX.Y.Z" comment rather than a version attribute).
+var moduleRefSHARe = regexp.MustCompile(`\?ref=[0-9a-f]{40}\b`)
+
+// WorkflowFindings runs AnalyzeWorkflow and reports missing or write-all
+// permissions, dangerous triggers, each untrusted-input path, artifact and
+// cache poisoning, self-hosted runners outsiders can reach and unpinned
+// uses: steps. filename is only used in messages.
+func WorkflowFindings(filename string, content []byte) []Finding {
+	wa := AnalyzeWorkflow(filename, content)
+	var findings []Finding
+
+	if !wa.HasPermissions {
+		findings = append(findings, Finding{Rule: RulePermissions, Level: LevelWarning,
+			Message: "workflow missing top-level permissions: block (default GITHUB_TOKEN is write-all)"})
+	} else if wa.IsWriteAll {
+		findings = append(findings, Finding{Rule: RulePermissions, Level: LevelError, Line: wa.WriteAllLine,
+			Message: "permissions: write-all grants the GITHUB_TOKEN full repository write access"})
+	}
+	// Each taint path is its own finding; HasDangerousTrigger only adds the
+	// pull_request_target checkout, when there is one.
+	if wa.HasDangerousTrigger && (len(wa.TaintPaths) == 0 || wa.DangerousTriggerLine != wa.TaintPaths[0].Line) {
+		findings = append(findings, Finding{Rule: RuleDangerousTrigger, Level: LevelError,
+			Line: wa.DangerousTriggerLine, Message: wa.DangerousTriggerDesc})
+	}
+	for _, p := range wa.TaintPaths {
+		findings = append(findings, Finding{Rule: RuleDangerousTrigger, Level: LevelError,
+			Line: p.Line, Message: p.Message()})
+	}
+	for _, r := range wa.ArtifactPoisoning {
+		findings = append(findings, Finding{Rule: RuleArtifactPoison, Level: LevelError, Line: r.Line, Message: r.Desc})
+	}
+	for _, r := range wa.CachePoisoning {
+		findings = append(findings, Finding{Rule: RuleCachePoison, Level: LevelError, Line: r.Line, Message: r.Desc})
+	}
+	// Whether the repository is public is unknown offline, so self-hosted
+	// runners are only reported where an outsider can start the workflow.
+	if trigger := firstTrigger(wa.Triggers, outsiderTriggers); trigger != "" {
+		for _, r := range wa.SelfHostedRunners {
+			findings = append(findings, Finding{Rule: RuleSelfHostedRunner, Level: LevelWarning, Line: r.Line,
+				Message: r.Desc + " but is triggered by " + trigger + "; in a public repository outsiders can run code on it"})
+		}
+	}
+	for _, step := range wa.Steps {
+		if step.IsSHAPinned {
+			continue
+		}
+		ref := step.Action
+		if r, _, _ := strings.Cut(step.Ref, "#"); strings.TrimSpace(r) != "" {
+			ref = step.Action + "@" + strings.TrimSpace(r)
+		}
+		findings = append(findings, Finding{Rule: RulePin, Level: LevelWarning, Line: step.Line,
+			Message: ref + " is not pinned to an immutable SHA", Suppressed: step.Suppressed})
+	}
+	for _, step := range parseDockerSteps(content) {
+		if strings.Contains(step.image, "@sha256:") {
+			continue
+		}
+		name, tag := splitImageTag(step.image)
+		findings = append(findings, Finding{Rule: RulePin, Level: LevelWarning, Line: step.line,
+			Message:    "docker://" + name + ":" + imageTag(tag) + " is not pinned to an immutable digest (@sha256:...)",
+			Suppressed: step.suppressed})
+	}
+	return findings
+}
+
+// ManifestFindings reports the dependency refs of a non-workflow manifest
+// that are not pinned to an immutable SHA, digest or exact version.
+func ManifestFindings(kind ManifestKind, refs []DepRef) []Finding {
+	var findings []Finding
+	pin := func(line int, msg string) {
+		findings = append(findings, Finding{Rule: RulePin, Level: LevelWarning, Line: line, Message: msg})
+	}
+
+	for _, ref := range refs {
+		switch kind {
+		case ManifestPreCommit:
+			if !IsSHAPinnedRef(ref.Version) {
+				pin(ref.Line, ref.Name+" rev "+ref.Version+" is not pinned to an immutable SHA")
+			}
+		case ManifestDockerfile, ManifestKube, ManifestCompose:
+			if !strings.HasPrefix(ref.Version, "sha256:") {
+				pin(ref.Line, ref.Name+":"+imageTag(ref.Version)+" is not pinned to an immutable digest (@sha256:...)")
+			}
+		case ManifestGitLab:
+			switch ref.Ecosystem {
+			case SourceGitLabComponent:
+				if !IsSHAPinnedRef(ref.Version) {
+					pin(ref.Line, ref.Name+"@"+ref.Version+" is not pinned to an immutable commit SHA")
+				}
+			case SourceGitLab:
+				if !strings.HasPrefix(ref.Version, "sha256:") {
+					pin(ref.Line, ref.Name+":"+imageTag(ref.Version)+" is not pinned to an immutable digest (@sha256:...)")
+				}
+			}
+		case ManifestTerraform:
+			if ref.Version == "" {
+				if moduleRefSHARe.MatchString(ref.Name) {
+					// Already pinned to an immutable SHA (ghat writes the tag as a
+					// trailing comment, not a version attribute) — nothing to flag.
+					continue
+				}
+				pin(ref.Line, ref.Name+" has no version constraint — run ghat to pin")
+				continue
+			}
+			if hasVersionConstraintOperator(ref.Version) {
+				pin(ref.Line, ref.Name+" uses version constraint "+ref.Version+" instead of an exact pin")
+			}
+		case ManifestCpanfile:
+			if strings.Contains(ref.Version, "==") {
+				continue
+			}
+			desc := ref.Name
+			if ref.Version != "" {
+				desc += " (" + ref.Version + ")"
+			}
+			pin(ref.Line, desc+" is not pinned to an exact version (use == <ver>)")
+		}
+	}
+	return findings
+}
+
+// imageTag returns tag, or "latest" when the image reference had none.
+func imageTag(tag string) string {
+	if tag == "" {
+		return "latest"
+	}
+	return tag
+}
+
+func hasVersionConstraintOperator(v string) bool {
+	for _, op := range []string{"~>", ">=", "<=", ">", "<", "!="} {
+		if strings.Contains(v, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// markSuppressed flags each finding whose line carries # ghat:suppress,
+// keeping the annotation's reason.
+func markSuppressed(content []byte, findings []Finding) []Finding {
+	lines := strings.Split(string(content), "\n")
+	for i := range findings {
+		l := findings[i].Line
+		if l < 1 || l > len(lines) {
+			continue
+		}
+		findings[i].Suppressed, findings[i].SuppressReason = parseSuppression(lines[l-1])
+	}
+	return findings
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyzeFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    []Finding
+	}{
+		{
+			name: "workflow",
+			path: ".github/workflows/ci.yml",
+			content: "on: pull_request_target\n" +
+				"permissions: write-all\n" +
+				"jobs:\n  build:\n    steps:\n" +
+				"      - uses: actions/checkout@v4 # ghat:suppress:reason=trusted\n" +
+				"      - uses: actions/setup-go@" + includeSHA + " # v5.0.0\n" +
+				"      - uses: actions/cache@v4\n" +
+				"      - run: curl -sSL https://example.com/install.sh | sh\n",
+			want: []Finding{
+				{Rule: RulePermissions, Level: LevelError, Line: 2},
+				{Rule: RulePin, Level: LevelWarning, Line: 6, Suppressed: true, SuppressReason: "trusted",
+					Message: "actions/checkout@v4 is not pinned to an immutable SHA"},
+				{Rule: RulePin, Level: LevelWarning, Line: 8, Message: "actions/cache@v4 is not pinned to an immutable SHA"},
+				{Rule: RuleUnpinnedInstall, Level: LevelWarning, Line: 9},
+			},
+		},
+		{
+			name: "docker steps",
+			path: ".github/workflows/docker.yml",
+			content: "permissions: read-all\njobs:\n  build:\n    steps:\n" +
+				"      - uses: docker://alpine:3.20\n" +
+				"      - uses: docker://alpine@sha256:abc # 3.20\n" +
+				"      - uses: 'docker://node' # ghat:suppress\n",
+			want: []Finding{
+				{Rule: RulePin, Level: LevelWarning, Line: 5,
+					Message: "docker://alpine:3.20 is not pinned to an immutable digest (@sha256:...)"},
+				{Rule: RulePin, Level: LevelWarning, Line: 7, Suppressed: true,
+					Message: "docker://node:latest is not pinned to an immutable digest (@sha256:...)"},
+			},
+		},
+		{
+			name:    "missing permissions",
+			path:    ".github/workflows/lint.yml",
+			content: "on: push\njobs:\n  lint:\n    steps:\n      - run: make lint\n",
+			want:    []Finding{{Rule: RulePermissions, Level: LevelWarning, Line: 0}},
+		},
+		{
+			name:    "dockerfile",
+			path:    "Dockerfile",
+			content: "FROM golang:1.22 AS build\nFROM alpine:3.20@sha256:abc\n",
+			want: []Finding{{Rule: RulePin, Level: LevelWarning, Line: 1,
+				Message: "golang:1.22 is not pinned to an immutable digest (@sha256:...)"}},
+		},
+		{
+			name: "gitlab",
+			path: ".gitlab-ci.yml",
+			content: "include:\n  - project: group/templates\n    ref: main\n" +
+				"build:\n  image: golang:1.22\n  script: go build\n",
+			want: []Finding{
+				{Rule: RulePin, Level: LevelWarning, Line: 3,
+					Message: "group/templates@main is not pinned to an immutable commit SHA"},
+				{Rule: RulePin, Level: LevelWarning, Line: 5,
+					Message: "golang:1.22 is not pinned to an immutable digest (@sha256:...)"},
+			},
+		},
+		{
+			name:    "kubernetes by content",
+			path:    "deploy/app.yaml",
+			content: "apiVersion: v1\nkind: Pod\nspec:\n  containers:\n    - image: nginx:1.27 # ghat:suppress\n",
+			want:    []Finding{{Rule: RulePin, Level: LevelWarning, Line: 5, Suppressed: true}},
+		},
+		{
+			name:    "terraform",
+			path:    "main.tf",
+			content: "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"~> 5.0\"\n}\n",
+			want: []Finding{{Rule: RulePin, Level: LevelWarning, Line: 2,
+				Message: "terraform-aws-modules/vpc/aws uses version constraint ~> 5.0 instead of an exact pin"}},
+		},
+		{
+			name:    "not analysed",
+			path:    "main.go",
+			content: "package main\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := AnalyzeFile(tt.path, []byte(tt.content))
+			if len(got) != len(tt.want) {
+				t.Fatalf("AnalyzeFile() returned %d findings, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Rule != w.Rule || g.Level != w.Level || g.Line != w.Line || g.File != tt.path ||
+					g.Suppressed != w.Suppressed || g.SuppressReason != w.SuppressReason ||
+					(w.Message != "" && g.Message != w.Message) {
+					t.Errorf("finding %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestFlags_LocateDeps(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	if err := os.MkdirAll(filepath.Dir(workflow), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(workflow, []byte("jobs:\n  a:\n    steps:\n      - uses: actions/checkout@v4\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gomod := filepath.Join(dir, "go.mod")
+	if err := os.WriteFile(gomod, []byte("module x\n\nrequire github.com/rs/zerolog v1.33.0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Flags{Entries: []string{workflow, gomod}}
+	deps := []dep{
+		{source: SourceGHA, label: "actions/checkout"},
+		{source: SourceGo, label: "github.com/rs/zerolog"},
+		{source: SourceGo, label: "golang.org/x/sys"},
+		{source: SourceNpm, label: "lodash"},
+	}
+	f.locateDeps(deps)
+
+	want := []depSite{{workflow, 4}, {gomod, 3}, {gomod, 0}, {"", 0}}
+	for i, w := range want {
+		if deps[i].file != w.file || deps[i].line != w.line {
+			t.Errorf("dep %s located at %s:%d, want %s:%d", deps[i].label, deps[i].file, deps[i].line, w.file, w.line)
+		}
+	}
+}
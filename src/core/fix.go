@@ -0,0 +1,470 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// FixRuleInjection is the ghat fix rule that moves github.event expressions
+// out of run: scripts into step env: entries.
+const FixRuleInjection = "injection"
+
+// eventExprRe matches a ${{ }} expression that is a plain github.event
+// property path, the form the injection fix can move into an env var.
+var eventExprRe = regexp.MustCompile(`\$\{\{\s*(github\.event(?:\.[A-Za-z0-9_-]+|\[[^\]]*\])+)\s*\}\}`)
+
+// heredocRe matches a here-document redirect and its delimiter.
+var heredocRe = regexp.MustCompile(`^<<-?\s*(['"]?)([A-Za-z_][A-Za-z0-9_]*)['"]?`)
+
+type fixRuleError struct {
+	rule string
+}
+
+func (e *fixRuleError) Error() string {
+	return fmt.Sprintf("unknown fix rule %q, supported rules: %s", e.rule, FixRuleInjection)
+}
+
+type fixParseError struct {
+	file string
+	err  error
+}
+
+func (e *fixParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s: %v", e.file, e.err)
+}
+
+// scriptShell is the syntax a run: script is interpolated into.
+type scriptShell int
+
+const (
+	shellUnsupported scriptShell = iota
+	shellPOSIX
+	shellPwsh
+)
+
+// stepShell classifies a step's shell: its own shell:, else the job or
+// workflow defaults, else pwsh on Windows runners and bash elsewhere.
+func stepShell(shell string, windows bool) scriptShell {
+	name, _, _ := strings.Cut(strings.TrimSpace(shell), " ")
+	switch {
+	case name == "" && windows, name == "pwsh", name == "powershell":
+		return shellPwsh
+	case name == "", name == "bash", name == "sh":
+		return shellPOSIX
+	}
+	return shellUnsupported
+}
+
+// envName derives an env var name from a github.event property path, e.g.
+// github.event.pull_request.title → PR_TITLE.
+func envName(path string) string {
+	path = strings.TrimPrefix(path, "github.event")
+	path = strings.Replace(path, ".pull_request.", ".pr.", 1)
+	if strings.HasSuffix(path, ".pull_request") {
+		path = strings.TrimSuffix(path, "pull_request") + "pr"
+	}
+	var b strings.Builder
+	for _, r := range path {
+		if r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToUpper(r))
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+			b.WriteByte('_')
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "EVENT_" + name
+	}
+	return name
+}
+
+// stepEnv assigns env var names to the github.event expressions moved out
+// of one step, reusing entries the step already declares.
+type stepEnv struct {
+	existing map[string]string // name → value already in env:
+	names    map[string]string // expression path → name
+	added    []string          // names to add, in first-use order
+}
+
+func newStepEnv(env *yaml.Node) *stepEnv {
+	s := &stepEnv{existing: map[string]string{}, names: map[string]string{}}
+	if env != nil {
+		for i := 1; i < len(env.Content); i += 2 {
+			s.existing[env.Content[i-1].Value] = env.Content[i].Value
+		}
+	}
+	return s
+}
+
+// name returns the env var carrying path, allocating one if needed.
+func (s *stepEnv) name(path string) string {
+	if name, ok := s.names[path]; ok {
+		return name
+	}
+	base := envName(path)
+	name := base
+	for n := 2; ; n++ {
+		value, taken := s.existing[name]
+		if !taken {
+			s.existing[name] = "${{ " + path + " }}"
+			s.added = append(s.added, name)
+			break
+		}
+		if m := eventExprRe.FindStringSubmatch(value); m != nil && strings.TrimSpace(value) == m[0] && m[1] == path {
+			break
+		}
+		name = base + "_" + strconv.Itoa(n)
+	}
+	s.names[path] = name
+	return name
+}
+
+// replaceEventExprs swaps each github.event expression in script for a
+// reference to its env var, quoted to suit where it sits: "$NAME" in bare
+// shell words, ${NAME} inside double quotes and unquoted here-documents,
+// '"$NAME"' inside single quotes. Expressions it cannot safely replace, such
+// as those inside a quoted here-document, are left and returned.
+func replaceEventExprs(script string, shell scriptShell, env *stepEnv) (string, []string) {
+	const (
+		bare = iota
+		comment
+		single
+		double
+		heredoc
+		quotedHeredoc
+	)
+	escape := byte('\\')
+	if shell == shellPwsh {
+		escape = '`'
+	}
+
+	var b strings.Builder
+	var left []string
+	state, pending, delimiter := bare, bare, ""
+	for i := 0; i < len(script); {
+		if m := eventExprRe.FindStringSubmatchIndex(script[i:]); m != nil && m[0] == 0 {
+			expr, path := script[i:i+m[1]], script[i+m[2]:i+m[3]]
+			switch {
+			case state == quotedHeredoc, shell == shellPwsh && state == single:
+				left = append(left, path)
+				b.WriteString(expr)
+			case shell == shellPwsh:
+				b.WriteString("$env:" + env.name(path))
+			case state == single:
+				b.WriteString(`'"$` + env.name(path) + `"'`)
+			case state == double, state == heredoc:
+				b.WriteString("${" + env.name(path) + "}")
+			default:
+				b.WriteString(`"$` + env.name(path) + `"`)
+			}
+			i += m[1]
+			continue
+		}
+
+		c := script[i]
+		switch state {
+		case bare:
+			switch {
+			case c == escape || c == '<' && strings.HasPrefix(script[i:], "<<<"):
+				// Copy the escaped character, or the here-string operator, as is.
+				n := min(len(script), i+2)
+				if c == '<' {
+					n = i + 3
+				}
+				b.WriteString(script[i:n])
+				i = n
+				continue
+			case c == '\'':
+				state = single
+			case c == '"':
+				state = double
+			case c == '#' && (i == 0 || unicode.IsSpace(rune(script[i-1]))):
+				state = comment
+			case c == '\n' && pending != bare:
+				state, pending = pending, bare
+			case c == '<' && shell == shellPOSIX:
+				if m := heredocRe.FindStringSubmatch(script[i:]); m != nil {
+					// The body starts on the next line.
+					delimiter, pending = m[2], heredoc
+					if m[1] != "" {
+						pending = quotedHeredoc
+					}
+					b.WriteString(m[0])
+					i += len(m[0])
+					continue
+				}
+			}
+		case comment:
+			if c == '\n' {
+				state = bare
+				if pending != bare {
+					state, pending = pending, bare
+				}
+			}
+		case single:
+			if c == '\'' {
+				state = bare
+			}
+		case double:
+			if c == escape {
+				b.WriteString(script[i:min(len(script), i+2)])
+				i += 2
+				continue
+			}
+			if c == '"' {
+				state = bare
+			}
+		case heredoc, quotedHeredoc:
+			if script[i-1] == '\n' {
+				line, _, _ := strings.Cut(script[i:], "\n")
+				if strings.TrimSpace(line) == delimiter {
+					state = bare
+				}
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String(), left
+}
+
+// quoteScalar renders value as a YAML scalar in the quoting style of the
+// original, falling back to double quotes for multi-line single-quoted text.
+func quoteScalar(value string, style yaml.Style) string {
+	if style&yaml.SingleQuotedStyle != 0 && !strings.Contains(value, "\n") {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return strconv.Quote(value)
+}
+
+// plainSafe reports whether value can stay a plain YAML scalar: it doesn't
+// start with an indicator or contain ": " or " #", which would end it early.
+func plainSafe(value string) bool {
+	if value == "" || strings.ContainsAny(value[:1], "\"'!&*-?:,[]{}#|>@%` \t") {
+		return false
+	}
+	return !strings.Contains(value, ": ") && !strings.Contains(value, " #") &&
+		!strings.HasSuffix(value, ":") && !strings.HasSuffix(value, " ")
+}
+
+// scalarEnd returns the 0-indexed line after the value of key, found by
+// indentation: the value runs until a line indented no deeper than key.
+func scalarEnd(lines []string, key *yaml.Node) int {
+	end := key.Line
+	for i := key.Line; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if trimmed == "" {
+			continue
+		}
+		if len(lines[i])-len(trimmed) <= key.Column-1 {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+// fixStep moves the github.event expressions in one step's run: script into
+// its env:, returning the edits and any expressions it had to leave.
+func fixStep(lines []string, step *yaml.Node, shell scriptShell) ([]lineEdit, []string) {
+	runKey, run := mappingKey(step, "run"), findMappingValue(step, "run")
+	if run == nil || run.Kind != yaml.ScalarNode || !eventExprRe.MatchString(run.Value) {
+		return nil, nil
+	}
+	at := fmt.Sprintf("line %d: ", runKey.Line)
+	var left []string
+	leave := func(paths ...string) {
+		for _, p := range paths {
+			left = append(left, at+p)
+		}
+	}
+	pathsIn := func(s string) []string {
+		var out []string
+		for _, m := range eventExprRe.FindAllStringSubmatch(s, -1) {
+			out = append(out, m[1])
+		}
+		return out
+	}
+
+	envKey, env := mappingKey(step, "env"), findMappingValue(step, "env")
+	if shell == shellUnsupported ||
+		env != nil && (env.Kind != yaml.MappingNode || env.Style&yaml.FlowStyle != 0 && len(env.Content) > 0) {
+		leave(pathsIn(run.Value)...)
+		return nil, left
+	}
+	names := newStepEnv(env)
+
+	start, end := runKey.Line-1, scalarEnd(lines, runKey)
+	var script []string
+	switch {
+	case run.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0:
+		value, unfixed := replaceEventExprs(run.Value, shell, names)
+		leave(unfixed...)
+		script = []string{lines[start][:run.Column-1] + quoteScalar(value, run.Style)}
+	case run.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		body, unfixed := replaceEventExprs(strings.Join(lines[start+1:end], "\n"), shell, names)
+		leave(unfixed...)
+		script = append([]string{lines[start]}, strings.Split(body, "\n")...)
+	default:
+		raw := strings.Join(lines[start:end], "\n")
+		body, unfixed := replaceEventExprs(raw[run.Column-1:], shell, names)
+		leave(unfixed...)
+		script = strings.Split(raw[:run.Column-1]+body, "\n")
+		if value, _ := replaceEventExprs(run.Value, shell, names); !plainSafe(value) {
+			// "$NAME" at the start, or a new ": " or " #", would no longer
+			// read as a plain scalar, so quote the whole value instead.
+			line := lines[start][:run.Column-1] + quoteScalar(value, yaml.DoubleQuotedStyle)
+			if run.LineComment != "" {
+				line += " " + run.LineComment
+			}
+			script = []string{line}
+		}
+	}
+	if len(names.added) == 0 {
+		return []lineEdit{{start: start, end: end, lines: script}}, left
+	}
+
+	var entries []string
+	entry := func(indent string) {
+		for _, name := range names.added {
+			entries = append(entries, indent+name+": "+names.existing[name])
+		}
+	}
+	indent := strings.Repeat(" ", runKey.Column-1)
+	switch {
+	case env != nil && len(env.Content) > 0:
+		entry(strings.Repeat(" ", env.Content[0].Column-1))
+		last := lastLine(env)
+		return []lineEdit{
+			{start: start, end: end, lines: script},
+			{start: last, end: last, lines: entries},
+		}, left
+	case env != nil:
+		// env: {} — replace it with a block mapping.
+		entry(indent + "  ")
+		return []lineEdit{
+			{start: start, end: end, lines: script},
+			{start: envKey.Line - 1, end: envKey.Line, lines: append([]string{indent + "env:"}, entries...)},
+		}, left
+	}
+
+	entry(indent + "  ")
+	block := append([]string{indent + "env:"}, entries...)
+	if strings.TrimSpace(lines[start][:runKey.Column-1]) == "" {
+		// run: is not the step's first key, so env: can go before it.
+		return []lineEdit{{start: start, end: end, lines: append(block, script...)}}, left
+	}
+	return []lineEdit{{start: start, end: end, lines: append(script, block...)}}, left
+}
+
+// FixInjection rewrites every run: step in a workflow or composite action
+// that interpolates a github.event expression into its script, moving the
+// expression into a step env: entry and referencing the variable instead,
+// so the shell never parses attacker-controlled text. Indentation, block
+// scalars and existing env: keys are preserved. It returns the rewritten
+// file and the expressions it had to leave in place, such as steps using a
+// shell other than bash, sh or pwsh.
+func FixInjection(content []byte) (string, []string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return "", nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+
+	var edits []lineEdit
+	var left []string
+	fixSteps := func(steps *yaml.Node, shell string, windows bool) {
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, step := range steps.Content {
+			stepDefault := shell
+			if s := scalarValue(step, "shell"); s != "" {
+				stepDefault = s
+			}
+			e, l := fixStep(lines, step, stepShell(stepDefault, windows))
+			edits = append(edits, e...)
+			left = append(left, l...)
+		}
+	}
+	defaultShell := func(n *yaml.Node, inherited string) string {
+		if s := scalarValue(findMappingValue(findMappingValue(n, "defaults"), "run"), "shell"); s != "" {
+			return s
+		}
+		return inherited
+	}
+
+	if runs := findMappingValue(&root, "runs"); runs != nil {
+		fixSteps(findMappingValue(runs, "steps"), "", false)
+	}
+	if jobs := findMappingValue(&root, "jobs"); jobs != nil && jobs.Kind == yaml.MappingNode {
+		workflowShell := defaultShell(&root, "")
+		for i := 1; i < len(jobs.Content); i += 2 {
+			job := jobs.Content[i]
+			windows := strings.Contains(strings.ToLower(scalarValue(job, "runs-on")), "windows")
+			fixSteps(findMappingValue(job, "steps"), defaultShell(job, workflowShell), windows)
+		}
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		lines = slices.Replace(lines, e.start, e.end, e.lines...)
+	}
+	sort.Strings(left)
+	return strings.Join(lines, "\n"), left, nil
+}
+
+// Fix applies the auto-fix for FixRule to each workflow and action file.
+func (f *Flags) Fix() error {
+	if f.FixRule != FixRuleInjection {
+		return &fixRuleError{f.FixRule}
+	}
+	files := f.GetGHA()
+	if f.File != "" {
+		files = []string{f.File}
+	}
+	for _, file := range files {
+		if err := f.fixInjectionFile(file); err != nil {
+			if f.ContinueOnError {
+				log.Warn().Err(err).Msg("skipping file")
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Flags) fixInjectionFile(file string) error {
+	buffer, err := os.ReadFile(file)
+	if err != nil {
+		return &ghaFileError{file}
+	}
+	replacement, left, err := FixInjection(buffer)
+	if err != nil {
+		return &fixParseError{file: file, err: err}
+	}
+	for _, l := range left {
+		log.Warn().Str("file", file).Msgf("left github.event expression in run: at %s; move it into env: by hand", l)
+	}
+	if replacement == string(buffer) {
+		return nil
+	}
+
+	f.printDiff(file, string(buffer), replacement)
+	if !f.DryRun {
+		if err := os.WriteFile(file, []byte(replacement), 0644); err != nil {
+			return &writeGHAError{file}
+		}
+	}
+	return nil
+}
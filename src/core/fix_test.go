@@ -0,0 +1,159 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFixInjection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		left    []string
+	}{
+		{
+			name: "plain run gets an env block before it",
+			content: "on: pull_request_target\njobs:\n  a:\n    steps:\n" +
+				"      - name: title\n        run: echo ${{ github.event.pull_request.title }}\n",
+			want: "on: pull_request_target\njobs:\n  a:\n    steps:\n" +
+				"      - name: title\n        env:\n          PR_TITLE: ${{ github.event.pull_request.title }}\n" +
+				"        run: echo \"$PR_TITLE\"\n",
+		},
+		{
+			name: "block scalar as the first key keeps quoting context",
+			content: "jobs:\n  a:\n    steps:\n" +
+				"      - run: |\n" +
+				"          echo \"Title: ${{ github.event.issue.title }}\"\n" +
+				"          echo '${{ github.event.issue.title }}' ${{ github.event.issue.body }}\n" +
+				"          # don't\n" +
+				"          cat <<EOF\n          ${{ github.event.comment.body }}\n          EOF\n" +
+				"      - run: make\n",
+			want: "jobs:\n  a:\n    steps:\n" +
+				"      - run: |\n" +
+				"          echo \"Title: ${ISSUE_TITLE}\"\n" +
+				"          echo ''\"$ISSUE_TITLE\"'' \"$ISSUE_BODY\"\n" +
+				"          # don't\n" +
+				"          cat <<EOF\n          ${COMMENT_BODY}\n          EOF\n" +
+				"        env:\n          ISSUE_TITLE: ${{ github.event.issue.title }}\n" +
+				"          ISSUE_BODY: ${{ github.event.issue.body }}\n" +
+				"          COMMENT_BODY: ${{ github.event.comment.body }}\n" +
+				"      - run: make\n",
+		},
+		{
+			name: "merges into existing env",
+			content: "jobs:\n  a:\n    steps:\n" +
+				"      - run: echo ${{ github.event.pull_request.title }} ${{ github.event.pull_request.head.ref }}\n" +
+				"        env:\n            PR_TITLE: ${{ github.event.pull_request.title }}\n            PR_HEAD_REF: main\n",
+			want: "jobs:\n  a:\n    steps:\n" +
+				"      - run: echo \"$PR_TITLE\" \"$PR_HEAD_REF_2\"\n" +
+				"        env:\n            PR_TITLE: ${{ github.event.pull_request.title }}\n            PR_HEAD_REF: main\n" +
+				"            PR_HEAD_REF_2: ${{ github.event.pull_request.head.ref }}\n",
+		},
+		{
+			name: "yaml-quoted run and pwsh",
+			content: "jobs:\n  win:\n    runs-on: windows-latest\n    steps:\n" +
+				"      - run: 'Write-Host \"${{ github.event.issue.title }}\"'\n" +
+				"      - shell: bash\n        run: \"echo ${{ github.event.issue.title }}\"\n",
+			want: "jobs:\n  win:\n    runs-on: windows-latest\n    steps:\n" +
+				"      - run: 'Write-Host \"$env:ISSUE_TITLE\"'\n" +
+				"        env:\n          ISSUE_TITLE: ${{ github.event.issue.title }}\n" +
+				"      - shell: bash\n        env:\n          ISSUE_TITLE: ${{ github.event.issue.title }}\n" +
+				"        run: \"echo \\\"$ISSUE_TITLE\\\"\"\n",
+		},
+		{
+			name: "composite action, unsupported shell and quoted heredoc are left",
+			content: "runs:\n  using: composite\n  steps:\n" +
+				"    - shell: python\n      run: print(\"${{ github.event.issue.title }}\")\n" +
+				"    - shell: bash\n      run: |\n        cat <<'EOF'\n        ${{ github.event.issue.body }}\n        EOF\n",
+			want: "runs:\n  using: composite\n  steps:\n" +
+				"    - shell: python\n      run: print(\"${{ github.event.issue.title }}\")\n" +
+				"    - shell: bash\n      run: |\n        cat <<'EOF'\n        ${{ github.event.issue.body }}\n        EOF\n",
+			left: []string{"line 5: github.event.issue.title", "line 7: github.event.issue.body"},
+		},
+		{
+			name: "plain run starting with the expression is quoted",
+			content: "jobs:\n  a:\n    steps:\n" +
+				"      - run: ${{ github.event.issue.title }} | tee x # log it\n" +
+				"      - run: ${{ github.event.issue.body }}\n",
+			want: "jobs:\n  a:\n    steps:\n" +
+				"      - run: \"\\\"$ISSUE_TITLE\\\" | tee x\" # log it\n" +
+				"        env:\n          ISSUE_TITLE: ${{ github.event.issue.title }}\n" +
+				"      - run: \"\\\"$ISSUE_BODY\\\"\"\n" +
+				"        env:\n          ISSUE_BODY: ${{ github.event.issue.body }}\n",
+		},
+		{
+			name:    "other contexts are untouched",
+			content: "jobs:\n  a:\n    steps:\n      - run: echo ${{ inputs.name }} ${{ toJSON(github.event) }}\n",
+			want:    "jobs:\n  a:\n    steps:\n      - run: echo ${{ inputs.name }} ${{ toJSON(github.event) }}\n",
+		},
+	}
+	for _, tt := range tests {
+		got, left, err := FixInjection([]byte(tt.content))
+		if err != nil {
+			t.Fatalf("%s: FixInjection() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: FixInjection() =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(got), &node); err != nil {
+			t.Errorf("%s: FixInjection() wrote invalid YAML: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(left, tt.left) {
+			t.Errorf("%s: left = %q, want %q", tt.name, left, tt.left)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"github.event.pull_request.title":          "PR_TITLE",
+		"github.event.pull_request":                "PR",
+		"github.event.head_commit.message":         "HEAD_COMMIT_MESSAGE",
+		"github.event.commits[0].author.name":      "COMMITS_0_AUTHOR_NAME",
+		"github.event.review.body":                 "REVIEW_BODY",
+		"github.event.pull_request.head.repo.name": "PR_HEAD_REPO_NAME",
+	}
+	for path, want := range tests {
+		if got := envName(path); got != want {
+			t.Errorf("envName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestFlags_Fix(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	if err := os.MkdirAll(filepath.Dir(workflow), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := "jobs:\n  a:\n    steps:\n      - run: echo ${{ github.event.issue.title }}\n"
+	if err := os.WriteFile(workflow, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Flags{Directory: dir, Entries: []string{workflow}, FixRule: "pin", Silent: true}
+	if err := f.Fix(); err == nil {
+		t.Error("Fix() with an unknown rule should fail")
+	}
+
+	f.FixRule = FixRuleInjection
+	if err := f.Fix(); err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	want := "jobs:\n  a:\n    steps:\n      - run: echo \"$ISSUE_TITLE\"\n        env:\n          ISSUE_TITLE: ${{ github.event.issue.title }}\n"
+	if got, _ := os.ReadFile(workflow); string(got) != want {
+		t.Errorf("Fix() wrote\n%s\nwant\n%s", got, want)
+	}
+}
@@ -35,6 +35,41 @@ type Flags struct {
 	Branch      string
 	PRToken     string
 	HTTPTimeout time.Duration
+
+	GitHubAPIURL string // GitHub REST root; overrides .ghat.yml github_api_url when set
+	GitLabURL    string // GitLab instance for project: includes (default https://gitlab.com)
+	GitLabToken  string // PAT for GitLab API lookups
+
+	Report     string   // "json" to emit a machine-readable change report
+	ReportFile string   // write the report here instead of stdout
+	Changes    []Change // rewrites recorded by the Update* functions
+
+	Format string // findings output: "text" (default) or "sarif"
+	Output string // write --format output here instead of stdout
+	FailOn string // lint: lowest finding level that fails the run ("none" never fails)
+
+	LedgerPath     string  // tag→SHA ledger file; empty disables the ledger
+	Ledger         *Ledger // opened by Action from LedgerPath
+	FailOnMovedTag bool    // fail, rather than warn, when a known tag resolves to a new SHA
+	movedTags      []error
+
+	LockPath string // ghat.lock location; defaults to Directory/ghat.lock
+
+	Offline    bool // resolve only from the cache, ignoring TTL; never touch the network
+	Transitive bool // follow actions into their own action.yml and report what they pull in
+	Tighten    bool // perms: replace jobs' existing permissions: blocks too
+
+	FixRule string // fix: which findings to rewrite ("injection")
+
+	Cooldown map[string]uint // .ghat.yml minimum release age in days, by ecosystem or "default"
+	Policies []Policy        // .ghat.yml per-dependency update policies; the last match wins
+
+	LockPlatforms      []string // shake: os_arch platforms to hash into .terraform.lock.hcl
+	VerifyProviderLock bool     // shake: check .terraform.lock.hcl against the declared constraints instead of updating
+
+	KeepConstraints bool // shake, swipe: bump version constraints in their own style instead of pinning exact versions
+
+	configApplied bool // .ghat.yml has been applied
 }
 
 // NewFlags creates a new Flags instance with default cache settings
@@ -57,8 +92,37 @@ func (f *Flags) InitializeCache() error {
 		return err
 	}
 	f.Cache = cache
+	return f.applyConfig()
+}
+
+// applyConfig applies .ghat.yml to the fields not already set on the
+// command line. Action calls it for the commands that don't initialise a
+// cache, so every pinner honours cooldowns and policies.
+func (f *Flags) applyConfig() error {
+	if f.configApplied {
+		return nil
+	}
+	f.configApplied = true
 	cfg := LoadConfig(f.Directory)
 	f.Substitutions = cfg.Substitutions
 	f.InputUpgrades = cfg.InputUpgrades
+	if f.Cooldown == nil {
+		f.Cooldown = cfg.Cooldown
+	}
+	if f.Policies == nil {
+		f.Policies = cfg.Policies
+	}
+	if f.LockPlatforms == nil {
+		f.LockPlatforms = cfg.LockPlatforms
+	}
+	if err := validatePolicies(f.Policies); err != nil {
+		return err
+	}
+	if f.GitHubAPIURL == "" {
+		f.GitHubAPIURL = cfg.GitHubAPIURL
+	}
+	if f.GitHubAPIURL != "" {
+		SetGitHubAPIURL(f.GitHubAPIURL)
+	}
 	return nil
 }
@@ -22,13 +22,13 @@ import (
 var _httpClient atomic.Pointer[http.Client]
 
 func init() {
-	_httpClient.Store(&http.Client{Timeout: 30 * time.Second})
+	_httpClient.Store(lookupClient(30 * time.Second))
 }
 
 // SetHTTPTimeout replaces the shared HTTP client with one using the given timeout.
 // Must be called before any API requests are made (i.e. at startup).
 func SetHTTPTimeout(d time.Duration) {
-	_httpClient.Store(&http.Client{Timeout: d})
+	_httpClient.Store(lookupClient(d))
 }
 
 var (
@@ -109,13 +109,12 @@ func GetFiles(dir string) ([]string, error) {
 }
 
 func (f *Flags) UpdateGHAS() error {
-	for _, gha := range f.GetGHA() {
+	return eachFile(f.GetGHA(), func(gha string) error {
 		if err := f.UpdateGHA(gha); err != nil {
 			return &ghaUpdateError{gha: gha, err: err}
 		}
-	}
-
-	return nil
+		return nil
+	})
 }
 
 // GetGHA gets all the actions in a directory
@@ -179,14 +178,13 @@ func (f *Flags) UpdateGHA(file string) error {
 			}
 		}
 
-		// Local/composite action path or docker:// ref — nothing to resolve on GitHub.
+		// Local/composite action path — nothing to resolve. docker:// refs
+		// are pinned to image digests below.
 		if strings.HasPrefix(action[0], ".") || strings.HasPrefix(action[0], "/") || strings.HasPrefix(action[0], "docker://") {
 			continue
 		}
 
-		// Reusable workflow call (owner/repo/.github/workflows/x.yml) — no releases to resolve.
-		if strings.Contains(action[0], "/.github/workflows/") {
-			log.Info().Str("ref", action[0]).Msg("skipping reusable workflow")
+		if f.frozen(SourceGHA, action[0]) {
 			continue
 		}
 
@@ -236,26 +234,47 @@ func (f *Flags) UpdateGHA(file string) error {
 				log.Warn().Msgf("failed to retrieve commit hash for %s@%s: %s", action[0], currentRef, err)
 				continue
 			}
+			moved, err := f.observeTag(LedgerEntry{Kind: SourceGHA, Name: ownerRepo(action[0]), Tag: currentRef, SHA: sha})
+			if err != nil {
+				continue
+			}
 			oldAction := leadingQuote + originalAction + "@" + originalRef + trailingQuote
 			newAction := action[0] + "@" + sha + " # " + currentRef
 			if !strings.Contains(string(buffer), oldAction) {
 				log.Warn().Str("uses", oldAction).Msg("resolved pin but reconstructed ref not found in source — please report this")
 			}
+			f.recordReplace(string(buffer), oldAction, Change{
+				File: file, Ecosystem: SourceGHA, Name: action[0],
+				OldRef: strings.TrimSpace(originalRef), NewRef: sha, Tag: currentRef, Mutation: moved,
+			})
 			replacement = strings.ReplaceAll(replacement, oldAction, newAction)
 			continue
 		}
 
-		body, err := getPayload(action[0], f.GitHubToken, f.Days)
+		// A reusable workflow call (owner/repo/.github/workflows/x.yml) is
+		// released with the repo that holds it, so skip straight to that.
+		lookup := action[0]
+		if isReusableWorkflow(lookup) {
+			lookup = ownerRepo(lookup)
+		}
+
+		// A nil Days is still an error; otherwise the cooldown may come from .ghat.yml.
+		days := f.Days
+		if days != nil {
+			cooled := f.cooldown(SourceGHA)
+			days = &cooled
+		}
+		body, err := getPayload(lookup, f.GitHubToken, days)
 
+		if err != nil && ownerRepo(lookup) != lookup {
+			body, err = getPayload(ownerRepo(lookup), f.GitHubToken, days)
+		}
 		if err != nil {
-			body, err = getPayload(ownerRepo(action[0]), f.GitHubToken, f.Days)
-			if err != nil {
-				if f.ContinueOnError {
-					log.Info().Err(err).Msgf("skipping action %s", action[0])
-					continue
-				}
-				return fmt.Errorf("failed to retrieve data for action %s with %s", action[0], err)
+			if f.ContinueOnError {
+				log.Info().Err(err).Msgf("skipping action %s", action[0])
+				continue
 			}
+			return fmt.Errorf("failed to retrieve data for action %s with %s", action[0], err)
 		}
 
 		msg, ok := body.(map[string]interface{})
@@ -267,13 +286,30 @@ func (f *Flags) UpdateGHA(file string) error {
 		if msg["tag_name"] != nil {
 			tag := msg["tag_name"].(string)
 
-			sha, err := resolveTagSHA(action[0], tag, f.GitHubToken)
-			if err != nil {
+			// A policy that rules out the latest release picks from the tags instead.
+			current := currentTag
+			if current == "" && len(action) > 1 && !shaRe.MatchString(strings.TrimSpace(action[1])) {
+				current = strings.TrimSpace(action[1])
+			}
+			var sha string
+			if policy := f.policy(SourceGHA, action[0]); !policy.permits(current, tag) {
+				sha, tag, err = githubTagWithin(ownerRepo(lookup), f.GitHubToken, *days, policy, current)
+				if err != nil {
+					log.Info().Err(err).Msgf("leaving %s as is", action[0])
+					continue
+				}
+			} else if sha, err = resolveTagSHA(action[0], tag, f.GitHubToken); err != nil {
 				log.Warn().Msgf("failed to retrieve commit hash for %s@%s: %s", action[0], tag, err)
 				continue
 			}
 
-			if isTagMutation(currentSHA, currentTag, sha, tag) {
+			moved, err := f.observeTag(LedgerEntry{Kind: SourceGHA, Name: ownerRepo(action[0]), Tag: tag, SHA: sha})
+			if err != nil {
+				continue
+			}
+
+			mutation := isTagMutation(currentSHA, currentTag, sha, tag)
+			if mutation {
 				log.Warn().Msgf("SUSPICIOUS: %s@%s — SHA changed from %s to %s with the same tag%s. "+
 					"The tag may have been moved to a different commit. Verify this is intentional before accepting.",
 					action[0], tag, currentSHA, sha, f.commitVerification(ownerRepo(action[0]), sha))
@@ -298,12 +334,35 @@ func (f *Flags) UpdateGHA(file string) error {
 			if !strings.Contains(string(buffer), oldAction) {
 				log.Warn().Str("uses", oldAction).Msg("resolved pin but reconstructed ref not found in source — please report this")
 			}
+			if oldRef := strings.TrimSpace(originalRef); oldAction != newAction {
+				if currentSHA != "" {
+					oldRef = currentSHA
+				}
+				f.recordReplace(string(buffer), oldAction, Change{
+					File: file, Ecosystem: SourceGHA, Name: action[0],
+					OldRef: oldRef, NewRef: sha, Tag: tag, Mutation: mutation || moved,
+				})
+			}
 			replacement = strings.ReplaceAll(replacement, oldAction, newAction)
 		} else {
 			log.Warn().Msgf("tag field empty skipping %s", action[0])
 		}
 	}
 
+	// Pin docker:// steps. Every rewrite above keeps the file's lines, so the
+	// steps' line numbers still hold in replacement.
+	lines := strings.Split(replacement, "\n")
+	for _, step := range parseDockerSteps(buffer) {
+		if step.suppressed {
+			log.Info().Str("ref", step.value).Msg("skipping suppressed uses: line")
+			continue
+		}
+		if newValue, ok := f.pinDockerStep(file, step); ok {
+			lines[step.line-1] = strings.Replace(lines[step.line-1], step.value, newValue, 1)
+		}
+	}
+	replacement = strings.Join(lines, "\n")
+
 	if upgraded, upgradeErr := f.applyInputUpgrades(replacement); upgradeErr == nil {
 		replacement = upgraded
 	} else {
@@ -320,17 +379,23 @@ func (f *Flags) UpdateGHA(file string) error {
 
 	for _, imageStr := range containerImages {
 		imgRef := parseImageReference(imageStr)
+		if f.frozen(SourceImage, imageDisplayName(imgRef)) {
+			continue
+		}
 		digest, err := f.getImageDigest(&imgRef)
 		if err != nil {
 			log.Warn().Err(err).Str("image", imageStr).Msg("failed to get digest for container image, skipping")
 			continue
 		}
 
-		if cur, ok := pinnedImages[imgRef.Tag]; ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag) {
+		cur, ok := pinnedImages[imgRef.Tag]
+		mutation := ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag)
+		if mutation {
 			log.Warn().Msgf("SUSPICIOUS: %s — digest changed from %s to %s with the same tag. "+
 				"The image tag may have been repointed. Verify before accepting.", imageStr, cur, digest)
 		}
 
+		f.recordImageChange(file, string(buffer), SourceImage, imageStr, imgRef, digest, mutation)
 		if at := strings.Index(imageStr, "@sha256:"); at >= 0 {
 			replacement = strings.ReplaceAll(replacement, imageStr, imageStr[:at]+"@"+digest)
 		} else {
@@ -340,6 +405,12 @@ func (f *Flags) UpdateGHA(file string) error {
 
 	replacement = ensurePermissions(file, replacement)
 
+	f.checkBumpedInputs(file, replacement)
+
+	if f.Transitive {
+		f.warnTransitive(file, replacement)
+	}
+
 	f.printDiff(file, string(buffer), replacement)
 
 	if !f.DryRun {
@@ -436,6 +507,84 @@ func extractGHAContainerImages(content string) ([]string, error) {
 	return images, nil
 }
 
+// dockerStep is a `uses: docker://image` step, which runs a container image
+// directly rather than an action.
+type dockerStep struct {
+	line       int    // 1-indexed
+	value      string // everything after uses:, quotes and comment included
+	image      string // name[:tag][@digest], without docker://
+	tag        string // the "# tag" comment on a digest-pinned step
+	suppressed bool
+}
+
+// parseDockerSteps finds the docker:// step references in a workflow,
+// skipping dynamic ones such as docker://${{ env.IMAGE }}.
+func parseDockerSteps(content []byte) []dockerStep {
+	var steps []dockerStep
+	for _, idx := range usesExtractRe.FindAllSubmatchIndex(content, -1) {
+		value := strings.TrimSpace(string(content[idx[2]:idx[3]]))
+		ref, _, _ := strings.Cut(value, "#")
+		image, ok := strings.CutPrefix(strings.Trim(strings.TrimSpace(ref), `"'`), "docker://")
+		if !ok || image == "" || strings.HasPrefix(image, "$") {
+			continue
+		}
+		step := dockerStep{line: offsetToLine(content, idx[2]), value: value, image: image}
+		step.suppressed, _ = parseSuppression(string(content[idx[0]:idx[1]]))
+		if strings.Contains(image, "@") {
+			step.tag = refTagComment(value)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// pinDockerStep resolves a docker:// step's image to its digest and returns
+// the pinned "docker://image@sha256:… # tag" value, or false when the step is
+// already pinned to it or cannot be resolved. A pinned step is re-resolved
+// from the tag in its comment; the tag itself is never upgraded.
+func (f *Flags) pinDockerStep(file string, step dockerStep) (string, bool) {
+	lookup, pinned, _ := strings.Cut(step.image, "@")
+	if pinned != "" {
+		if f.PinOnly || step.tag == "" {
+			return "", false
+		}
+		name, _ := splitImageTag(lookup)
+		lookup = name + ":" + step.tag
+	}
+
+	ref := parseImageReference(lookup)
+	if f.frozen(SourceImage, imageDisplayName(ref)) {
+		return "", false
+	}
+	digest, err := f.getImageDigest(&ref)
+	if err != nil {
+		log.Warn().Err(err).Str("image", step.image).Msg("failed to get digest for docker:// step, skipping")
+		return "", false
+	}
+
+	mutation := isTagMutation(pinned, step.tag, digest, ref.Tag)
+	if mutation {
+		log.Warn().Msgf("SUSPICIOUS: docker://%s — digest changed from %s to %s with the same tag. "+
+			"The image tag may have been repointed. Verify before accepting.", lookup, pinned, digest)
+	}
+
+	newValue := "docker://" + formatImageWithDigest(ref, digest)
+	if newValue == step.value {
+		return "", false
+	}
+	if pinned != digest {
+		old := pinned
+		if old == "" {
+			old = ref.Tag
+		}
+		f.recordChange(Change{
+			File: file, Line: step.line, Ecosystem: SourceImage, Name: imageDisplayName(ref),
+			OldRef: old, NewRef: digest, Tag: ref.Tag, Mutation: mutation,
+		})
+	}
+	return newValue, true
+}
+
 // applyInputUpgrades scans workflow content for action `with:` inputs that need
 // upgrading when the action's major version changes (e.g. golangci-lint-action v7+
 // requires golangci-lint v2+). Rules are loaded from substitutions.yml / ~/.ghat.yml.
@@ -551,42 +700,59 @@ func getPayload(action string, gitHubToken string, days *uint) (interface{}, err
 		return nil, &daysParameterError{}
 	}
 
-	if *days == 0 {
-		return GetLatestRelease(action, gitHubToken)
-	}
-
-	return GetReleases(action, gitHubToken, days)
+	return resolve(fmt.Sprintf("github-release %s days=%d", repoAPI(action), *days), func() (interface{}, error) {
+		if *days == 0 {
+			return GetLatestRelease(action, gitHubToken)
+		}
+		return GetReleases(action, gitHubToken, days)
+	})
 }
 
 func GetLatestRelease(action string, gitHubToken string) (interface{}, error) {
-	url := "https://api.github.com/repos/" + action + "/releases/latest"
+	url := repoAPI(action) + "/releases/latest"
 	return GetGithubBody(gitHubToken, url)
 }
 
 func GetLatestTag(action string, gitHubToken string) (interface{}, error) {
-	const maxPages = 5
-	url := "https://api.github.com/repos/" + action + "/tags?per_page=100"
-	var tagged []interface{}
-	for p := 0; url != "" && p < maxPages; p++ {
-		page, next, err := getPagedGithubBody(gitHubToken, url)
-		if err != nil {
-			return nil, err
-		}
-		items, ok := page.([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("failed to assert slice %s", page)
-		}
-		tagged = append(tagged, items...)
-		url = next
-	}
+	return resolve("github-latest-tag "+repoAPI(action), func() (interface{}, error) {
+		return getLatestTag(action, gitHubToken)
+	})
+}
 
-	if len(tagged) == 0 {
-		return nil, fmt.Errorf("repo %s has no tags", action)
+func getLatestTag(action string, gitHubToken string) (interface{}, error) {
+	tagged, err := githubTags(action, gitHubToken)
+	if err != nil {
+		return nil, err
 	}
-
 	return tagged[pickLatestTag(tagged)], nil
 }
 
+// githubTags lists up to five pages of a repository's tags.
+func githubTags(action string, gitHubToken string) ([]interface{}, error) {
+	return resolve("github-tags "+repoAPI(action), func() ([]interface{}, error) {
+		const maxPages = 5
+		url := repoAPI(action) + "/tags?per_page=100"
+		var tagged []interface{}
+		for p := 0; url != "" && p < maxPages; p++ {
+			page, next, err := getPagedGithubBody(gitHubToken, url)
+			if err != nil {
+				return nil, err
+			}
+			items, ok := page.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("failed to assert slice %s", page)
+			}
+			tagged = append(tagged, items...)
+			url = next
+		}
+
+		if len(tagged) == 0 {
+			return nil, fmt.Errorf("repo %s has no tags", action)
+		}
+		return tagged, nil
+	})
+}
+
 var versionRunRe = regexp.MustCompile(`\d+\.\d`)
 
 // coerceSemver turns common tag spellings into something golang.org/x/mod/semver
@@ -654,23 +820,41 @@ func pickLatestTag(tagged []any) int {
 }
 
 func getHash(action string, tag string, gitHubToken string) (interface{}, error) {
-	url := "https://api.github.com/repos/" + action + "/git/ref/tags/" + tag
+	url := repoAPI(action) + "/git/ref/tags/" + tag
 	return GetGithubBody(gitHubToken, url)
 }
 
 // ownerRepo strips any sub-path from an action ref so it can be used as a
 // /repos/{owner}/{repo} API path (e.g. "github/codeql-action/init" → "github/codeql-action").
+// A host qualifier ("ghes.example.com/owner/repo/path") is kept so repoAPI can
+// still route the call to that host.
 func ownerRepo(action string) string {
-	parts := strings.SplitN(action, "/", 3)
+	host, path := splitUsesHost(action)
+	parts := strings.SplitN(path, "/", 3)
 	if len(parts) < 2 {
 		return action
 	}
+	if host != "" {
+		return host + "/" + parts[0] + "/" + parts[1]
+	}
 	return parts[0] + "/" + parts[1]
 }
 
+// isReusableWorkflow reports whether a uses: path calls a reusable workflow
+// (owner/repo/.github/workflows/x.yml) rather than an action.
+func isReusableWorkflow(path string) bool {
+	return strings.Contains(path, "/.github/workflows/")
+}
+
 // resolveTagSHA returns the commit SHA a tag points at, dereferencing
 // annotated tag objects to the underlying commit.
 func resolveTagSHA(action, tag, token string) (string, error) {
+	return resolve("github-tag-sha "+repoAPI(ownerRepo(action))+" "+tag, func() (string, error) {
+		return getTagSHA(action, tag, token)
+	})
+}
+
+func getTagSHA(action, tag, token string) (string, error) {
 	payload, err := getHash(ownerRepo(action), tag, token)
 	if err != nil {
 		return "", err
@@ -707,7 +891,7 @@ func resolveTagSHA(action, tag, token string) (string, error) {
 // GPG/SSH signature status of sha, for triaging tag-repoint warnings. Returns
 // "" if the lookup fails so the warning still fires.
 func (f *Flags) commitVerification(repo, sha string) string {
-	url := "https://api.github.com/repos/" + repo + "/commits/" + sha
+	url := repoAPI(repo) + "/commits/" + sha
 	body, err := GetGithubBodyWithCache(f.GitHubToken, url, f.Cache)
 	if err != nil {
 		return ""
@@ -728,8 +912,24 @@ func (f *Flags) commitVerification(repo, sha string) string {
 
 // GetGithubBodyWithCache fetches data from GitHub API with caching support
 func GetGithubBodyWithCache(token, url string, cache *Cache) (interface{}, error) {
+	return resolve("github "+url, func() (interface{}, error) {
+		return getGithubBodyWithCache(token, url, cache)
+	})
+}
+
+func getGithubBodyWithCache(token, url string, cache *Cache) (interface{}, error) {
+	// Offline, an expired entry is still better than none.
+	if Offline() && cache != nil {
+		if raw, found := cache.getRaw(url); found {
+			var cached interface{}
+			if err := json.Unmarshal(raw, &cached); err == nil {
+				return cached, nil
+			}
+		}
+	}
+
 	// Try cache first
-	if cache != nil && cache.enabled {
+	if cache != nil && cache.enabled && !Offline() {
 		if cached, found := cache.Get(url); found {
 			log.Debug().Str("url", url).Msg("Using cached response")
 			return cached, nil
@@ -755,6 +955,7 @@ func GetGithubBodyWithCache(token, url string, cache *Cache) (interface{}, error
 
 // GetGithubBody fetches data from GitHub API (existing function, keep as-is for compatibility)
 func GetGithubBody(token, url string) (interface{}, error) {
+	token = tokenFor(token, url)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -808,6 +1009,7 @@ func GetGithubBody(token, url string) (interface{}, error) {
 
 // getPagedGithubBody fetches one page and returns the body plus the URL of the next page (empty if last).
 func getPagedGithubBody(token, url string) (interface{}, string, error) {
+	token = tokenFor(token, url)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create request: %w", err)
@@ -863,6 +1065,7 @@ func postGithubBody(token, url string, payload []byte) (interface{}, error) {
 }
 
 func sendGithubBody(token, method, url string, payload []byte) (interface{}, error) {
+	token = tokenFor(token, url)
 	req, err := http.NewRequest(method, url, strings.NewReader(string(payload)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -451,14 +452,15 @@ func TestFlags_UpdateGHA(t *testing.T) {
 			args: args{"./testdata/dynref/.github/workflows/test.yml"},
 		},
 		{
-			name: "Reusable workflow refs are skipped",
+			name: "Reusable workflow refs are resolved",
 			fields: fields{
-				File:        "./testdata/reusable/.github/workflows/test.yml",
-				GitHubToken: gitHubToken,
-				Days:        &days,
-				DryRun:      true,
-				Entries:     []string{"./testdata/reusable/.github/workflows/test.yml"},
-				Update:      true,
+				File:            "./testdata/reusable/.github/workflows/test.yml",
+				GitHubToken:     gitHubToken,
+				Days:            &days,
+				DryRun:          true,
+				Entries:         []string{"./testdata/reusable/.github/workflows/test.yml"},
+				Update:          true,
+				ContinueOnError: true,
 			},
 			args: args{"./testdata/reusable/.github/workflows/test.yml"},
 		},
@@ -1112,3 +1114,80 @@ func TestEnsurePermissions(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateGHA_ReusableWorkflow(t *testing.T) {
+	fakeTagServer(t, includeSHA)
+
+	tests := []struct {
+		name    string
+		pinOnly bool
+		uses    string
+		want    string
+	}{
+		{name: "latest release", uses: "actions/checkout/.github/workflows/ci.yml@v4",
+			want: "actions/checkout/.github/workflows/ci.yml@" + includeSHA + " # v4.2.2"},
+		{name: "pin only", pinOnly: true, uses: "actions/checkout/.github/workflows/ci.yml@v4.2.2",
+			want: "actions/checkout/.github/workflows/ci.yml@" + includeSHA + " # v4.2.2"},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "ci.yml")
+		content := "permissions: read-all\njobs:\n  call:\n    uses: " + tt.uses + "\n"
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		var days uint
+		f := &Flags{Days: &days, Silent: true, PinOnly: tt.pinOnly}
+		if err := f.UpdateGHA(file); err != nil {
+			t.Fatalf("%s: UpdateGHA() error = %v", tt.name, err)
+		}
+		if got, _ := os.ReadFile(file); !strings.Contains(string(got), "    uses: "+tt.want+"\n") {
+			t.Errorf("%s: UpdateGHA() =\n%s\nwant uses: %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateGHA_DockerSteps(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	stale := "sha256:" + strings.Repeat("b", 64)
+
+	t.Cleanup(func() { SetResponseCache(nil, false) })
+	cache := newCacheIn(t.TempDir(), time.Hour)
+	if err := cache.Set("registry:digest alpine:3.20", digest); err != nil {
+		t.Fatal(err)
+	}
+	SetResponseCache(cache, true)
+
+	file := filepath.Join(t.TempDir(), "ci.yml")
+	content := "permissions: read-all\njobs:\n  build:\n    steps:\n" +
+		"      - uses: docker://alpine:3.20\n" +
+		"      - uses: \"docker://alpine:3.20\"\n" +
+		"      - uses: docker://alpine@" + stale + " # 3.20\n" +
+		"      - uses: docker://alpine:3.20 # ghat:suppress\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Flags{Silent: true}
+	if err := f.UpdateGHA(file); err != nil {
+		t.Fatalf("UpdateGHA() error = %v", err)
+	}
+
+	pinned := "      - uses: docker://alpine@" + digest + " # 3.20\n"
+	want := "permissions: read-all\njobs:\n  build:\n    steps:\n" +
+		pinned + pinned + pinned +
+		"      - uses: docker://alpine:3.20 # ghat:suppress\n"
+	if got, _ := os.ReadFile(file); string(got) != want {
+		t.Errorf("UpdateGHA() =\n%s\nwant\n%s", got, want)
+	}
+
+	if len(f.Changes) != 3 {
+		t.Fatalf("UpdateGHA() recorded %d changes, want 3: %+v", len(f.Changes), f.Changes)
+	}
+	if c := f.Changes[0]; c.Line != 5 || c.Ecosystem != SourceImage || c.Name != "alpine" || c.OldRef != "3.20" || c.NewRef != digest || c.Mutation {
+		t.Errorf("change[0] = %+v", c)
+	}
+	if c := f.Changes[2]; c.Line != 7 || c.OldRef != stale || !c.Mutation {
+		t.Errorf("change[2] = %+v, want the repointed tag flagged", c)
+	}
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// _githubAPIURL is the REST root every GitHub call is built from. It is
+// process-wide, like _httpClient, so deep helpers (getHash, GetReleases,
+// audit checks) don't need it threaded through their signatures.
+var _githubAPIURL atomic.Pointer[string]
+
+func init() {
+	u := defaultGitHubAPIURL
+	_githubAPIURL.Store(&u)
+}
+
+// SetGitHubAPIURL points all GitHub REST/GraphQL calls at a different API
+// root, e.g. https://ghes.example.com/api/v3 for GitHub Enterprise Server or
+// an httptest server. An empty string restores https://api.github.com.
+// Must be called before any API requests are made (i.e. at startup).
+func SetGitHubAPIURL(u string) {
+	u = strings.TrimRight(strings.TrimSpace(u), "/")
+	if u == "" {
+		u = defaultGitHubAPIURL
+	}
+	_githubAPIURL.Store(&u)
+}
+
+// GitHubAPIURL returns the configured GitHub REST root without a trailing slash.
+func GitHubAPIURL() string {
+	return *_githubAPIURL.Load()
+}
+
+// githubAPI joins path (which must start with "/") onto the configured API root.
+func githubAPI(path string) string {
+	return GitHubAPIURL() + path
+}
+
+// githubGraphQLURL returns the GraphQL endpoint matching the configured REST
+// root. GHES serves it at /api/graphql alongside /api/v3.
+func githubGraphQLURL() string {
+	base := GitHubAPIURL()
+	if root, ok := strings.CutSuffix(base, "/api/v3"); ok {
+		return root + "/api/graphql"
+	}
+	return base + "/graphql"
+}
+
+// githubWebURL returns the browser/clone root for the configured API:
+// api.github.com → github.com, <host>/api/v3 → <host>, api.<x> → <x>.
+func githubWebURL() string {
+	base := GitHubAPIURL()
+	if root, ok := strings.CutSuffix(base, "/api/v3"); ok {
+		return root
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		return "https://github.com"
+	}
+	if host, ok := strings.CutPrefix(u.Host, "api."); ok {
+		u.Host = host
+	}
+	u.Path = ""
+	return u.String()
+}
+
+// isGitHubWebHost reports whether host serves the configured GitHub instance,
+// so GHES remotes without "github" in their name are still recognised.
+func isGitHubWebHost(host string) bool {
+	u, err := url.Parse(githubWebURL())
+	return err == nil && (strings.EqualFold(u.Host, host) || strings.EqualFold(u.Hostname(), host))
+}
+
+// splitUsesHost separates a host-qualified uses: ref such as
+// "ghes.example.com/owner/repo/path" into its host and owner/repo path.
+// GitHub owners cannot contain dots, so a dotted first segment is a host.
+// Plain "owner/repo" refs return an empty host.
+func splitUsesHost(action string) (host, path string) {
+	first, rest, ok := strings.Cut(action, "/")
+	if !ok || !strings.Contains(first, ".") {
+		return "", action
+	}
+	return strings.ToLower(first), rest
+}
+
+// apiURLForHost returns the REST root for a uses: host. An empty host, or the
+// host of the configured instance, uses the configured root; github.com uses
+// the public API; any other host is assumed to be a GHES instance.
+func apiURLForHost(host string) string {
+	if host == "" {
+		return GitHubAPIURL()
+	}
+	if isGitHubWebHost(host) {
+		return GitHubAPIURL()
+	}
+	if host == "github.com" {
+		return defaultGitHubAPIURL
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// _tokenHosts are hosts, beyond the configured GitHub instance, that the token given to the GitHub API helpers may go to: the
+// GitLab instance org mode drives through them.
+var _tokenHosts sync.Map
+
+// trustTokenHost lets the API helpers send their token to the host of rawURL.
+func trustTokenHost(rawURL string) {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		_tokenHosts.Store(strings.ToLower(u.Host), true)
+	}
+}
+
+// tokenFor returns token if rawURL is on the configured GitHub instance's API
+// or web host or on a host passed to trustTokenHost, and "" otherwise, so a
+// host-qualified uses: ref can't make ghat send the token to its host. With
+// --github-api-url set, github.com is just another host: a GHES token is no
+// good there and should not leave the instance.
+func tokenFor(token, rawURL string) string {
+	if token == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Host)
+	if isGitHubWebHost(host) {
+		return token
+	}
+	if api, err := url.Parse(GitHubAPIURL()); err == nil && strings.EqualFold(api.Host, host) {
+		return token
+	}
+	if _, ok := _tokenHosts.Load(host); ok {
+		return token
+	}
+	return ""
+}
+
+// repoAPI returns the /repos/{owner}/{repo} URL for an action ref, routing
+// host-qualified refs to their own API and dropping any sub-path.
+func repoAPI(action string) string {
+	host, path := splitUsesHost(action)
+	return apiURLForHost(host) + "/repos/" + ownerRepo(path)
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitUsesHost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in, wantHost, wantPath string
+	}{
+		{"actions/checkout", "", "actions/checkout"},
+		{"github/codeql-action/init", "", "github/codeql-action/init"},
+		{"github.com/actions/checkout", "github.com", "actions/checkout"},
+		{"GHES.example.com/org/action/sub", "ghes.example.com", "org/action/sub"},
+		{"checkout", "", "checkout"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			host, path := splitUsesHost(tt.in)
+			if host != tt.wantHost || path != tt.wantPath {
+				t.Errorf("splitUsesHost(%q) = (%q, %q), want (%q, %q)", tt.in, host, path, tt.wantHost, tt.wantPath)
+			}
+		})
+	}
+}
+
+// Tests below swap the process-wide API root, so they must not run in parallel.
+
+func TestGitHubAPIURL_Default(t *testing.T) {
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL("")
+
+	if got := repoAPI("github/codeql-action/init"); got != "https://api.github.com/repos/github/codeql-action" {
+		t.Errorf("repoAPI = %q", got)
+	}
+	if got := githubGraphQLURL(); got != "https://api.github.com/graphql" {
+		t.Errorf("githubGraphQLURL = %q", got)
+	}
+	if got := githubWebURL(); got != "https://github.com" {
+		t.Errorf("githubWebURL = %q", got)
+	}
+	if got := repoAPI("ghes.example.com/org/action"); got != "https://ghes.example.com/api/v3/repos/org/action" {
+		t.Errorf("repoAPI(host-qualified) = %q", got)
+	}
+}
+
+func TestGitHubAPIURL_Enterprise(t *testing.T) {
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL("https://ghes.example.com/api/v3/")
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"api root", GitHubAPIURL(), "https://ghes.example.com/api/v3"},
+		{"graphql", githubGraphQLURL(), "https://ghes.example.com/api/graphql"},
+		{"web", githubWebURL(), "https://ghes.example.com"},
+		{"plain ref", repoAPI("org/action"), "https://ghes.example.com/api/v3/repos/org/action"},
+		{"own host", repoAPI("ghes.example.com/org/action/sub"), "https://ghes.example.com/api/v3/repos/org/action"},
+		{"github.com ref", repoAPI("github.com/actions/checkout"), "https://api.github.com/repos/actions/checkout"},
+		{"ownerRepo keeps host", ownerRepo("ghes.example.com/org/action/sub"), "ghes.example.com/org/action"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if p, _, _, _ := parseRemoteURL("git@ghes.example.com:org/app.git"); p != "github" {
+		t.Errorf("parseRemoteURL(GHES) provider = %q, want github", p)
+	}
+}
+
+func TestUpdateGHA_FakeGitHub(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/actions/checkout/releases/latest", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v4.2.2"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/actions/checkout/git/ref/tags/v4.2.2", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"object":{"sha":"` + sha + `","type":"commit"}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL + "/api/v3")
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ci.yml")
+	content := "permissions: read-all\njobs:\n  test:\n    steps:\n" +
+		"      - uses: actions/checkout@v4\n" +
+		"      - uses: " + host + "/actions/checkout@v3\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var days uint
+	f := &Flags{Days: &days, Silent: true}
+	if err := f.UpdateGHA(file); err != nil {
+		t.Fatalf("UpdateGHA() error = %v", err)
+	}
+
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"uses: actions/checkout@" + sha + " # v4.2.2",
+		"uses: " + host + "/actions/checkout@" + sha + " # v4.2.2",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("UpdateGHA() output missing %q:\n%s", want, got)
+		}
+	}
+
+	if len(f.Changes) != 2 {
+		t.Fatalf("UpdateGHA() recorded %d changes, want 2: %+v", len(f.Changes), f.Changes)
+	}
+	if c := f.Changes[0]; c.Line != 5 || c.Ecosystem != SourceGHA || c.Name != "actions/checkout" ||
+		c.OldRef != "v4" || c.NewRef != sha || c.Tag != "v4.2.2" {
+		t.Errorf("UpdateGHA() change = %+v", c)
+	}
+}
+
+func TestTokenFor(t *testing.T) {
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL("https://ghes.example.com/api/v3")
+	trustTokenHost("https://gitlab.example.com")
+
+	tests := map[string]string{
+		"https://api.github.com/repos/actions/checkout":          "",
+		"https://github.com/actions/checkout.git":                "",
+		"https://ghes.example.com/api/v3/repos/org/action":       "secret",
+		"https://ghes.example.com/org/action.git":                "secret",
+		"https://gitlab.example.com/api/v4/projects/1":           "secret",
+		"https://evil.example.com/api/v3/repos/o/r":              "",
+		"https://api.github.com.evil.example.com/repos/o/r/tags": "",
+	}
+	for u, want := range tests {
+		if got := tokenFor("secret", u); got != want {
+			t.Errorf("tokenFor(%q) = %q, want %q", u, got, want)
+		}
+	}
+
+	SetGitHubAPIURL("")
+	for _, u := range []string{"https://api.github.com/repos/actions/checkout", "https://github.com/actions/checkout.git"} {
+		if got := tokenFor("secret", u); got != "secret" {
+			t.Errorf("tokenFor(%q) with the default API = %q, want the token", u, got)
+		}
+	}
+	if got := tokenFor("secret", "https://ghes.example.com/api/v3/repos/org/action"); got != "" {
+		t.Errorf("tokenFor() sent the token to a GHES host that is no longer configured")
+	}
+}
+
+func TestGetGithubBody_OtherHostGetsNoToken(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL("")
+
+	if _, err := GetGithubBody("secret", srv.URL+"/api/v3/repos/o/r"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "" {
+		t.Errorf("GetGithubBody() sent %q to a host that isn't GitHub", auth)
+	}
+}
@@ -103,16 +103,17 @@ func (f *Flags) UpdateGitlab() error {
 		return err
 	}
 
+	// Pin include: components, projects and remote templates to commit SHAs.
+	replacement := f.pinGitLabIncludes(projectFile, string(project))
+
 	if len(images) == 0 {
 		log.Info().Msg("No container images found in GitLab CI configuration")
-		return nil
 	}
 
 	// Snapshot existing digest→tag mappings before YAML strips comments.
 	pinnedImages := parsePinnedImages(string(project))
 
 	// Process each image
-	replacement := string(project)
 	for _, imageStr := range images {
 		imageStr = strings.TrimSpace(imageStr)
 		if imageStr == "" {
@@ -126,6 +127,9 @@ func (f *Flags) UpdateGitlab() error {
 
 		// Parse the image reference
 		imgRef := parseImageReference(imageStr)
+		if f.frozen(SourceImage, imageDisplayName(imgRef)) {
+			continue
+		}
 		log.Info().Str("image", imageStr).Msg("Processing image")
 
 		// Get the digest for the image
@@ -136,10 +140,13 @@ func (f *Flags) UpdateGitlab() error {
 		}
 
 		// Detect tag mutation: same tag, different digest.
-		if cur, ok := pinnedImages[imgRef.Tag]; ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag) {
+		cur, ok := pinnedImages[imgRef.Tag]
+		mutation := ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag)
+		if mutation {
 			log.Warn().Msgf("SUSPICIOUS: %s — digest changed from %s to %s with the same tag. "+
 				"The image tag may have been repointed to a different layer. Verify before accepting.", imageStr, cur, digest)
 		}
+		f.recordImageChange(projectFile, string(project), SourceGitLab, imageStr, imgRef, digest, mutation)
 
 		// Create new image reference with digest
 		newImageRef := formatImageWithDigest(imgRef, digest)
@@ -276,7 +283,9 @@ func parseImageReference(image string) ImageReference {
 }
 
 // bestSemanticTag queries the registry for all tags on an image and returns
-// the highest stable semver tag. Falls back to "latest" if none are found.
+// the highest stable semver tag its .ghat.yml policy permits, or with an
+// image cooldown the highest built long enough ago. Falls back to "latest"
+// if none are found.
 // Only used when the source image had no explicit tag (e.g. FROM alpine).
 func (f *Flags) bestSemanticTag(ref ImageReference) string {
 	repoStr := ref.Registry + "/" + ref.Repository
@@ -286,11 +295,20 @@ func (f *Flags) bestSemanticTag(ref ImageReference) string {
 	}
 
 	opts := []remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)}
-	tags, err := remote.List(repo, opts...)
+	tags, err := recorded("registry:tags "+repoStr, func() ([]string, error) {
+		return remote.List(repo, opts...)
+	})
 	if err != nil {
 		return "latest"
 	}
 
+	if p := f.policy(SourceImage, imageDisplayName(ref)); p.constrains() {
+		if tags = p.permitted("", tags); len(tags) == 0 {
+			log.Warn().Str("image", repoStr).Msg("no tag satisfies the .ghat.yml policy, leaving the implicit latest")
+			return "latest"
+		}
+	}
+
 	// Convert to []interface{} so pickLatestTag can be reused.
 	items := make([]interface{}, 0, len(tags))
 	for _, t := range tags {
@@ -300,6 +318,15 @@ func (f *Flags) bestSemanticTag(ref ImageReference) string {
 		return "latest"
 	}
 
+	if days := f.cooldown(SourceImage); days > 0 {
+		tag, err := imageTagOlderThan(repoStr, tags, days)
+		if err != nil {
+			log.Warn().Err(err).Str("image", repoStr).Msg("no tag old enough for the cooldown, leaving the implicit latest")
+			return "latest"
+		}
+		return tag
+	}
+
 	best := tags[pickLatestTag(items)]
 	if best == "" {
 		return "latest"
@@ -328,10 +355,10 @@ func (f *Flags) getImageDigest(ref *ImageReference) (string, error) {
 
 	const digestKeyPrefix = "digest:"
 	cacheKey := digestKeyPrefix + imageStr
-	if f.Cache != nil {
+	if f.Cache != nil && !Offline() {
 		if cached, ok := f.Cache.Get(cacheKey); ok {
 			if s, ok := cached.(string); ok {
-				return s, nil
+				return f.observeImageDigest(ref, s)
 			}
 		}
 	}
@@ -348,36 +375,46 @@ func (f *Flags) getImageDigest(ref *ImageReference) (string, error) {
 		opts = []remote.Option{remote.WithAuth(&authn.Bearer{Token: f.GitHubToken})}
 	}
 
-	desc, err := remote.Head(parsed, opts...)
+	digest, err := recorded("registry:digest "+imageStr, func() (string, error) {
+		desc, err := remote.Head(parsed, opts...)
+		if err != nil {
+			return "", err
+		}
+		return desc.Digest.String(), nil
+	})
 	if err != nil {
 		return "", fmt.Errorf("resolve digest for %q: %w", ref.Original, err)
 	}
 
-	digest := desc.Digest.String()
 	if f.Cache != nil {
 		_ = f.Cache.Set(cacheKey, digest)
 	}
+	return f.observeImageDigest(ref, digest)
+}
+
+// observeImageDigest checks a digest, fetched or cached, against the tag
+// ledger before getImageDigest returns it.
+func (f *Flags) observeImageDigest(ref *ImageReference, digest string) (string, error) {
+	if _, err := f.observeTag(LedgerEntry{Kind: SourceImage, Name: imageDisplayName(*ref), Tag: ref.Tag, SHA: digest}); err != nil {
+		return "", err
+	}
 	return digest, nil
 }
 
+// imageDisplayName returns the image name as it is written in source files:
+// Docker Hub images lose their docker.io/ and library/ prefixes.
+func imageDisplayName(ref ImageReference) string {
+	if ref.Registry == "docker.io" {
+		return strings.TrimPrefix(ref.Repository, "library/")
+	}
+	return ref.Registry + "/" + ref.Repository
+}
+
 // formatImageWithDigest creates the new image reference with digest
 func formatImageWithDigest(ref ImageReference, digest string) string {
 	var result strings.Builder
 
-	// Don't include docker.io prefix for Docker Hub images in output (keep them simple)
-	if ref.Registry == "docker.io" {
-		// Remove library/ prefix for official images
-		repo := ref.Repository
-
-		repo = strings.TrimPrefix(repo, "library/")
-
-		result.WriteString(repo)
-	} else {
-		result.WriteString(ref.Registry)
-		result.WriteString("/")
-		result.WriteString(ref.Repository)
-	}
-
+	result.WriteString(imageDisplayName(ref))
 	result.WriteString("@")
 	result.WriteString(digest)
 	result.WriteString(" # ")
@@ -440,25 +477,35 @@ func gitlabComponentPath(name string) (host, projectPath string, err error) {
 // gitlabGet performs an authenticated GET against the GitLab API and returns the
 // decoded JSON body. The caller owns the response.
 func gitlabGet(apiURL, token string, out interface{}) error {
-	client := &http.Client{Timeout: 30 * time.Second}
-	req, err := http.NewRequest("GET", apiURL, nil)
+	body, err := resolve("gitlab "+apiURL, func() ([]byte, error) {
+		return gitlabGetBody(apiURL, token)
+	})
 	if err != nil {
 		return err
 	}
+	return json.Unmarshal(body, out)
+}
+
+func gitlabGetBody(apiURL, token string) ([]byte, error) {
+	client := lookupClient(30 * time.Second)
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("User-Agent", "ghat")
 	if token != "" {
 		req.Header.Set("PRIVATE-TOKEN", token)
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("GitLab API request failed: %w", err)
+		return nil, fmt.Errorf("GitLab API request failed: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("GitLab API %s returned %s: %s", apiURL, resp.Status, string(body))
+		return nil, fmt.Errorf("GitLab API %s returned %s: %s", apiURL, resp.Status, string(body))
 	}
-	return json.NewDecoder(resp.Body).Decode(out)
+	return io.ReadAll(resp.Body)
 }
 
 // ResolveGitLabComponentSHA resolves a GitLab CI component reference tag to its
@@ -470,22 +517,7 @@ func ResolveGitLabComponentSHA(name, version, token string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	encodedProject := strings.ReplaceAll(url.QueryEscape(projectPath), "+", "%20")
-	apiURL := fmt.Sprintf("https://%s/api/v4/projects/%s/repository/tags/%s",
-		host, encodedProject, url.QueryEscape(version))
-
-	var tagResp struct {
-		Commit struct {
-			ID string `json:"id"`
-		} `json:"commit"`
-	}
-	if err := gitlabGet(apiURL, token, &tagResp); err != nil {
-		return "", err
-	}
-	if tagResp.Commit.ID == "" {
-		return "", fmt.Errorf("no commit SHA in GitLab tag response for %s@%s", name, version)
-	}
-	return tagResp.Commit.ID, nil
+	return gitlabTagSHA("https://"+host, projectPath, version, token)
 }
 
 // ResolveGitLabComponentLatest resolves the latest release tag of a GitLab CI
@@ -0,0 +1,406 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	gitlabIncludeComponent = "component"
+	gitlabIncludeProject   = "project"
+	gitlabIncludeRemote    = "remote"
+
+	gitlabLatestVersion = "~latest"
+	defaultGitLabURL    = "https://gitlab.com"
+)
+
+var (
+	// gitlabRemoteRawRe splits a GitLab raw-file URL into base+project, ref and file path:
+	// https://gitlab.com/group/proj/-/raw/v1.2.0/templates/ci.yml
+	gitlabRemoteRawRe = regexp.MustCompile(`^(https?://[^/]+)/(.+?)/-/raw/([^/]+)(/.+)$`)
+	// commentTagRe pulls the tag out of a trailing "# tag" comment.
+	commentTagRe = regexp.MustCompile(`#\s*(\S+)`)
+)
+
+// gitlabInclude is one pinnable entry under the top-level include: key.
+type gitlabInclude struct {
+	kind    string // gitlabIncludeComponent, gitlabIncludeProject or gitlabIncludeRemote
+	baseURL string // GitLab instance root, e.g. https://gitlab.com
+	project string // project path on baseURL, e.g. components/opentofu
+	name    string // component path (component includes only)
+	ref     string // version/ref as written, or the "# tag" comment when already pinned
+	sha     string // current SHA when already pinned
+	line    int    // 1-based line holding value
+	column  int    // 1-based column where value's scalar starts
+	value   string // literal scalar to rewrite on that line
+}
+
+// gitlabIncludePin is a resolved include ready to be written back.
+type gitlabIncludePin struct {
+	value    string // replacement scalar
+	tag      string // written as the trailing "# tag" comment
+	original string // literal scalar being replaced
+}
+
+// includePos is where an include's scalar starts, so several entries of a
+// flow sequence on one line each keep their pin.
+type includePos struct {
+	line   int // 1-based
+	column int // 1-based
+}
+
+// parseGitLabIncludes walks the top-level include: key and returns every
+// component, project+ref and GitLab raw remote entry with its source line.
+// local: and template: entries are GitLab-internal and have nothing to pin.
+func parseGitLabIncludes(content, gitlabURL string) []gitlabInclude {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	if gitlabURL == "" {
+		gitlabURL = defaultGitLabURL
+	}
+	gitlabURL = strings.TrimRight(gitlabURL, "/")
+	lines := strings.Split(content, "\n")
+
+	include := findMappingValue(doc.Content[0], "include")
+	if include == nil {
+		return nil
+	}
+	items := []*yaml.Node{include}
+	if include.Kind == yaml.SequenceNode {
+		items = include.Content
+	}
+
+	var out []gitlabInclude
+	for _, item := range items {
+		switch item.Kind {
+		case yaml.ScalarNode:
+			if inc, ok := parseGitLabRemoteInclude(item, lines); ok {
+				out = append(out, inc)
+			}
+		case yaml.MappingNode:
+			if n := findMappingValue(item, "component"); n != nil {
+				if inc, ok := parseGitLabComponentInclude(n, lines); ok {
+					out = append(out, inc)
+				}
+				continue
+			}
+			if n := findMappingValue(item, "remote"); n != nil {
+				if inc, ok := parseGitLabRemoteInclude(n, lines); ok {
+					out = append(out, inc)
+				}
+				continue
+			}
+			project, ref := findMappingValue(item, "project"), findMappingValue(item, "ref")
+			if project == nil || ref == nil || project.Value == "" || ref.Value == "" {
+				continue
+			}
+			inc := gitlabInclude{kind: gitlabIncludeProject, baseURL: gitlabURL, project: project.Value,
+				ref: ref.Value, line: ref.Line, column: ref.Column, value: ref.Value}
+			inc.sha, inc.ref = pinnedIncludeRef(ref.Value, lineAt(lines, ref.Line))
+			out = append(out, inc)
+		}
+	}
+	return out
+}
+
+func parseGitLabComponentInclude(n *yaml.Node, lines []string) (gitlabInclude, bool) {
+	name, version, ok := strings.Cut(n.Value, "@")
+	if !ok || version == "" || strings.Contains(n.Value, "$") {
+		return gitlabInclude{}, false
+	}
+	host, project, err := gitlabComponentPath(name)
+	if err != nil {
+		return gitlabInclude{}, false
+	}
+	inc := gitlabInclude{kind: gitlabIncludeComponent, baseURL: "https://" + host, project: project, name: name,
+		line: n.Line, column: n.Column, value: n.Value}
+	inc.sha, inc.ref = pinnedIncludeRef(version, lineAt(lines, n.Line))
+	return inc, true
+}
+
+func parseGitLabRemoteInclude(n *yaml.Node, lines []string) (gitlabInclude, bool) {
+	m := gitlabRemoteRawRe.FindStringSubmatch(n.Value)
+	if m == nil {
+		if strings.HasPrefix(n.Value, "http") {
+			log.Info().Str("remote", n.Value).Msg("remote include is not a GitLab raw URL, cannot pin")
+		}
+		return gitlabInclude{}, false
+	}
+	inc := gitlabInclude{kind: gitlabIncludeRemote, baseURL: m[1], project: m[2], line: n.Line, column: n.Column, value: n.Value}
+	inc.sha, inc.ref = pinnedIncludeRef(m[3], lineAt(lines, n.Line))
+	return inc, true
+}
+
+// pinnedIncludeRef returns (sha, tag) for a ref already pinned as "sha # tag",
+// or ("", ref) for a mutable ref.
+func pinnedIncludeRef(ref, line string) (sha, tag string) {
+	if !shaRe.MatchString(ref) {
+		return "", ref
+	}
+	if _, comment, ok := strings.Cut(line, "#"); ok {
+		if m := commentTagRe.FindStringSubmatch("#" + comment); m != nil && !strings.HasPrefix(m[1], "ghat:") {
+			return ref, m[1]
+		}
+	}
+	return ref, ""
+}
+
+func lineAt(lines []string, line int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
+
+// rewriteGitLabIncludes writes each pin back onto its line as "value # tag",
+// replacing any existing trailing comment. Flow-style entries keep their
+// closing brackets and get no comment. Pins sharing a line are written right
+// to left, so the columns of those before them still hold.
+func rewriteGitLabIncludes(content string, pins map[includePos]gitlabIncludePin) string {
+	lines := strings.Split(content, "\n")
+	positions := make([]includePos, 0, len(pins))
+	for pos := range pins {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, k int) bool {
+		if positions[i].line != positions[k].line {
+			return positions[i].line < positions[k].line
+		}
+		return positions[i].column > positions[k].column
+	})
+	for _, pos := range positions {
+		pin, n := pins[pos], pos.line
+		if n < 1 || n > len(lines) {
+			continue
+		}
+		line := lines[n-1]
+		start := min(max(pos.column-1, 0), len(line))
+		i := strings.Index(line[start:], pin.original)
+		if i < 0 {
+			continue
+		}
+		i += start
+		end := i + len(pin.original)
+		quote := ""
+		if end < len(line) && (line[end] == '"' || line[end] == '\'') {
+			quote = string(line[end])
+			end++
+		}
+		rest := strings.TrimSpace(line[end:])
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			lines[n-1] = line[:i] + pin.value + quote + line[end:]
+			continue
+		}
+		lines[n-1] = line[:i] + pin.value + quote + " # " + pin.tag
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pinGitLabIncludes resolves every include in content to a commit SHA and
+// returns the rewritten content. Suppressed lines, dynamic refs and lookups
+// that fail are left untouched.
+func (f *Flags) pinGitLabIncludes(file, content string) string {
+	lines := strings.Split(content, "\n")
+	pins := map[includePos]gitlabIncludePin{}
+	for _, inc := range parseGitLabIncludes(content, f.GitLabURL) {
+		if ok, reason := parseSuppression(lineAt(lines, inc.line)); ok {
+			log.Info().Str("include", inc.value).Str("reason", reason).Msg("skipping suppressed include")
+			continue
+		}
+		if inc.ref == "" || strings.HasPrefix(inc.ref, "$") {
+			continue
+		}
+		name := inc.name
+		if name == "" {
+			name = inc.project
+		}
+		if f.frozen(SourceGitLabComponent, name) {
+			continue
+		}
+
+		sha, tag, isTag, err := f.resolveGitLabInclude(inc)
+		if err != nil {
+			log.Warn().Err(err).Str("include", inc.value).Msg("failed to resolve include ref, skipping")
+			continue
+		}
+
+		var moved bool
+		if isTag {
+			moved, err = f.observeTag(LedgerEntry{Kind: SourceGitLabComponent, Name: inc.project, URL: inc.baseURL, Tag: tag, SHA: sha})
+			if err != nil {
+				continue
+			}
+		}
+
+		mutation := isTag && isTagMutation(inc.sha, inc.ref, sha, tag)
+		if mutation {
+			log.Warn().Msgf("SUSPICIOUS: %s@%s — SHA changed from %s to %s with the same tag. "+
+				"The tag may have been moved to a different commit. Verify this is intentional before accepting.",
+				inc.project, tag, inc.sha, sha)
+		}
+
+		var value string
+		switch inc.kind {
+		case gitlabIncludeComponent:
+			value = inc.name + "@" + sha
+		case gitlabIncludeRemote:
+			m := gitlabRemoteRawRe.FindStringSubmatch(inc.value)
+			value = m[1] + "/" + m[2] + "/-/raw/" + sha + m[4]
+		default:
+			value = sha
+		}
+		log.Info().Str("old", inc.value).Str("new", value).Str("tag", tag).Msg("Include update")
+		if value != inc.value {
+			oldRef := inc.ref
+			if inc.sha != "" {
+				oldRef = inc.sha
+			}
+			f.recordChange(Change{File: file, Line: inc.line, Ecosystem: SourceGitLabComponent, Name: name,
+				OldRef: oldRef, NewRef: sha, Tag: tag, Mutation: mutation || moved})
+		}
+		pins[includePos{line: inc.line, column: inc.column}] = gitlabIncludePin{value: value, tag: tag, original: inc.value}
+	}
+	return rewriteGitLabIncludes(content, pins)
+}
+
+// resolveGitLabInclude returns the SHA and tag an include should be pinned to.
+// Components accept ~latest and partial versions (1, 1.2), which resolve to
+// the newest matching release older than the cooldown that the include's
+// policy permits; anything else is pinned as written. isTag is false for
+// branch refs, which move by design.
+func (f *Flags) resolveGitLabInclude(inc gitlabInclude) (sha, tag string, isTag bool, err error) {
+	if inc.kind == gitlabIncludeComponent {
+		if inc.ref == gitlabLatestVersion || isPartialVersion(inc.ref) {
+			days := f.cooldown(SourceGitLabComponent)
+			prefix := ""
+			if inc.ref != gitlabLatestVersion {
+				prefix = inc.ref
+			}
+			policy := f.policy(SourceGitLabComponent, inc.name)
+			sha, tag, err = latestGitLabRelease(inc.baseURL, inc.project, f.gitlabToken(inc.baseURL), days, prefix, policy)
+			return sha, tag, true, err
+		}
+	}
+	sha, isTag, err = resolveGitLabRef(inc.baseURL, inc.project, inc.ref, f.gitlabToken(inc.baseURL))
+	return sha, inc.ref, isTag, err
+}
+
+// gitlabToken returns --gitlab-token for lookups on the --gitlab-url
+// instance and "" for any other host, since components and remote: includes
+// name their own host and a crafted one could otherwise collect the token.
+func (f *Flags) gitlabToken(baseURL string) string {
+	configured := f.GitLabURL
+	if configured == "" {
+		configured = defaultGitLabURL
+	}
+	want, err := url.Parse(configured)
+	if err != nil {
+		return ""
+	}
+	got, err := url.Parse(baseURL)
+	if err != nil || !strings.EqualFold(got.Host, want.Host) {
+		return ""
+	}
+	return f.GitLabToken
+}
+
+// isPartialVersion reports whether v is a major or major.minor version that
+// GitLab expands to the newest matching component release.
+func isPartialVersion(v string) bool {
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(parts) > 2 {
+		return false
+	}
+	for _, p := range parts {
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func gitlabProjectAPI(baseURL, project string) string {
+	return strings.TrimRight(baseURL, "/") + "/api/v4/projects/" + strings.ReplaceAll(url.QueryEscape(project), "+", "%20")
+}
+
+// resolveGitLabRef resolves a tag, branch or SHA on a GitLab project to a
+// commit SHA. Tags are tried first so callers can tell them from branches.
+func resolveGitLabRef(baseURL, project, ref, token string) (sha string, isTag bool, err error) {
+	if sha, err := gitlabTagSHA(baseURL, project, ref, token); err == nil {
+		return sha, true, nil
+	}
+
+	var commit struct {
+		ID string `json:"id"`
+	}
+	apiURL := gitlabProjectAPI(baseURL, project) + "/repository/commits/" + url.PathEscape(ref)
+	if err := gitlabGet(apiURL, token, &commit); err != nil {
+		return "", false, err
+	}
+	if commit.ID == "" {
+		return "", false, fmt.Errorf("no commit SHA for %s@%s", project, ref)
+	}
+	return commit.ID, false, nil
+}
+
+// gitlabTagSHA returns the commit SHA a GitLab tag points at.
+func gitlabTagSHA(baseURL, project, tag, token string) (string, error) {
+	apiURL := gitlabProjectAPI(baseURL, project) + "/repository/tags/" + url.QueryEscape(tag)
+	var tagResp struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := gitlabGet(apiURL, token, &tagResp); err != nil {
+		return "", err
+	}
+	if tagResp.Commit.ID == "" {
+		return "", fmt.Errorf("no commit SHA in GitLab tag response for %s@%s", project, tag)
+	}
+	return tagResp.Commit.ID, nil
+}
+
+// latestGitLabRelease returns the highest release of project that is at least
+// days old, that p permits and, when prefix is set, whose version starts with
+// prefix.
+func latestGitLabRelease(baseURL, project, token string, days uint, prefix string, p *Policy) (sha, tag string, err error) {
+	var releases []struct {
+		TagName    string    `json:"tag_name"`
+		ReleasedAt time.Time `json:"released_at"`
+		Commit     struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := gitlabGet(gitlabProjectAPI(baseURL, project)+"/releases?per_page=100", token, &releases); err != nil {
+		return "", "", err
+	}
+
+	limit := time.Now().Add(-time.Duration(int64(days) * dayInNanos))
+	prefix = strings.TrimPrefix(prefix, "v")
+	var candidates []any
+	shas := map[string]string{}
+	for _, r := range releases {
+		if r.Commit.ID == "" || r.ReleasedAt.After(limit) || !p.permits(prefix, r.TagName) {
+			continue
+		}
+		if v := strings.TrimPrefix(r.TagName, "v"); prefix != "" && v != prefix && !strings.HasPrefix(v, prefix+".") {
+			continue
+		}
+		candidates = append(candidates, map[string]any{"name": r.TagName})
+		shas[r.TagName] = r.Commit.ID
+	}
+	if len(candidates) == 0 {
+		return "", "", fmt.Errorf("no release of %s matches %q older than %d days", project, prefix, days)
+	}
+	tag, _ = candidates[pickLatestTag(candidates)].(map[string]any)["name"].(string)
+	return shas[tag], tag, nil
+}
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	includeSHA    = "1111111111111111111111111111111111111111"
+	includeOldSHA = "2222222222222222222222222222222222222222"
+)
+
+func TestParseGitLabIncludes(t *testing.T) {
+	t.Parallel()
+
+	content := `include:
+  - component: gitlab.com/components/opentofu/full-pipeline@0.1.0
+  - project: my-group/templates
+    ref: main
+    file: /ci.yml
+  - remote: 'https://gitlab.example.com/ops/ci/-/raw/v2.0.0/jobs.yml'
+  - component: $CI_SERVER_FQDN/x/y/z@1.0.0
+  - local: /local.yml
+  - template: Auto-DevOps.gitlab-ci.yml
+  - project: my-group/pinned
+    ref: ` + includeOldSHA + ` # v1.0.0
+build:
+  script: echo hi
+`
+	got := parseGitLabIncludes(content, "https://gitlab.internal/")
+
+	want := []gitlabInclude{
+		{kind: gitlabIncludeComponent, baseURL: "https://gitlab.com", project: "components/opentofu",
+			name: "gitlab.com/components/opentofu/full-pipeline", ref: "0.1.0", line: 2},
+		{kind: gitlabIncludeProject, baseURL: "https://gitlab.internal", project: "my-group/templates", ref: "main", line: 4},
+		{kind: gitlabIncludeRemote, baseURL: "https://gitlab.example.com", project: "ops/ci", ref: "v2.0.0", line: 6},
+		{kind: gitlabIncludeProject, baseURL: "https://gitlab.internal", project: "my-group/pinned", ref: "v1.0.0",
+			sha: includeOldSHA, line: 11},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseGitLabIncludes() returned %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.kind != w.kind || g.baseURL != w.baseURL || g.project != w.project || g.name != w.name ||
+			g.ref != w.ref || g.sha != w.sha || g.line != w.line {
+			t.Errorf("entry %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestParseGitLabIncludes_ScalarForms(t *testing.T) {
+	t.Parallel()
+
+	got := parseGitLabIncludes("include: https://gitlab.com/a/b/-/raw/main/ci.yml\n", "")
+	if len(got) != 1 || got[0].kind != gitlabIncludeRemote || got[0].ref != "main" || got[0].project != "a/b" {
+		t.Errorf("parseGitLabIncludes(scalar) = %+v", got)
+	}
+
+	if got := parseGitLabIncludes("include: /local.yml\n", ""); len(got) != 0 {
+		t.Errorf("parseGitLabIncludes(local) = %+v, want none", got)
+	}
+}
+
+func TestRewriteGitLabIncludes(t *testing.T) {
+	t.Parallel()
+
+	content := "include:\n" +
+		"  - component: \"gitlab.com/c/p/name@1.0.0\"\n" +
+		"  - project: g/p\n" +
+		"    ref: main # old comment\n" +
+		"  - { project: g/q, ref: v1 }\n"
+	pins := map[includePos]gitlabIncludePin{
+		{2, 16}: {value: "gitlab.com/c/p/name@" + includeSHA, tag: "1.0.0", original: "gitlab.com/c/p/name@1.0.0"},
+		{4, 10}: {value: includeSHA, tag: "main", original: "main"},
+		{5, 26}: {value: includeSHA, tag: "v1", original: "v1"},
+	}
+	want := "include:\n" +
+		"  - component: \"gitlab.com/c/p/name@" + includeSHA + "\" # 1.0.0\n" +
+		"  - project: g/p\n" +
+		"    ref: " + includeSHA + " # main\n" +
+		"  - { project: g/q, ref: " + includeSHA + " }\n"
+	if got := rewriteGitLabIncludes(content, pins); got != want {
+		t.Errorf("rewriteGitLabIncludes() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestIsPartialVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"1": true, "1.2": true, "v1.2": true, "1.2.3": false, "~latest": false, "main": false, "1.": false,
+	}
+	for in, want := range tests {
+		if got := isPartialVersion(in); got != want {
+			t.Errorf("isPartialVersion(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func fakeGitLab(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.EscapedPath(), "/repository/tags/v1.0.0"):
+			_, _ = w.Write([]byte(`{"name":"v1.0.0","commit":{"id":"` + includeSHA + `"}}`))
+		case strings.HasSuffix(r.URL.EscapedPath(), "/repository/commits/main"):
+			_, _ = w.Write([]byte(`{"id":"` + includeSHA + `"}`))
+		case strings.HasSuffix(r.URL.EscapedPath(), "/releases"):
+			recent := time.Now().Format(time.RFC3339)
+			old := time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339)
+			_, _ = w.Write([]byte(`[{"tag_name":"v1.1.0","released_at":"` + recent + `","commit":{"id":"` + includeOldSHA + `"}},` +
+				`{"tag_name":"v1.0.0","released_at":"` + old + `","commit":{"id":"` + includeSHA + `"}}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUpdateGitlab_Includes(t *testing.T) {
+	t.Parallel()
+
+	srv := fakeGitLab(t)
+	content := "include:\n" +
+		"  - project: group/templates\n" +
+		"    ref: main\n" +
+		"  - project: group/tagged\n" +
+		"    ref: v1.0.0\n" +
+		"  - project: group/suppressed\n" +
+		"    ref: v1.0.0 # ghat:suppress\n" +
+		"  - remote: " + srv.URL + "/group/tpl/-/raw/v1.0.0/ci.yml\n" +
+		"build:\n  script: echo hi\n"
+
+	tests := []struct {
+		name   string
+		dryRun bool
+		want   []string
+	}{
+		{"dry run leaves file", true, []string{"    ref: main\n", "    ref: v1.0.0\n"}},
+		{"pins", false, []string{
+			"    ref: " + includeSHA + " # main\n",
+			"    ref: " + includeSHA + " # v1.0.0\n",
+			"    ref: v1.0.0 # ghat:suppress\n",
+			"  - remote: " + srv.URL + "/group/tpl/-/raw/" + includeSHA + "/ci.yml # v1.0.0\n",
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			file := filepath.Join(dir, gitlab)
+			if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			f := &Flags{Directory: dir, DryRun: tt.dryRun, GitLabURL: srv.URL, Silent: true}
+			if err := f.UpdateGitlab(); err != nil {
+				t.Fatalf("UpdateGitlab() error = %v", err)
+			}
+			got, _ := os.ReadFile(file)
+			for _, w := range tt.want {
+				if !strings.Contains(string(got), w) {
+					t.Errorf("output missing %q:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateGitlab_FlowIncludes(t *testing.T) {
+	t.Parallel()
+
+	srv := fakeGitLab(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, gitlab)
+	content := "include: [{project: group/a, ref: main}, {project: group/b, ref: v1.0.0}]\nbuild:\n  script: echo hi\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f := &Flags{Directory: dir, GitLabURL: srv.URL, Silent: true}
+	if err := f.UpdateGitlab(); err != nil {
+		t.Fatalf("UpdateGitlab() error = %v", err)
+	}
+	got, _ := os.ReadFile(file)
+	want := "include: [{project: group/a, ref: " + includeSHA + "}, {project: group/b, ref: " + includeSHA + "}]\n"
+	if !strings.HasPrefix(string(got), want) {
+		t.Errorf("UpdateGitlab() wrote\n%s\nwant\n%s", got, want)
+	}
+	if len(f.Changes) != 2 {
+		t.Errorf("Changes = %+v, want both includes", f.Changes)
+	}
+}
+
+func TestLatestGitLabRelease_Stable(t *testing.T) {
+	t.Parallel()
+
+	srv := fakeGitLab(t)
+	tests := []struct {
+		name            string
+		days            uint
+		prefix, wantTag string
+	}{
+		{"newest", 0, "", "v1.1.0"},
+		{"stable skips fresh release", 7, "", "v1.0.0"},
+		{"partial version", 0, "1.0", "v1.0.0"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, tag, err := latestGitLabRelease(srv.URL, "components/x", "", tt.days, tt.prefix, nil)
+			if err != nil {
+				t.Fatalf("latestGitLabRelease() error = %v", err)
+			}
+			if tag != tt.wantTag {
+				t.Errorf("latestGitLabRelease() tag = %q, want %q", tag, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestFlags_GitLabToken(t *testing.T) {
+	t.Parallel()
+
+	f := &Flags{GitLabURL: "https://gitlab.example.com/", GitLabToken: "secret"}
+	tests := map[string]string{
+		"https://gitlab.example.com": "secret",
+		"https://GITLAB.example.com": "secret",
+		"https://gitlab.com":         "",
+		"https://evil.example.com":   "",
+	}
+	for baseURL, want := range tests {
+		if got := f.gitlabToken(baseURL); got != want {
+			t.Errorf("gitlabToken(%q) = %q, want %q", baseURL, got, want)
+		}
+	}
+	if got := (&Flags{GitLabToken: "secret"}).gitlabToken("https://gitlab.com"); got != "secret" {
+		t.Errorf("gitlabToken() = %q, want the token for the default instance", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -23,7 +24,7 @@ type hostProvider interface {
 	ListRepos() ([]hostRepo, error)
 	RepoFromName(name string) (hostRepo, error)
 	PRExists(r hostRepo, branch string) (open bool, prURL, mergeID string, err error)
-	CreatePR(r hostRepo, head, base string) (prURL, mergeID string, err error)
+	CreatePR(r hostRepo, head, base, body string) (prURL, mergeID string, err error)
 	EnableAutoMerge(mergeID string) error
 	WaitForRateLimit(threshold int)
 }
@@ -50,6 +51,7 @@ func newHost(provider, owner, token, baseURL string) (hostProvider, error) {
 		if baseURL == "" {
 			baseURL = "https://gitlab.com"
 		}
+		trustTokenHost(baseURL)
 		return &gitlabHost{owner: owner, token: token, baseURL: strings.TrimRight(baseURL, "/")}, nil
 	default:
 		return nil, fmt.Errorf("unknown --provider %q (supported: github, gitlab)", provider)
@@ -64,11 +66,11 @@ type githubHost struct {
 }
 
 func (h *githubHost) ListRepos() ([]hostRepo, error) {
-	u := "https://api.github.com/user/repos?type=owner&per_page=100"
+	u := githubAPI("/user/repos?type=owner&per_page=100")
 	if h.owner != "" {
-		u = "https://api.github.com/orgs/" + h.owner + "/repos?per_page=100"
+		u = githubAPI("/orgs/" + h.owner + "/repos?per_page=100")
 		if _, _, err := getPagedGithubBody(h.token, u); err != nil {
-			u = "https://api.github.com/users/" + h.owner + "/repos?per_page=100"
+			u = githubAPI("/users/" + h.owner + "/repos?per_page=100")
 		}
 	}
 	var all []hostRepo
@@ -101,13 +103,13 @@ func (h *githubHost) ListRepos() ([]hostRepo, error) {
 }
 
 func (h *githubHost) RepoFromName(name string) (hostRepo, error) {
-	clone := withBasicAuth("https://github.com/"+name+".git", "x-access-token", h.token)
+	clone := withBasicAuth(githubWebURL()+"/"+name+".git", "x-access-token", h.token)
 	return hostRepo{Name: name, CloneURL: clone, id: name}, nil
 }
 
 func (h *githubHost) PRExists(r hostRepo, branch string) (bool, string, string, error) {
 	owner := strings.SplitN(r.id, "/", 2)[0]
-	u := fmt.Sprintf("https://api.github.com/repos/%s/pulls?head=%s:%s&state=open", r.id, owner, branch)
+	u := githubAPI(fmt.Sprintf("/repos/%s/pulls?head=%s:%s&state=open", r.id, owner, branch))
 	body, err := GetGithubBody(h.token, u)
 	if err != nil {
 		return false, "", "", err
@@ -124,11 +126,11 @@ func (h *githubHost) PRExists(r hostRepo, branch string) (bool, string, string,
 	return true, "", "", nil
 }
 
-func (h *githubHost) CreatePR(r hostRepo, head, base string) (string, string, error) {
-	u := fmt.Sprintf("https://api.github.com/repos/%s/pulls", r.id)
+func (h *githubHost) CreatePR(r hostRepo, head, base, body string) (string, string, error) {
+	u := githubAPI("/repos/" + r.id + "/pulls")
 	payload := map[string]string{
 		"title": "chore: pin dependencies to immutable SHAs via ghat",
-		"body":  prBody,
+		"body":  body,
 		"head":  head,
 		"base":  base,
 	}
@@ -152,12 +154,12 @@ func (h *githubHost) EnableAutoMerge(nodeID string) error {
 		"query":     query,
 		"variables": map[string]string{"id": nodeID},
 	})
-	_, err := postGithubBody(h.token, "https://api.github.com/graphql", payload)
+	_, err := postGithubBody(h.token, githubGraphQLURL(), payload)
 	return err
 }
 
 func (h *githubHost) WaitForRateLimit(threshold int) {
-	body, err := GetGithubBody(h.token, "https://api.github.com/rate_limit")
+	body, err := GetGithubBody(h.token, githubAPI("/rate_limit"))
 	if err != nil {
 		return
 	}
@@ -254,11 +256,11 @@ func (h *gitlabHost) PRExists(r hostRepo, branch string) (bool, string, string,
 	return true, "", "", nil
 }
 
-func (h *gitlabHost) CreatePR(r hostRepo, head, base string) (string, string, error) {
+func (h *gitlabHost) CreatePR(r hostRepo, head, base, body string) (string, string, error) {
 	u := h.api("/projects/" + r.id + "/merge_requests")
 	payload := map[string]interface{}{
 		"title":                "chore: pin dependencies to immutable SHAs via ghat",
-		"description":          prBody,
+		"description":          body,
 		"source_branch":        head,
 		"target_branch":        base,
 		"remove_source_branch": true,
@@ -298,3 +300,25 @@ func (h *gitlabHost) WaitForRateLimit(threshold int) {}
 
 const prBody = "Automated dependency pinning by [ghat](https://github.com/JamesWoolfenden/ghat).\n\n" +
 	"Pins GitHub Actions, pre-commit hooks, Terraform modules/providers, Dockerfiles, and Kubernetes images to SHA digests."
+
+// prDescription is prBody followed by the breaking input changes swot found
+// in the actions it bumped, so a reviewer knows which steps to fix first.
+func prDescription(dir string, changes []Change) string {
+	var b strings.Builder
+	for _, c := range changes {
+		if len(c.Breaking) == 0 {
+			continue
+		}
+		file := c.File
+		if abs, err := filepath.Abs(dir); err == nil {
+			if rel, err := filepath.Rel(abs, c.File); err == nil {
+				file = filepath.ToSlash(rel)
+			}
+		}
+		fmt.Fprintf(&b, "- `%s` → %s (`%s` line %d): %s\n", c.Name, c.Tag, file, c.Line, strings.Join(c.Breaking, "; "))
+	}
+	if b.Len() == 0 {
+		return prBody
+	}
+	return prBody + "\n\n### Breaking input changes\n\n" + b.String()
+}
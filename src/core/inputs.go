@@ -0,0 +1,314 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// retiredRuntimes are the runs.using values GitHub's runners no longer
+// support; an action on one runs on a newer Node, if at all.
+var retiredRuntimes = []string{"node12", "node16"}
+
+// dockerArgs are the with: keys a docker action accepts without declaring.
+var dockerArgs = []string{"args", "entrypoint"}
+
+// actionManifest is the part of an action.yml the input checks read.
+type actionManifest struct {
+	Inputs map[string]actionInput `yaml:"inputs"`
+	Runs   struct {
+		Using string `yaml:"using"`
+	} `yaml:"runs"`
+}
+
+// actionInput is one declared input. Required is kept as text since action
+// authors write both true and 'true'; Default is untyped so that an explicit
+// empty default still counts as one.
+type actionInput struct {
+	Required           string      `yaml:"required"`
+	Default            interface{} `yaml:"default"`
+	DeprecationMessage string      `yaml:"deprecationMessage"`
+}
+
+// mandatory reports whether a step must pass the input itself.
+func (i actionInput) mandatory() bool {
+	return strings.EqualFold(i.Required, "true") && i.Default == nil
+}
+
+// loadActionManifest fetches and parses the action.yml of the action at ref.
+func loadActionManifest(token, ref string, cache *Cache) (*actionManifest, error) {
+	body, err := fetchActionManifest(token, ref, cache)
+	if err != nil {
+		return nil, err
+	}
+	var m actionManifest
+	if err := yaml.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("parse action.yml of %s: %w", ref, err)
+	}
+	return &m, nil
+}
+
+// usesStep is a step that calls a remote action, with the inputs it passes.
+type usesStep struct {
+	line int            // line of the uses: value
+	ref  string         // owner/repo[/path]@version
+	with map[string]int // input name → line
+}
+
+// action returns the step's action without its version.
+func (s usesStep) action() string {
+	action, _, _ := strings.Cut(s.ref, "@")
+	return action
+}
+
+// display names the action and version for a message, abbreviating a SHA.
+func (s usesStep) display() string {
+	action, version, _ := strings.Cut(s.ref, "@")
+	if shaRe.MatchString(version) {
+		version = shortSHA(version)
+	}
+	return action + "@" + version
+}
+
+// usesSteps returns the steps in a workflow's jobs, or an action.yml's
+// composite runs, that call a versioned remote action.
+func usesSteps(content []byte) []usesStep {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil
+	}
+	var lists []*yaml.Node
+	if jobs := findMappingValue(&root, "jobs"); jobs != nil && jobs.Kind == yaml.MappingNode {
+		for i := 1; i < len(jobs.Content); i += 2 {
+			lists = append(lists, findMappingValue(jobs.Content[i], "steps"))
+		}
+	}
+	lists = append(lists, findMappingValue(findMappingValue(&root, "runs"), "steps"))
+
+	var out []usesStep
+	for _, steps := range lists {
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, step := range steps.Content {
+			uses := findMappingValue(step, "uses")
+			if uses == nil || uses.Kind != yaml.ScalarNode {
+				continue
+			}
+			ref := strings.TrimSpace(uses.Value)
+			action, version, ok := strings.Cut(ref, "@")
+			if !ok || version == "" || strings.HasPrefix(version, "$") ||
+				strings.HasPrefix(action, ".") || strings.HasPrefix(action, "docker://") {
+				continue
+			}
+			s := usesStep{line: uses.Line, ref: ref, with: map[string]int{}}
+			if with := findMappingValue(step, "with"); with != nil && with.Kind == yaml.MappingNode {
+				for i := 0; i < len(with.Content); i += 2 {
+					s.with[with.Content[i].Value] = with.Content[i].Line
+				}
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// sortedInputs returns the names of inputs in order.
+func sortedInputs[V any](inputs map[string]V) []string {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// inputFindings checks a step against the action.yml of the version it
+// calls: inputs it passes that the action does not declare or has
+// deprecated, required inputs without a default it leaves out, and a
+// retired Node runtime.
+func inputFindings(step usesStep, m *actionManifest) []Finding {
+	var findings []Finding
+	if slices.Contains(retiredRuntimes, m.Runs.Using) {
+		findings = append(findings, Finding{Rule: RuleRetiredRuntime, Level: LevelWarning, Line: step.line,
+			Message: fmt.Sprintf("%s runs on %s, which GitHub Actions no longer supports", step.display(), m.Runs.Using)})
+	}
+	for _, name := range sortedInputs(step.with) {
+		input, declared := m.Inputs[name]
+		switch {
+		case !declared && m.Runs.Using == "docker" && slices.Contains(dockerArgs, name):
+		case !declared:
+			findings = append(findings, Finding{Rule: RuleActionInputs, Level: LevelWarning, Line: step.with[name],
+				Message: fmt.Sprintf("%q is not an input of %s", name, step.display())})
+		case input.DeprecationMessage != "":
+			findings = append(findings, Finding{Rule: RuleActionInputs, Level: LevelWarning, Line: step.with[name],
+				Message: fmt.Sprintf("input %q of %s is deprecated: %s", name, step.display(), input.DeprecationMessage)})
+		}
+	}
+	for _, name := range sortedInputs(m.Inputs) {
+		if _, passed := step.with[name]; !passed && m.Inputs[name].mandatory() {
+			findings = append(findings, Finding{Rule: RuleActionInputs, Level: LevelWarning, Line: step.line,
+				Message: fmt.Sprintf("%s requires input %q", step.display(), name)})
+		}
+	}
+	return findings
+}
+
+// inputChanges lists what breaks for step when its action moves from one
+// version to another: inputs it passes that the new version no longer
+// declares, and required inputs without a default that the new version adds
+// and the step does not pass.
+func inputChanges(step usesStep, from, to *actionManifest) []string {
+	var changes []string
+	for _, name := range sortedInputs(step.with) {
+		_, before := from.Inputs[name]
+		if _, after := to.Inputs[name]; before && !after {
+			changes = append(changes, fmt.Sprintf("input %q was removed", name))
+		}
+	}
+	for _, name := range sortedInputs(to.Inputs) {
+		_, passed := step.with[name]
+		if !passed && to.Inputs[name].mandatory() && !from.Inputs[name].mandatory() {
+			changes = append(changes, fmt.Sprintf("input %q is now required", name))
+		}
+	}
+	return changes
+}
+
+// ActionInputFindings fetches the action.yml of every versioned action a
+// workflow or composite action calls, at the version it pins, and checks
+// each step's with: keys and the action's runtime against it. Actions whose
+// action.yml cannot be fetched are skipped.
+func ActionInputFindings(content []byte, token string, cache *Cache) []Finding {
+	return NewActionManifests(token, cache).Findings(context.Background(), content)
+}
+
+// failedManifestTTL is how long ActionManifests remembers that an action.yml
+// could not be fetched before asking again.
+const failedManifestTTL = 10 * time.Minute
+
+// ActionManifests memoises action.yml lookups for a long-lived caller such as
+// the LSP, which checks the same workflow on every edit. Unlike resolve it
+// also remembers failures, for failedManifestTTL, so an action without an
+// action.yml, or a typo still being fixed, is not fetched again per keystroke.
+type ActionManifests struct {
+	token string
+	cache *Cache
+
+	mu     sync.Mutex
+	loaded map[string]*actionManifest
+	failed map[string]time.Time // ref → when the lookup failed
+}
+
+// NewActionManifests returns an empty memo that fetches with token and cache.
+func NewActionManifests(token string, cache *Cache) *ActionManifests {
+	return &ActionManifests{
+		token:  token,
+		cache:  cache,
+		loaded: map[string]*actionManifest{},
+		failed: map[string]time.Time{},
+	}
+}
+
+// load returns the action.yml of the action at ref, fetching it unless an
+// earlier lookup succeeded or recently failed.
+func (a *ActionManifests) load(ref string) (*actionManifest, bool) {
+	a.mu.Lock()
+	m, ok := a.loaded[ref]
+	failedAt, failed := a.failed[ref]
+	a.mu.Unlock()
+	if ok {
+		return m, true
+	}
+	if failed && time.Since(failedAt) < failedManifestTTL {
+		return nil, false
+	}
+
+	m, err := loadActionManifest(a.token, ref, a.cache)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		log.Debug().Str("action", ref).Err(err).Msg("no action.yml to check inputs against")
+		a.failed[ref] = time.Now()
+		return nil, false
+	}
+	delete(a.failed, ref)
+	a.loaded[ref] = m
+	return m, true
+}
+
+// Findings checks content as ActionInputFindings does, through the memo. It
+// stops fetching and returns nil once ctx is cancelled.
+func (a *ActionManifests) Findings(ctx context.Context, content []byte) []Finding {
+	var findings []Finding
+	for _, step := range usesSteps(content) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if m, ok := a.load(step.ref); ok {
+			findings = append(findings, inputFindings(step, m)...)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return markSuppressed(content, findings)
+}
+
+// checkBumpedInputs checks the actions swot moved to a new version in file
+// against the new version's action.yml, logging what the step passes wrongly,
+// and records on each Change the inputs that break between the old version
+// and the new.
+func (f *Flags) checkBumpedInputs(file, content string) {
+	changesMu.Lock()
+	bumped := map[int]int{} // line → index in f.Changes
+	for i, c := range f.Changes {
+		if c.File == file && c.Ecosystem == SourceGHA && c.OldRef != "" && c.OldRef != c.NewRef && c.OldRef != c.Tag {
+			bumped[c.Line] = i
+		}
+	}
+	changesMu.Unlock()
+	if len(bumped) == 0 {
+		return
+	}
+
+	for _, step := range usesSteps([]byte(content)) {
+		i, ok := bumped[step.line]
+		if !ok {
+			continue
+		}
+		current, err := loadActionManifest(f.GitHubToken, step.ref, f.Cache)
+		if err != nil {
+			log.Debug().Str("action", step.ref).Err(err).Msg("no action.yml to check inputs against")
+			continue
+		}
+		for _, finding := range markSuppressed([]byte(content), inputFindings(step, current)) {
+			if !finding.Suppressed {
+				log.Warn().Str("file", file).Int("line", finding.Line).Msg(finding.Message)
+			}
+		}
+
+		changesMu.Lock()
+		oldRef := step.action() + "@" + f.Changes[i].OldRef
+		changesMu.Unlock()
+		previous, err := loadActionManifest(f.GitHubToken, oldRef, f.Cache)
+		if err != nil {
+			log.Debug().Str("action", oldRef).Err(err).Msg("no action.yml to compare inputs with")
+			continue
+		}
+		breaking := inputChanges(step, previous, current)
+		for _, change := range breaking {
+			log.Warn().Str("file", file).Int("line", step.line).Msgf("%s: %s", step.display(), change)
+		}
+		changesMu.Lock()
+		f.Changes[i].Breaking = breaking
+		changesMu.Unlock()
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	inputsOldSHA = "1111111111111111111111111111111111111111"
+	inputsNewSHA = "2222222222222222222222222222222222222222"
+)
+
+// fakeActionVersions serves org/setup@v1 (inputs version and always-auth)
+// and its latest release v2.0.0, which drops always-auth, adds a required
+// token and runs on node16.
+func fakeActionVersions(t *testing.T) {
+	t.Helper()
+	manifests := map[string]string{
+		"v1": "inputs:\n  version:\n    required: true\n  always-auth:\n    default: 'false'\n" +
+			"runs:\n  using: node20\n",
+		inputsNewSHA: "inputs:\n  version:\n    required: true\n  token:\n    required: 'true'\n" +
+			"runs:\n  using: node16\n",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/org/setup/releases/latest", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v2.0.0"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/org/setup/git/ref/tags/v2.0.0", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"object":{"sha":"%s","type":"commit"}}`, inputsNewSHA)
+	})
+	mux.HandleFunc("/api/v3/repos/org/setup/contents/action.yml", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := manifests[r.URL.Query().Get("ref")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"content":%q,"encoding":"base64"}`, base64.StdEncoding.EncodeToString([]byte(body)))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL + "/api/v3")
+}
+
+func TestInputFindings(t *testing.T) {
+	t.Parallel()
+
+	manifest := "inputs:\n  path:\n    required: false\n  token:\n    required: true\n" +
+		"  key:\n    required: true\n    default: ''\n  old:\n    deprecationMessage: use path\n"
+	tests := []struct {
+		name     string
+		manifest string
+		content  string
+		want     []string
+	}{
+		{
+			name:     "unknown, deprecated and missing inputs",
+			manifest: manifest + "runs:\n  using: node20\n",
+			content: "jobs:\n  a:\n    steps:\n      - uses: org/act@" + inputsOldSHA + "\n" +
+				"        with:\n          old: x\n          paths: y\n",
+			want: []string{
+				`6 ghat-action-inputs input "old" of org/act@1111111 is deprecated: use path`,
+				`7 ghat-action-inputs "paths" is not an input of org/act@1111111`,
+				`4 ghat-action-inputs org/act@1111111 requires input "token"`,
+			},
+		},
+		{
+			name:     "retired runtime",
+			manifest: "runs:\n  using: node12\n",
+			content:  "runs:\n  using: composite\n  steps:\n    - uses: org/act@v1\n",
+			want:     []string{"4 ghat-retired-runtime org/act@v1 runs on node12, which GitHub Actions no longer supports"},
+		},
+		{
+			name:     "docker args",
+			manifest: "runs:\n  using: docker\n  image: Dockerfile\n",
+			content:  "jobs:\n  a:\n    steps:\n      - uses: org/act@v1\n        with:\n          args: --help\n",
+		},
+	}
+	for _, tt := range tests {
+		var m actionManifest
+		if err := yaml.Unmarshal([]byte(tt.manifest), &m); err != nil {
+			t.Fatal(err)
+		}
+		steps := usesSteps([]byte(tt.content))
+		if len(steps) != 1 {
+			t.Fatalf("%s: usesSteps() = %+v, want one step", tt.name, steps)
+		}
+		var got []string
+		for _, f := range inputFindings(steps[0], &m) {
+			got = append(got, fmt.Sprintf("%d %s %s", f.Line, f.Rule, f.Message))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: inputFindings() =\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUsesSteps_Skips(t *testing.T) {
+	t.Parallel()
+
+	content := "jobs:\n  call:\n    uses: org/shared/.github/workflows/ci.yml@v1\n  a:\n    steps:\n" +
+		"      - uses: ./local\n      - uses: docker://alpine:3.20\n      - uses: org/act@${{ env.V }}\n" +
+		"      - uses: org/act\n      - run: make\n"
+	if got := usesSteps([]byte(content)); len(got) != 0 {
+		t.Errorf("usesSteps() = %+v, want none", got)
+	}
+}
+
+func TestActionInputFindings(t *testing.T) {
+	fakeActionVersions(t)
+
+	content := "jobs:\n  a:\n    steps:\n" +
+		"      - uses: org/setup@v1\n        with:\n          version: 20\n          cache: npm # ghat:suppress\n" +
+		"      - uses: org/missing@v1\n"
+	var got []string
+	for _, f := range ActionInputFindings([]byte(content), "", nil) {
+		got = append(got, fmt.Sprintf("%d %s %v", f.Line, f.Message, f.Suppressed))
+	}
+	want := []string{`7 "cache" is not an input of org/setup@v1 true`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ActionInputFindings() = %q, want %q", got, want)
+	}
+}
+
+func TestActionManifests_Memoises(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path != "/repos/org/act/contents/action.yml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"content":%q,"encoding":"base64"}`, base64.StdEncoding.EncodeToString([]byte("runs:\n  using: node16\n")))
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL)
+
+	content := []byte("jobs:\n  a:\n    steps:\n      - uses: org/act@v1\n      - uses: org/missing@v1\n")
+	a := NewActionManifests("", nil)
+	if got := a.Findings(context.Background(), content); len(got) != 1 {
+		t.Fatalf("Findings() = %+v, want the retired runtime", got)
+	}
+	first := hits.Load()
+	if got := a.Findings(context.Background(), content); len(got) != 1 {
+		t.Fatalf("Findings() again = %+v, want the retired runtime", got)
+	}
+	if again := hits.Load(); again != first {
+		t.Errorf("second Findings() made %d requests, want none", again-first)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := a.Findings(ctx, content); got != nil {
+		t.Errorf("Findings() after cancel = %+v, want nil", got)
+	}
+}
+
+func TestUpdateGHA_BreakingInputs(t *testing.T) {
+	fakeActionVersions(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ci.yml")
+	content := "permissions: read-all\njobs:\n  test:\n    steps:\n" +
+		"      - uses: org/setup@v1\n        with:\n          version: 20\n          always-auth: true\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var days uint
+	f := &Flags{Days: &days, Silent: true}
+	if err := f.UpdateGHA(file); err != nil {
+		t.Fatalf("UpdateGHA() error = %v", err)
+	}
+	if len(f.Changes) != 1 {
+		t.Fatalf("UpdateGHA() recorded %d changes, want 1: %+v", len(f.Changes), f.Changes)
+	}
+	want := []string{`input "always-auth" was removed`, `input "token" is now required`}
+	if got := f.Changes[0].Breaking; !reflect.DeepEqual(got, want) {
+		t.Errorf("Change.Breaking = %q, want %q", got, want)
+	}
+
+	body := prDescription(dir, f.Changes)
+	if !strings.HasPrefix(body, prBody) ||
+		!strings.Contains(body, "- `org/setup` → v2.0.0 (`ci.yml` line 5): "+strings.Join(want, "; ")) {
+		t.Errorf("prDescription() =\n%s", body)
+	}
+}
+
+func TestPRDescription_NoBreaking(t *testing.T) {
+	t.Parallel()
+
+	changes := []Change{{File: "ci.yml", Line: 4, Ecosystem: SourceGHA, Name: "actions/checkout", OldRef: "v4", NewRef: inputsNewSHA}}
+	if got := prDescription(".", changes); got != prBody {
+		t.Errorf("prDescription() = %q, want prBody", got)
+	}
+}
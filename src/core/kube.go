@@ -24,25 +24,29 @@ var k8sKinds = map[string]bool{
 
 // UpdateKubes pins all Kubernetes manifests and Docker Compose files found in the scanned entries.
 func (f *Flags) UpdateKubes() error {
-	for _, file := range f.GetKubeFiles() {
+	err := eachFile(f.GetKubeFiles(), func(file string) error {
 		if err := f.UpdateKube(file); err != nil {
 			if f.ContinueOnError {
 				log.Warn().Err(err).Str("file", file).Msg("skipping file")
-				continue
+				return nil
 			}
 			return err
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	for _, file := range f.GetComposeFiles() {
+	return eachFile(f.GetComposeFiles(), func(file string) error {
 		if err := f.UpdateCompose(file); err != nil {
 			if f.ContinueOnError {
 				log.Warn().Err(err).Str("file", file).Msg("skipping file")
-				continue
+				return nil
 			}
 			return err
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // UpdateKube pins container image references in a single Kubernetes manifest file.
@@ -67,6 +71,9 @@ func (f *Flags) UpdateKube(file string) error {
 			continue
 		}
 		imgRef := parseImageReference(imageStr)
+		if f.frozen(SourceImage, imageDisplayName(imgRef)) {
+			continue
+		}
 		digest, err := f.getImageDigest(&imgRef)
 		if err != nil {
 			log.Warn().Err(err).Str("image", imageStr).Msg("failed to get digest, skipping")
@@ -74,10 +81,13 @@ func (f *Flags) UpdateKube(file string) error {
 		}
 
 		// Detect tag mutation: same tag, different digest.
-		if cur, ok := pinnedImages[imgRef.Tag]; ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag) {
+		cur, ok := pinnedImages[imgRef.Tag]
+		mutation := ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag)
+		if mutation {
 			log.Warn().Msgf("SUSPICIOUS: %s — digest changed from %s to %s with the same tag. "+
 				"The image tag may have been repointed to a different layer. Verify before accepting.", imageStr, cur, digest)
 		}
+		f.recordImageChange(file, string(content), SourceKube, imageStr, imgRef, digest, mutation)
 
 		// For already-SHA-pinned images: replace only the digest, preserving
 		// any existing # tag comment. For fresh images: write full # tag annotation.
@@ -242,15 +252,21 @@ func (f *Flags) UpdateCompose(file string) error {
 	replacement := string(content)
 	for _, imageStr := range images {
 		imgRef := parseImageReference(imageStr)
+		if f.frozen(SourceImage, imageDisplayName(imgRef)) {
+			continue
+		}
 		digest, err := f.getImageDigest(&imgRef)
 		if err != nil {
 			log.Warn().Err(err).Str("image", imageStr).Msg("failed to get digest, skipping")
 			continue
 		}
-		if cur, ok := pinnedImages[imgRef.Tag]; ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag) {
+		cur, ok := pinnedImages[imgRef.Tag]
+		mutation := ok && isTagMutation(cur, imgRef.Tag, digest, imgRef.Tag)
+		if mutation {
 			log.Warn().Msgf("SUSPICIOUS: %s — digest changed from %s to %s with the same tag. "+
 				"Verify before accepting.", imageStr, cur, digest)
 		}
+		f.recordImageChange(file, string(content), SourceCompose, imageStr, imgRef, digest, mutation)
 		if at := strings.Index(imageStr, "@sha256:"); at >= 0 {
 			newRef := imageStr[:at] + "@" + digest
 			replacement = strings.ReplaceAll(replacement, imageStr, newRef)
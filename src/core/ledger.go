@@ -0,0 +1,308 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LedgerEntry records the commit SHA (or image digest) a tag resolved to
+// the first time ghat saw it.
+type LedgerEntry struct {
+	Kind      string    `json:"kind"` // SourceGHA, SourcePreCommit, SourceGitLabComponent or SourceImage
+	Name      string    `json:"name"`
+	URL       string    `json:"url,omitempty"` // GitLab instance for SourceGitLabComponent
+	Tag       string    `json:"tag"`
+	SHA       string    `json:"sha"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+func (e LedgerEntry) key() string {
+	return e.Kind + "|" + e.URL + "|" + e.Name + "|" + e.Tag
+}
+
+func (e LedgerEntry) String() string {
+	sep := "@"
+	if e.Kind == SourceImage {
+		sep = ":"
+	}
+	return e.Name + sep + e.Tag
+}
+
+// Ledger is a trust-on-first-use record of every tag ghat has resolved.
+// A tag that later resolves to a different SHA has been moved upstream.
+type Ledger struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]*LedgerEntry
+}
+
+// DefaultLedgerPath is where the ledger lives unless --ledger says otherwise.
+// It sits in the config dir rather than the cache dir so that
+// `ghat cache clear` does not wipe the history it exists to keep.
+func DefaultLedgerPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "ghat", "ledger.json")
+}
+
+// OpenLedger loads the ledger at path; a missing file is an empty ledger.
+func OpenLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path, entries: map[string]*LedgerEntry{}}
+
+	data, err := os.ReadFile(path) // #nosec G304 — path is the user's --ledger
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger %s: %w", path, err)
+	}
+
+	var entries []LedgerEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse ledger %s: %w", path, err)
+	}
+	for i := range entries {
+		l.entries[entries[i].key()] = &entries[i]
+	}
+	return l, nil
+}
+
+// Observe records that e.Name@e.Tag resolved to e.SHA. When the ledger
+// already holds a different SHA for the tag, that SHA is kept and returned
+// with moved set.
+func (l *Ledger) Observe(e LedgerEntry) (previous string, moved bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now().UTC()
+	known, ok := l.entries[e.key()]
+	if !ok {
+		e.FirstSeen, e.LastSeen = now, now
+		l.entries[e.key()] = &e
+		return "", false
+	}
+	if known.SHA != e.SHA {
+		return known.SHA, true
+	}
+	known.LastSeen = now
+	return known.SHA, false
+}
+
+// Accept replaces the recorded SHA for e's tag, used once a move has been
+// reviewed.
+func (l *Ledger) Accept(e LedgerEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now().UTC()
+	if known, ok := l.entries[e.key()]; ok {
+		known.SHA, known.LastSeen = e.SHA, now
+		return
+	}
+	e.FirstSeen, e.LastSeen = now, now
+	l.entries[e.key()] = &e
+}
+
+// Entries returns the ledger sorted by kind, name and tag.
+func (l *Ledger) Entries() []LedgerEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := make([]LedgerEntry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key() < entries[j].key() })
+	return entries
+}
+
+// Save writes the ledger back to its path, creating the directory if needed.
+func (l *Ledger) Save() error {
+	data, err := json.MarshalIndent(l.Entries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write ledger %s: %w", l.path, err)
+	}
+	return os.Rename(tmp, l.path)
+}
+
+type movedTagError struct {
+	entry    LedgerEntry
+	previous string
+}
+
+func (e *movedTagError) Error() string {
+	return fmt.Sprintf("%s moved from %s to %s since ghat first resolved it", e.entry, e.previous, e.entry.SHA)
+}
+
+type ledgerVerifyError struct {
+	moved  int
+	failed int
+}
+
+func (e *ledgerVerifyError) Error() string {
+	return fmt.Sprintf("ledger verify: %d moved tags, %d entries could not be resolved", e.moved, e.failed)
+}
+
+// observeTag records a resolved tag in the ledger and warns when upstream
+// now returns a different SHA for a tag ghat has seen before. With
+// --fail-on-moved-tag it also returns an error, so the caller skips the pin
+// and the run fails.
+func (f *Flags) observeTag(e LedgerEntry) (bool, error) {
+	if f.Ledger == nil || e.Tag == "" || e.SHA == "" {
+		return false, nil
+	}
+	previous, moved := f.Ledger.Observe(e)
+	if !moved {
+		return false, nil
+	}
+
+	log.Warn().Msgf("SUSPICIOUS: %s now resolves to %s, but ghat recorded %s for this tag on an earlier run. "+
+		"The tag may have been moved. Verify, then accept it with `ghat ledger verify --update`.", e, e.SHA, previous)
+
+	err := &movedTagError{entry: e, previous: previous}
+	changesMu.Lock()
+	f.movedTags = append(f.movedTags, err)
+	changesMu.Unlock()
+	if f.FailOnMovedTag {
+		return true, err
+	}
+	return true, nil
+}
+
+// openLedger loads f.LedgerPath for a pinning run. An empty path disables the
+// ledger.
+func (f *Flags) openLedger() error {
+	if f.Ledger != nil || f.LedgerPath == "" {
+		return nil
+	}
+	ledger, err := OpenLedger(f.LedgerPath)
+	if err != nil {
+		return err
+	}
+	f.Ledger = ledger
+	return nil
+}
+
+// closeLedger saves the ledger (unless this is a dry run) and, with
+// --fail-on-moved-tag, turns any moved tags into the run's error.
+func (f *Flags) closeLedger() error {
+	if f.Ledger == nil {
+		return nil
+	}
+	var errs []error
+	if !f.DryRun {
+		if err := f.Ledger.Save(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if f.FailOnMovedTag {
+		for _, moved := range f.movedTags {
+			errs = append(errs, moved)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// VerifyLedger re-resolves every ledger entry and writes one line per entry
+// to w. It fails if any tag has moved or could not be resolved. With accept,
+// moved entries are updated to the SHA upstream now returns.
+func (f *Flags) VerifyLedger(w io.Writer, accept bool) error {
+	if err := f.openLedger(); err != nil {
+		return err
+	}
+	if f.Ledger == nil {
+		return fmt.Errorf("no ledger configured")
+	}
+
+	var moved, failed int
+	for _, e := range f.Ledger.Entries() {
+		current, err := f.resolveLedgerEntry(e)
+		switch {
+		case err != nil:
+			failed++
+			_, _ = fmt.Fprintf(w, "error  %s %s: %s\n", e.Kind, e, err)
+		case current != e.SHA:
+			moved++
+			_, _ = fmt.Fprintf(w, "moved  %s %s: %s -> %s\n", e.Kind, e, e.SHA, current)
+			if accept {
+				e.SHA = current
+				f.Ledger.Accept(e)
+			}
+		default:
+			_, _ = fmt.Fprintf(w, "ok     %s %s\n", e.Kind, e)
+		}
+	}
+
+	if accept && moved > 0 {
+		if err := f.Ledger.Save(); err != nil {
+			return err
+		}
+		moved = 0
+	}
+	if moved > 0 || failed > 0 {
+		return &ledgerVerifyError{moved: moved, failed: failed}
+	}
+	return nil
+}
+
+// resolveLedgerEntry asks upstream, bypassing the cache and the ledger
+// itself, what e's tag points at now.
+func (f *Flags) resolveLedgerEntry(e LedgerEntry) (string, error) {
+	switch e.Kind {
+	case SourceGHA:
+		return resolveTagSHA(e.Name, e.Tag, f.GitHubToken)
+	case SourcePreCommit:
+		if strings.HasPrefix(e.Name, GitHubPrefix) {
+			return resolveTagSHA(strings.TrimSuffix(strings.TrimPrefix(e.Name, GitHubPrefix), ".git"), e.Tag, f.GitHubToken)
+		}
+		return gitTagSHA(e.Name, e.Tag)
+	case SourceGitLabComponent:
+		return gitlabTagSHA(e.URL, e.Name, e.Tag, f.gitlabToken(e.URL))
+	case SourceImage:
+		ref := parseImageReference(e.Name + ":" + e.Tag)
+		uncached := &Flags{GitHubToken: f.GitHubToken}
+		return uncached.getImageDigest(&ref)
+	}
+	return "", fmt.Errorf("unknown ledger kind %q", e.Kind)
+}
+
+// gitTagSHA returns the commit a tag on a non-GitHub repository points at,
+// via `git ls-remote` as sift does.
+func gitTagSHA(repoURL, tag string) (string, error) {
+	if err := checkGitURL(repoURL); err != nil {
+		return "", err
+	}
+	out, err := recorded("git:ls-remote "+repoURL+" "+tag, func() (string, error) {
+		// #nosec G204 — repoURL and tag come from the user's own ledger and are
+		// passed as discrete argv elements after --, never through a shell.
+		cmd := exec.Command("git", "ls-remote", "--tags", "--", repoURL, "refs/tags/"+tag, "refs/tags/"+tag+"^{}")
+		out, err := cmd.Output()
+		return string(out), err
+	})
+	if err != nil {
+		return "", fmt.Errorf("git ls-remote %s: %w", repoURL, err)
+	}
+	sha, _, err := parseLsRemoteTags(out)
+	return sha, err
+}
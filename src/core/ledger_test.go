@@ -0,0 +1,228 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	ledgerOldSHA = "1111111111111111111111111111111111111111"
+	ledgerNewSHA = "2222222222222222222222222222222222222222"
+)
+
+func TestLedger_Observe(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "nested", "ledger.json")
+	l, err := OpenLedger(path)
+	if err != nil {
+		t.Fatalf("OpenLedger() on a missing file error = %v", err)
+	}
+
+	checkout := LedgerEntry{Kind: SourceGHA, Name: "actions/checkout", Tag: "v4.2.2", SHA: ledgerOldSHA}
+	if _, moved := l.Observe(checkout); moved {
+		t.Error("Observe() first sighting reported a move")
+	}
+	if _, moved := l.Observe(checkout); moved {
+		t.Error("Observe() same SHA reported a move")
+	}
+	image := LedgerEntry{Kind: SourceImage, Name: "nginx", Tag: "1.27", SHA: "sha256:abc"}
+	l.Observe(image)
+
+	if err := l.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	reopened, err := OpenLedger(path)
+	if err != nil {
+		t.Fatalf("OpenLedger() error = %v", err)
+	}
+	if n := len(reopened.Entries()); n != 2 {
+		t.Fatalf("reopened ledger has %d entries, want 2", n)
+	}
+
+	moved := checkout
+	moved.SHA = ledgerNewSHA
+	previous, isMoved := reopened.Observe(moved)
+	if !isMoved || previous != ledgerOldSHA {
+		t.Errorf("Observe() moved tag = (%q, %v), want (%q, true)", previous, isMoved, ledgerOldSHA)
+	}
+	if _, isMoved := reopened.Observe(moved); !isMoved {
+		t.Error("Observe() forgot the original SHA after a move")
+	}
+
+	reopened.Accept(moved)
+	if _, isMoved := reopened.Observe(moved); isMoved {
+		t.Error("Observe() still reports a move after Accept()")
+	}
+}
+
+func TestOpenLedger_Corrupt(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenLedger(path); err == nil {
+		t.Error("OpenLedger() on a corrupt file should error")
+	}
+}
+
+func TestFlags_ObserveTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		strict  bool
+		dryRun  bool
+		wantErr bool
+	}{
+		{name: "warn"},
+		{name: "fail on moved tag", strict: true, wantErr: true},
+		{name: "dry run still fails", strict: true, dryRun: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "ledger.json")
+			f := &Flags{LedgerPath: path, FailOnMovedTag: tt.strict, DryRun: tt.dryRun}
+			if err := f.openLedger(); err != nil {
+				t.Fatal(err)
+			}
+
+			e := LedgerEntry{Kind: SourcePreCommit, Name: "https://github.com/pre-commit/pre-commit-hooks", Tag: "v4.6.0", SHA: ledgerOldSHA}
+			if moved, err := f.observeTag(e); moved || err != nil {
+				t.Fatalf("observeTag() first sighting = (%v, %v)", moved, err)
+			}
+			e.SHA = ledgerNewSHA
+			moved, err := f.observeTag(e)
+			if !moved || (err != nil) != tt.wantErr {
+				t.Errorf("observeTag() moved tag = (%v, %v), want (true, wantErr %v)", moved, err, tt.wantErr)
+			}
+
+			if err := f.closeLedger(); (err != nil) != tt.wantErr {
+				t.Errorf("closeLedger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(path); tt.dryRun != os.IsNotExist(err) {
+				t.Errorf("ledger written = %v, want %v", err == nil, !tt.dryRun)
+			}
+		})
+	}
+}
+
+func TestFlags_ObserveTag_NoLedger(t *testing.T) {
+	t.Parallel()
+
+	f := &Flags{FailOnMovedTag: true}
+	if err := f.openLedger(); err != nil || f.Ledger != nil {
+		t.Fatalf("openLedger() with no path = (%v, %v)", f.Ledger, err)
+	}
+	if moved, err := f.observeTag(LedgerEntry{Kind: SourceGHA, Name: "a/b", Tag: "v1", SHA: ledgerOldSHA}); moved || err != nil {
+		t.Errorf("observeTag() without a ledger = (%v, %v)", moved, err)
+	}
+}
+
+// fakeTagServer serves actions/checkout@v4.2.2 as sha on a fake GitHub API.
+func fakeTagServer(t *testing.T, sha string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/actions/checkout/releases/latest", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v4.2.2"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/actions/checkout/git/ref/tags/v4.2.2", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"object":{"sha":"%s","type":"commit"}}`, sha)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL + "/api/v3")
+}
+
+func TestUpdateGHA_MovedTagInLedger(t *testing.T) {
+	fakeTagServer(t, ledgerNewSHA)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ci.yml")
+	content := "permissions: read-all\njobs:\n  test:\n    steps:\n      - uses: actions/checkout@v4\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var days uint
+	f := &Flags{Days: &days, Silent: true, FailOnMovedTag: true, LedgerPath: filepath.Join(dir, "ledger.json")}
+	if err := f.openLedger(); err != nil {
+		t.Fatal(err)
+	}
+	f.Ledger.Observe(LedgerEntry{Kind: SourceGHA, Name: "actions/checkout", Tag: "v4.2.2", SHA: ledgerOldSHA})
+
+	if err := f.UpdateGHA(file); err != nil {
+		t.Fatalf("UpdateGHA() error = %v", err)
+	}
+	if got, _ := os.ReadFile(file); string(got) != content {
+		t.Errorf("UpdateGHA() pinned a moved tag:\n%s", got)
+	}
+	if err := f.closeLedger(); err == nil || !strings.Contains(err.Error(), "actions/checkout@v4.2.2 moved") {
+		t.Errorf("closeLedger() error = %v, want a moved tag", err)
+	}
+}
+
+func TestFlags_VerifyLedger(t *testing.T) {
+	fakeTagServer(t, ledgerNewSHA)
+
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l, _ := OpenLedger(path)
+	l.Observe(LedgerEntry{Kind: SourceGHA, Name: "actions/checkout", Tag: "v4.2.2", SHA: ledgerOldSHA})
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	f := &Flags{LedgerPath: path}
+	err := f.VerifyLedger(&out, false)
+	if got := fmt.Sprintf("%T", err); got != "*core.ledgerVerifyError" {
+		t.Errorf("VerifyLedger() error = %v (%s), want *core.ledgerVerifyError", err, got)
+	}
+	want := "moved  gha actions/checkout@v4.2.2: " + ledgerOldSHA + " -> " + ledgerNewSHA + "\n"
+	if out.String() != want {
+		t.Errorf("VerifyLedger() output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := (&Flags{LedgerPath: path}).VerifyLedger(&out, true); err != nil {
+		t.Fatalf("VerifyLedger(update) error = %v", err)
+	}
+	out.Reset()
+	if err := (&Flags{LedgerPath: path}).VerifyLedger(&out, false); err != nil {
+		t.Errorf("VerifyLedger() after update error = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "ok ") {
+		t.Errorf("VerifyLedger() after update output = %q", out.String())
+	}
+}
+
+func TestFlags_GetImageDigest_CachedMovedTag(t *testing.T) {
+	t.Parallel()
+
+	f := &Flags{LedgerPath: filepath.Join(t.TempDir(), "ledger.json"), FailOnMovedTag: true,
+		Cache: newCacheIn(t.TempDir(), time.Hour)}
+	if err := f.openLedger(); err != nil {
+		t.Fatal(err)
+	}
+	ref := parseImageReference("alpine:3.20")
+	f.Ledger.Observe(LedgerEntry{Kind: SourceImage, Name: imageDisplayName(ref), Tag: "3.20", SHA: "sha256:" + ledgerOldSHA})
+	if err := f.Cache.Set("digest:alpine:3.20", "sha256:"+ledgerNewSHA); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.getImageDigest(&ref); err == nil {
+		t.Error("getImageDigest() should fail on a cached digest the ledger has seen move")
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// FailOnNone disables lint's non-zero exit.
+const FailOnNone = "none"
+
+// levelRank orders finding levels for --fail-on.
+var levelRank = map[string]int{LevelNote: 1, LevelWarning: 2, LevelError: 3}
+
+type failOnError struct {
+	level string
+}
+
+func (e *failOnError) Error() string {
+	return fmt.Sprintf("unknown --fail-on level %q (valid: %s, %s, %s, %s)", e.level, LevelError, LevelWarning, LevelNote, FailOnNone)
+}
+
+type lintFailedError struct {
+	count int
+	level string
+}
+
+func (e *lintFailedError) Error() string {
+	return fmt.Sprintf("%d findings at %s or above", e.count, e.level)
+}
+
+// Lint runs the same static checks as the editor diagnostics over the scanned
+// files and reports each finding with file:line, level and rule id, as text
+// or SARIF. It fails when any unsuppressed finding is at or above f.FailOn.
+// No network calls are made.
+func (f *Flags) Lint() error {
+	if err := validateFormat(f.Format); err != nil {
+		return err
+	}
+	threshold := f.FailOn
+	if threshold == "" {
+		threshold = LevelWarning
+	}
+	if _, ok := levelRank[threshold]; !ok && threshold != FailOnNone {
+		return &failOnError{level: threshold}
+	}
+
+	findings := f.StaticFindings()
+
+	var w io.Writer = os.Stdout
+	if f.Output != "" {
+		file, err := os.Create(f.Output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", f.Output, err)
+		}
+		defer file.Close() //nolint:errcheck
+		w = file
+	}
+
+	if f.Format == FormatSARIF {
+		if err := WriteSARIF(w, f.Directory, findings); err != nil {
+			return err
+		}
+	} else {
+		writeLintText(w, f.Directory, findings)
+	}
+
+	if threshold == FailOnNone {
+		return nil
+	}
+	var failing int
+	for _, finding := range findings {
+		if !finding.Suppressed && levelRank[finding.Level] >= levelRank[threshold] {
+			failing++
+		}
+	}
+	if failing > 0 {
+		return &lintFailedError{count: failing, level: threshold}
+	}
+	return nil
+}
+
+// writeLintText prints unsuppressed findings as "file:line: level: message [rule]",
+// the form editors and CI problem matchers pick up.
+func writeLintText(w io.Writer, root string, findings []Finding) {
+	var suppressed int
+	for _, finding := range findings {
+		if finding.Suppressed {
+			suppressed++
+			continue
+		}
+		line := finding.Line
+		if line < 1 {
+			line = 1
+		}
+		_, _ = fmt.Fprintf(w, "%s:%d: %s: %s [%s]\n", relSlashPath(root, finding.File), line, finding.Level, finding.Message, finding.Rule)
+	}
+	if suppressed > 0 {
+		_, _ = fmt.Fprintf(w, "%d suppressed\n", suppressed)
+	}
+}
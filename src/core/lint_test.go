@@ -0,0 +1,103 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func lintFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	if err := os.MkdirAll(filepath.Dir(workflow), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := "permissions:\n  contents: read\njobs:\n  build:\n    steps:\n" +
+		"      - uses: actions/checkout@v4\n" +
+		"      - uses: actions/cache@v4 # ghat:suppress\n"
+	if err := os.WriteFile(workflow, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFlags_Lint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		failOn   string
+		format   string
+		wantErr  bool
+		wantType string
+	}{
+		{name: "warning fails by default", wantErr: true, wantType: "*core.lintFailedError"},
+		{name: "error threshold passes", failOn: LevelError},
+		{name: "none never fails", failOn: FailOnNone},
+		{name: "note fails", failOn: LevelNote, wantErr: true, wantType: "*core.lintFailedError"},
+		{name: "unknown level", failOn: "fatal", wantErr: true, wantType: "*core.failOnError"},
+		{name: "unknown format", failOn: FailOnNone, format: "xml", wantErr: true, wantType: "*core.unknownFormatError"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := lintFixture(t)
+			f := &Flags{Directory: dir, FailOn: tt.failOn, Format: tt.format, Output: filepath.Join(dir, "out.txt")}
+			f.Entries, _ = GetFiles(dir)
+
+			err := f.Lint()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := fmt.Sprintf("%T", err); tt.wantType != "" && got != tt.wantType {
+				t.Errorf("Lint() error type = %s, want %s", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestFlags_LintOutput(t *testing.T) {
+	t.Parallel()
+
+	dir := lintFixture(t)
+	text := filepath.Join(dir, "lint.txt")
+	f := &Flags{Directory: dir, FailOn: FailOnNone, Output: text}
+	f.Entries, _ = GetFiles(dir)
+	if err := f.Lint(); err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+	got, _ := os.ReadFile(text)
+	want := ".github/workflows/ci.yml:6: warning: actions/checkout@v4 is not pinned to an immutable SHA [ghat-pin]\n1 suppressed\n"
+	if string(got) != want {
+		t.Errorf("Lint() text =\n%s\nwant\n%s", got, want)
+	}
+
+	f.Format, f.Output = FormatSARIF, filepath.Join(dir, "lint.sarif")
+	if err := f.Lint(); err != nil {
+		t.Fatalf("Lint(sarif) error = %v", err)
+	}
+	data, _ := os.ReadFile(f.Output)
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("Lint(sarif) output is not JSON: %v", err)
+	}
+	if n := len(log.Runs[0].Results); n != 2 {
+		t.Errorf("Lint(sarif) results = %d, want 2 (one suppressed)", n)
+	}
+}
+
+func TestWriteLintText_FileLevelFinding(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	writeLintText(&buf, "", []Finding{{Rule: RulePermissions, Level: LevelWarning, File: "ci.yml", Message: "m"}})
+	if !strings.HasPrefix(buf.String(), "ci.yml:1: warning: m [ghat-permissions]") {
+		t.Errorf("writeLintText() = %q", buf.String())
+	}
+}
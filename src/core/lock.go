@@ -0,0 +1,317 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LockFile is the lockfile ghat lock writes at the root of the scanned directory.
+const LockFile = "ghat.lock"
+
+// lockVersion is bumped whenever the lockfile layout changes incompatibly.
+const lockVersion = 1
+
+// LockEntry is one dependency reference as declared in a file, with what it
+// resolved to when the lock was written.
+type LockEntry struct {
+	File       string    `json:"file"` // slash path relative to the scanned directory
+	Ecosystem  string    `json:"ecosystem"`
+	Name       string    `json:"name"`
+	Ref        string    `json:"ref"`           // version, tag, SHA or digest as written
+	SHA        string    `json:"sha,omitempty"` // commit SHA or image digest the ref resolves to
+	Tag        string    `json:"tag,omitempty"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+func (e LockEntry) key() string {
+	return e.File + "|" + e.Ecosystem + "|" + e.Name + "|" + e.Ref
+}
+
+// Lock is the ghat.lock document.
+type Lock struct {
+	Version      int         `json:"version"`
+	Dependencies []LockEntry `json:"dependencies"`
+}
+
+type lockVersionError struct {
+	path    string
+	version int
+}
+
+func (e *lockVersionError) Error() string {
+	return fmt.Sprintf("%s has lock version %d; this ghat understands version %d", e.path, e.version, lockVersion)
+}
+
+type lockMismatchError struct {
+	count int
+}
+
+func (e *lockMismatchError) Error() string {
+	return fmt.Sprintf("%d dependencies do not match %s", e.count, LockFile)
+}
+
+// lockPath is --lockfile, or ghat.lock in the scanned directory.
+func (f *Flags) lockPath() string {
+	if f.LockPath != "" {
+		return f.LockPath
+	}
+	return filepath.Join(f.Directory, LockFile)
+}
+
+// ReadLock loads a lockfile.
+func ReadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path) // #nosec G304 — path is the user's --lockfile
+	if err != nil {
+		return nil, err
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if lock.Version != lockVersion {
+		return nil, &lockVersionError{path: path, version: lock.Version}
+	}
+	return &lock, nil
+}
+
+// Lock writes ghat.lock: every dependency reference in every manifest
+// ParseManifest understands, with the SHA or digest it resolves to. Refs
+// already pinned are read from the file; tags are resolved upstream. Entries
+// unchanged since the previous lock keep their resolution time.
+func (f *Flags) Lock() error {
+	previous := map[string]LockEntry{}
+	if old, err := ReadLock(f.lockPath()); err == nil {
+		for _, e := range old.Dependencies {
+			previous[e.key()] = e
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	entries := f.lockEntries()
+	for i := range entries {
+		e := &entries[i]
+		if e.SHA == "" {
+			if sha, ok := f.resolveLockEntry(*e); ok {
+				e.SHA = sha
+			}
+		}
+		e.ResolvedAt = now
+		if old, ok := previous[e.key()]; ok && old.SHA == e.SHA && old.Tag == e.Tag {
+			e.ResolvedAt = old.ResolvedAt
+		}
+	}
+
+	data, err := json.MarshalIndent(Lock{Version: lockVersion, Dependencies: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if f.DryRun {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(f.lockPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.lockPath(), err)
+	}
+	log.Info().Int("dependencies", len(entries)).Str("file", f.lockPath()).Msg("lock written")
+	return nil
+}
+
+// VerifyLock checks, without network calls, that every dependency reference
+// in the scanned files matches ghat.lock, writing one line per difference to w.
+func (f *Flags) VerifyLock(w io.Writer) error {
+	lock, err := ReadLock(f.lockPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s not found, run ghat lock first: %w", f.lockPath(), err)
+	}
+	if err != nil {
+		return err
+	}
+
+	locked := map[string]LockEntry{}
+	for _, e := range lock.Dependencies {
+		locked[e.key()] = e
+	}
+
+	var mismatches int
+	mismatch := func(e LockEntry, format string, args ...interface{}) {
+		mismatches++
+		_, _ = fmt.Fprintf(w, "%s: %s %s: %s\n", e.File, e.Ecosystem, e.Name, fmt.Sprintf(format, args...))
+	}
+
+	current := f.lockEntries()
+	for _, e := range current {
+		want, ok := locked[e.key()]
+		if !ok {
+			if old := lockedRef(lock.Dependencies, e); old != "" {
+				mismatch(e, "ref %s, %s has %s", e.Ref, LockFile, old)
+			} else {
+				mismatch(e, "ref %s is not in %s", e.Ref, LockFile)
+			}
+			continue
+		}
+		delete(locked, e.key())
+		if e.SHA != "" && want.SHA != "" && e.SHA != want.SHA {
+			mismatch(e, "resolves to %s, %s has %s", e.SHA, LockFile, want.SHA)
+		}
+		if e.Tag != "" && want.Tag != "" && e.Tag != want.Tag {
+			mismatch(e, "tag %s, %s has %s", e.Tag, LockFile, want.Tag)
+		}
+	}
+
+	var stale []LockEntry
+	for _, e := range locked {
+		if lockedRef(current, e) == "" {
+			stale = append(stale, e)
+		}
+	}
+	sortLockEntries(stale)
+	for _, e := range stale {
+		mismatch(e, "ref %s is in %s but no longer declared", e.Ref, LockFile)
+	}
+
+	if mismatches > 0 {
+		return &lockMismatchError{count: mismatches}
+	}
+	_, _ = fmt.Fprintf(w, "%d dependencies match %s\n", len(lock.Dependencies), LockFile)
+	return nil
+}
+
+// lockedRef returns the ref entries hold for e's file, ecosystem and name.
+func lockedRef(entries []LockEntry, e LockEntry) string {
+	for _, l := range entries {
+		if l.File == e.File && l.Ecosystem == e.Ecosystem && l.Name == e.Name {
+			return l.Ref
+		}
+	}
+	return ""
+}
+
+// lockEntries parses every scanned manifest into lock entries, taking the
+// SHA from refs that are already pinned and the tag from a trailing
+// "# tag" comment or the image reference. No network calls are made.
+func (f *Flags) lockEntries() []LockEntry {
+	var entries []LockEntry
+	for _, file := range f.Entries {
+		kind, ok := ClassifyManifest(file)
+		content, err := os.ReadFile(file) // #nosec G304
+		if err != nil {
+			continue
+		}
+		if !ok && isYAMLFile(file) && HasKubeResource(content) {
+			kind, ok = ManifestKube, true
+		}
+		if !ok {
+			continue
+		}
+
+		lines := strings.Split(string(content), "\n")
+		rel := relSlashPath(f.Directory, file)
+		for _, ref := range ParseManifest(kind, content) {
+			line := ref.Line
+			if ref.VersionLine > 0 {
+				line = ref.VersionLine
+			}
+			e := LockEntry{File: rel, Ecosystem: ref.Ecosystem, Name: ref.Name, Ref: ref.Version}
+			switch {
+			case strings.HasPrefix(ref.Version, "sha256:"), IsSHAPinnedRef(ref.Version):
+				e.SHA = ref.Version
+				e.Tag = refTagComment(lineAt(lines, line))
+			case isImageEcosystem(ref.Ecosystem):
+				e.Tag = imageTag(ref.Version)
+			default:
+				e.Tag = ref.Version
+			}
+			if isImageEcosystem(ref.Ecosystem) && e.Tag == "" {
+				_, e.Tag = splitImageTag(ref.Name)
+			}
+			entries = append(entries, e)
+		}
+	}
+	sortLockEntries(entries)
+	return entries
+}
+
+func sortLockEntries(entries []LockEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key() < entries[j].key() })
+}
+
+func isImageEcosystem(ecosystem string) bool {
+	switch ecosystem {
+	case SourceDockerfile, SourceGitLab, SourceKube, SourceCompose, SourceImage:
+		return true
+	}
+	return false
+}
+
+// splitImageTag splits "registry/repo:tag" into its name and tag. A colon
+// before the last slash is a registry port, not a tag.
+func splitImageTag(image string) (name, tag string) {
+	colon := strings.LastIndex(image, ":")
+	if colon < 0 || colon < strings.LastIndex(image, "/") {
+		return image, ""
+	}
+	return image[:colon], image[colon+1:]
+}
+
+// refTagComment returns the tag in a trailing "# tag" comment, ignoring
+// ghat:suppress annotations.
+func refTagComment(line string) string {
+	_, comment, ok := strings.Cut(line, "#")
+	if !ok {
+		return ""
+	}
+	fields := strings.Fields(comment)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "ghat:") {
+		return ""
+	}
+	return fields[0]
+}
+
+// resolveLockEntry resolves an unpinned tag to a SHA or digest for the
+// ecosystems that have one.
+func (f *Flags) resolveLockEntry(e LockEntry) (string, bool) {
+	if e.Ref == "" && !isImageEcosystem(e.Ecosystem) {
+		return "", false
+	}
+
+	var target LedgerEntry
+	switch {
+	case e.Ecosystem == SourceGHA, e.Ecosystem == SourcePreCommit:
+		target = LedgerEntry{Kind: e.Ecosystem, Name: e.Name, Tag: e.Ref}
+	case e.Ecosystem == SourceGitLabComponent:
+		base := f.GitLabURL
+		if base == "" {
+			base = defaultGitLabURL
+		}
+		project := e.Name
+		if host, path, err := gitlabComponentPath(e.Name); err == nil && strings.Contains(e.Name, ".") {
+			base, project = "https://"+host, path
+		}
+		target = LedgerEntry{Kind: SourceGitLabComponent, Name: project, URL: base, Tag: e.Ref}
+	case isImageEcosystem(e.Ecosystem):
+		name, _ := splitImageTag(e.Name)
+		target = LedgerEntry{Kind: SourceImage, Name: name, Tag: e.Tag}
+	default:
+		return "", false
+	}
+
+	sha, err := f.resolveLedgerEntry(target)
+	if err != nil {
+		log.Warn().Err(err).Str("dependency", e.Name).Str("ref", e.Ref).Msg("failed to resolve for ghat.lock")
+		return "", false
+	}
+	return sha, true
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func lockFixture(t *testing.T) (dir, workflow string) {
+	t.Helper()
+	dir = t.TempDir()
+	workflow = filepath.Join(dir, ".github", "workflows", "ci.yml")
+	if err := os.MkdirAll(filepath.Dir(workflow), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		workflow: "permissions: read-all\njobs:\n  b:\n    steps:\n" +
+			"      - uses: actions/checkout@" + includeSHA + " # v4.2.2\n",
+		filepath.Join(dir, "Dockerfile"):              "FROM golang:1.22@sha256:abc AS build\nFROM nginx@sha256:def # 1.27\n",
+		filepath.Join(dir, ".pre-commit-config.yaml"): "repos:\n  - repo: https://github.com/pre-commit/pre-commit-hooks\n    rev: " + includeSHA + " # v4.6.0\n",
+		filepath.Join(dir, "main.tf"):                 "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"5.1.0\"\n}\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, workflow
+}
+
+func TestFlags_LockEntries(t *testing.T) {
+	t.Parallel()
+
+	dir, _ := lockFixture(t)
+	f := &Flags{Directory: dir}
+	f.Entries, _ = GetFiles(dir)
+
+	got := f.lockEntries()
+	want := []LockEntry{
+		{File: ".github/workflows/ci.yml", Ecosystem: SourceGHA, Name: "actions/checkout", Ref: includeSHA, SHA: includeSHA, Tag: "v4.2.2"},
+		{File: ".pre-commit-config.yaml", Ecosystem: SourcePreCommit, Name: "https://github.com/pre-commit/pre-commit-hooks", Ref: includeSHA, SHA: includeSHA, Tag: "v4.6.0"},
+		{File: "Dockerfile", Ecosystem: SourceDockerfile, Name: "golang:1.22", Ref: "sha256:abc", SHA: "sha256:abc", Tag: "1.22"},
+		{File: "Dockerfile", Ecosystem: SourceDockerfile, Name: "nginx", Ref: "sha256:def", SHA: "sha256:def", Tag: "1.27"},
+		{File: "main.tf", Ecosystem: SourceTerraform, Name: "terraform-aws-modules/vpc/aws", Ref: "5.1.0", Tag: "5.1.0"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("lockEntries() = %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("lockEntries()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFlags_VerifyLock(t *testing.T) {
+	t.Parallel()
+
+	dir, workflow := lockFixture(t)
+	f := &Flags{Directory: dir}
+	f.Entries, _ = GetFiles(dir)
+
+	var out bytes.Buffer
+	if err := f.VerifyLock(&out); err == nil {
+		t.Error("VerifyLock() without a lockfile should error")
+	}
+
+	// Every ref in the fixture is pinned, so Lock makes no network calls.
+	if err := f.Lock(); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	before, _ := os.ReadFile(filepath.Join(dir, LockFile))
+	if err := f.VerifyLock(&out); err != nil {
+		t.Fatalf("VerifyLock() error = %v\n%s", err, out.String())
+	}
+
+	if err := f.Lock(); err != nil {
+		t.Fatalf("Lock() second run error = %v", err)
+	}
+	if after, _ := os.ReadFile(filepath.Join(dir, LockFile)); !bytes.Equal(before, after) {
+		t.Errorf("Lock() rewrote unchanged entries:\n%s\nwant\n%s", after, before)
+	}
+
+	content, _ := os.ReadFile(workflow)
+	content = bytes.Replace(content, []byte("# v4.2.2"), []byte("# v4.2.3"), 1)
+	if err := os.WriteFile(workflow, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "main.tf")); err != nil {
+		t.Fatal(err)
+	}
+	f.Entries, _ = GetFiles(dir)
+
+	out.Reset()
+	err := f.VerifyLock(&out)
+	if got := fmt.Sprintf("%T", err); got != "*core.lockMismatchError" {
+		t.Fatalf("VerifyLock() error = %v (%s), want *core.lockMismatchError", err, got)
+	}
+	for _, want := range []string{
+		".github/workflows/ci.yml: gha actions/checkout: tag v4.2.3, ghat.lock has v4.2.2\n",
+		"main.tf: terraform terraform-aws-modules/vpc/aws: ref 5.1.0 is in ghat.lock but no longer declared\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("VerifyLock() output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestReadLock_Version(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), LockFile)
+	if err := os.WriteFile(path, []byte(`{"version":99,"dependencies":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ReadLock(path)
+	if got := fmt.Sprintf("%T", err); got != "*core.lockVersionError" {
+		t.Errorf("ReadLock() error = %v (%s), want *core.lockVersionError", err, got)
+	}
+}
+
+func TestSplitImageTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		image, name, tag string
+	}{
+		{"nginx:1.27", "nginx", "1.27"},
+		{"nginx", "nginx", ""},
+		{"registry.example.com:5000/team/app", "registry.example.com:5000/team/app", ""},
+		{"registry.example.com:5000/team/app:v2", "registry.example.com:5000/team/app", "v2"},
+	}
+	for _, tt := range tests {
+		name, tag := splitImageTag(tt.image)
+		if name != tt.name || tag != tt.tag {
+			t.Errorf("splitImageTag(%q) = (%q, %q), want (%q, %q)", tt.image, name, tag, tt.name, tt.tag)
+		}
+	}
+}
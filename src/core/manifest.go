@@ -2,6 +2,7 @@ package core
 
 import (
 	"encoding/json"
+	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
@@ -39,6 +40,44 @@ type DepRef struct {
 	VersionLine int    // 1-indexed line of the version attribute when separate (0 = same as Line)
 }
 
+// ClassifyManifest maps a file path to a ManifestKind by name, returning
+// false for files ghat does not handle. Kubernetes manifests are only
+// recognisable by content; see HasKubeResource.
+func ClassifyManifest(path string) (ManifestKind, bool) {
+	base := filepath.Base(path)
+	slashed := filepath.ToSlash(path)
+
+	switch {
+	case (strings.HasSuffix(base, ".yml") || strings.HasSuffix(base, ".yaml")) &&
+		strings.Contains(slashed, ".github/workflows/"):
+		return ManifestGHA, true
+	case base == "go.mod":
+		return ManifestGoMod, true
+	case base == "package.json":
+		return ManifestNPM, true
+	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+		return ManifestPyPI, true
+	case base == "Cargo.toml":
+		return ManifestCargo, true
+	case base == "Gemfile":
+		return ManifestGem, true
+	case base == ".pre-commit-config.yaml" || base == ".pre-commit-config.yml":
+		return ManifestPreCommit, true
+	case base == "cpanfile":
+		return ManifestCpanfile, true
+	case base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile."):
+		return ManifestDockerfile, true
+	case base == ".gitlab-ci.yml" || strings.HasSuffix(base, ".gitlab-ci.yml"):
+		return ManifestGitLab, true
+	case base == "docker-compose.yml" || base == "docker-compose.yaml" ||
+		base == "compose.yml" || base == "compose.yaml":
+		return ManifestCompose, true
+	case strings.HasSuffix(base, ".tf"):
+		return ManifestTerraform, true
+	}
+	return 0, false
+}
+
 // ParseManifest parses a manifest file's raw bytes and returns the dependency
 // references it contains. No network calls are made.
 func ParseManifest(kind ManifestKind, content []byte) []DepRef {
@@ -87,9 +126,23 @@ func parseGHAManifest(content []byte) []DepRef {
 			continue
 		}
 		ref := strings.TrimSpace(m[1])
-		if strings.HasPrefix(ref, "./") ||
-			strings.HasPrefix(ref, "docker://") ||
-			strings.Contains(ref, "/.github/workflows/") {
+		if image, ok := strings.CutPrefix(ref, "docker://"); ok {
+			// docker:// steps run an image, so report them as one.
+			if image == "" || strings.HasPrefix(image, "$") || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			nameTag, version, pinned := strings.Cut(image, "@")
+			name, tag := splitImageTag(nameTag)
+			if !pinned {
+				version = tag
+			}
+			refs = append(refs, DepRef{Ecosystem: SourceImage, Name: name, Version: version, Line: i + 1})
+			continue
+		}
+		// Reusable workflow calls (owner/repo/.github/workflows/x.yml@v1) are
+		// pinned like actions, so they are reported under their repository.
+		if strings.HasPrefix(ref, "./") {
 			continue
 		}
 		path, ver, _ := strings.Cut(ref, "@")
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -15,8 +16,8 @@ jobs:
       - uses: docker://alpine:3.19
 `)
 	refs := ParseManifest(ManifestGHA, content)
-	if len(refs) != 2 {
-		t.Fatalf("got %d refs, want 2: %+v", len(refs), refs)
+	if len(refs) != 3 {
+		t.Fatalf("got %d refs, want 3: %+v", len(refs), refs)
 	}
 	if refs[0].Name != "actions/checkout" || refs[0].Ecosystem != SourceGHA {
 		t.Errorf("ref[0] = %+v", refs[0])
@@ -24,11 +25,40 @@ jobs:
 	if refs[1].Name != "actions/setup-go" || refs[1].Version != "v5" {
 		t.Errorf("ref[1] = %+v", refs[1])
 	}
+	if refs[2] != (DepRef{Ecosystem: SourceImage, Name: "alpine", Version: "3.19", Line: 8}) {
+		t.Errorf("ref[2] = %+v, want the docker:// step as an image", refs[2])
+	}
 	if refs[0].Line == 0 || refs[1].Line == 0 {
 		t.Error("line numbers must be non-zero")
 	}
 }
 
+func TestParseManifestGHA_ReusableWorkflow(t *testing.T) {
+	content := []byte(`on: push
+jobs:
+  call:
+    uses: org/repo/.github/workflows/y.yml@v1
+  local:
+    uses: ./.github/workflows/local.yml
+`)
+	refs := ParseManifest(ManifestGHA, content)
+	want := DepRef{Ecosystem: SourceGHA, Name: "org/repo", Version: "v1", Line: 4}
+	if len(refs) != 1 || refs[0] != want {
+		t.Errorf("got %+v, want [%+v]", refs, want)
+	}
+
+	findings := WorkflowFindings("ci.yml", content)
+	var pin bool
+	for _, f := range findings {
+		if f.Rule == RulePin && f.Line == 4 && strings.Contains(f.Message, "org/repo/.github/workflows/y.yml@v1") {
+			pin = true
+		}
+	}
+	if !pin {
+		t.Errorf("WorkflowFindings() = %+v, want the reusable workflow call reported as unpinned", findings)
+	}
+}
+
 func TestParseTerraformManifestSkipsLocalModules(t *testing.T) {
 	content := []byte(`module "cloudarmour" {
   source = "../../"
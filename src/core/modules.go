@@ -7,8 +7,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/rs/zerolog/log"
@@ -35,21 +33,49 @@ func (f *Flags) UpdateModule(file string) error {
 
 	for _, block := range root.Blocks() {
 		if block.Type() == "module" {
-			version = GetVersion(block)
-
 			source := GetStringValue(block, "source")
+			name, oldRef, _ := strings.Cut(source, "?ref=")
+			if f.frozen(SourceTerraform, name) {
+				continue
+			}
 
 			myType, err := f.GetType(source)
+			if constraint := GetStringValue(block, "version"); myType == "registry" {
+				// Modules on registries other than registry.terraform.io can't be
+				// mapped to a GitHub repository, so they keep a version.
+				if host, _, _, _ := registrySource(source); host != defaultProviderHost || (f.KeepConstraints && constraint != "") {
+					if err := f.updateRegistryModule(file, string(src), block, source, constraint); err != nil {
+						log.Warn().Err(err).Str("source", source).Msg("failed to update module version, leaving unchanged")
+					}
+					continue
+				}
+			}
+
+			version = GetVersion(block)
 
 			if err != nil {
 				log.Info().Msgf("source type failure %s", source)
 			} else {
-				newValue, version, err = f.UpdateSource(source, myType, version)
+				oldVersion := GetStringValue(block, "version")
+				newValue, version, err = f.UpdateSource(source, myType, version, f.policy(SourceTerraform, name))
 				if err != nil {
 					log.Warn().Err(err).Str("source", source).Msg("failed to update module source, leaving unchanged")
 				} else {
 					block.Body().RemoveAttribute("version")
 					block.Body().SetAttributeValue("source", cty.StringVal(newValue))
+					if newValue != source {
+						if oldRef == "" {
+							oldRef = oldVersion
+						}
+						newRef := newValue
+						if _, ref, ok := strings.Cut(newValue, "?ref="); ok {
+							newRef = ref
+						}
+						f.recordReplace(string(src), "\""+source+"\"", Change{
+							File: file, Ecosystem: SourceTerraform, Name: name,
+							OldRef: oldRef, NewRef: newRef, Tag: version,
+						})
+					}
 				}
 			}
 		}
@@ -95,27 +121,61 @@ func (f *Flags) UpdateModule(file string) error {
 	return nil
 }
 
+// GetVersion returns a module's version attribute as a v-prefixed version
+// when it names exactly one, or "" when it is missing or a range.
 func GetVersion(block *hclwrite.Block) string {
 	version := GetStringValue(block, "version")
 	if version == "" {
 		return ""
 	}
 
-	constraints := []string{"=", "!", ">", ">", "~"}
+	cs, err := parseConstraints(version)
+	if err != nil || len(cs) != 1 || (cs[0].op != "" && cs[0].op != "=") {
+		log.Info().Msg("constraints not valid, using latest")
+		return ""
+	}
+
+	return "v" + cs[0].version
+}
 
-	for _, constraint := range constraints {
-		if strings.Contains(version, constraint) {
-			version = ""
-			log.Info().Msg("constraints not valid, using latest")
-			continue
+// updateRegistryModule moves a registry module's version up to the newest
+// release its policy and cooldown allow, leaving the module a registry one.
+// With --keep-constraints the constraint keeps its style and its upper
+// bounds; otherwise it becomes that exact version.
+func (f *Flags) updateRegistryModule(file, src string, block *hclwrite.Block, source, constraint string) error {
+	host, module, _, err := registrySource(source)
+	if err != nil {
+		return err
+	}
+	keep := f.KeepConstraints && constraint != ""
+	var bound string
+	if keep {
+		bound = upperBounds(constraint)
+	}
+	latest, err := registryModuleVersion(host, module, f.cooldown(SourceTerraform), f.policy(SourceTerraform, source),
+		constraint, bound)
+	if err != nil {
+		return err
+	}
+	bumped := latest
+	if keep {
+		if bumped, err = bumpConstraints(constraint, latest); err != nil {
+			return err
 		}
 	}
-
-	if !strings.Contains(version, "v") && version != "" {
-		version = "v" + version
+	if sameConstraints(bumped, constraint) {
+		return nil
 	}
 
-	return version
+	block.Body().SetAttributeValue("version", cty.StringVal(bumped))
+	old := "\"" + constraint + "\""
+	if constraint == "" {
+		old = "\"" + source + "\""
+	}
+	f.recordReplace(src, old, Change{
+		File: file, Ecosystem: SourceTerraform, Name: source, OldRef: constraint, NewRef: bumped, Tag: latest,
+	})
+	return nil
 }
 
 func GetStringValue(block *hclwrite.Block, attribute string) string {
@@ -137,14 +197,11 @@ func (f *Flags) UpdateModules() error {
 	}
 
 	// contains a module?
-	for _, file := range terraform {
-		err = f.UpdateModule(file)
-		if err != nil {
-			return err
-		}
+	if err := eachFile(terraform, f.UpdateModule); err != nil {
+		return err
 	}
 
-	return nil
+	return eachFile(f.GetTerragrunt(), f.UpdateTerragrunt)
 }
 
 func (f *Flags) GetTF() ([]string, error) {
@@ -183,13 +240,29 @@ func (f *Flags) GetType(module string) (string, error) {
 		return "gcs", nil
 	}
 
+	// Terragrunt's registry scheme, e.g. tfr:///terraform-aws-modules/vpc/aws?version=5.1.0
+	if strings.HasPrefix(module, "tfr://") {
+		return "tfr", nil
+	}
+
 	if strings.Contains(module, ".zip") || strings.Contains(module, "archive=") {
 		return "archive", nil
 	}
 
+	// scp-style ssh, e.g. git@gitlab.com:group/subgroup/vpc.git, which can
+	// have as many parts as a registry source
+	if strings.HasPrefix(module, "git@") && !strings.Contains(module, "depth=") {
+		return "git", nil
+	}
+
 	// gitHub registry format and sub dirs
 	splitter := strings.Split(module, "/")
 
+	// a registry other than registry.terraform.io, e.g. app.terraform.io/org/vpc/aws
+	if len(splitter) == 4 && isRegistryHost(splitter[0]) && !strings.Contains(module, "github.com") {
+		return "registry", nil
+	}
+
 	if len(splitter) == 3 && !strings.Contains(module, "git::") && !strings.Contains(module, "https:") {
 		if strings.Contains(module, "github.com") {
 			return "github", nil
@@ -222,7 +295,9 @@ func (f *Flags) GetType(module string) (string, error) {
 	return moduleType, err
 }
 
-func (f *Flags) UpdateSource(module string, moduleType string, version string) (string, string, error) {
+// UpdateSource resolves a module source to a commit-pinned one, returning it
+// and the tag it pins. p is the .ghat.yml policy for the module, if any.
+func (f *Flags) UpdateSource(module string, moduleType string, version string, p *Policy) (string, string, error) {
 
 	var newModule string
 
@@ -235,6 +310,10 @@ func (f *Flags) UpdateSource(module string, moduleType string, version string) (
 		{
 			newModule := strings.TrimPrefix(module, "git::")
 
+			if !strings.Contains(newModule, "github.com/") {
+				return f.UpdateGitSource(module, p)
+			}
+
 			splitter := strings.Split(newModule, "?ref=")
 
 			root := splitter[0]
@@ -244,60 +323,33 @@ func (f *Flags) UpdateSource(module string, moduleType string, version string) (
 				// If ?ref= is a bare 40-char commit SHA (already pinned by ghat),
 				// clear version so GetGithubLatestHash is called and we get a real
 				// tag name for the comment rather than writing #SHA.
-				if len(existingRef) == 40 && strings.Trim(existingRef, "0123456789abcdefABCDEF") == "" {
+				if isCommitSHA(existingRef) {
 					version = ""
 				} else {
 					version = existingRef
 				}
 			}
 
-			if f.Update {
-				if !strings.Contains(newModule, "github.com") {
-					repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
-						URL: strings.TrimRight(module, ".git"),
-					})
-
-					if err != nil {
-						return "", "", fmt.Errorf("failed to clone %s", newModule)
-					}
-
-					ref, err := repo.Head()
-					if err != nil {
-						return "", "", err
-					}
-					log.Print(ref)
-				} else {
-					hash, version, err := f.GetGithubLatestHash(newModule)
-					if err != nil {
-						return "", "", err
-					}
+			if f.Update || version == "" {
+				hash, version, err = f.GetGithubLatestHash(newModule, version, p)
+			} else {
+				hash, err = f.GetGithubHash(strings.TrimPrefix(newModule, "https://"), version)
+			}
+			if err != nil {
+				return "", "", err
+			}
 
-					return "git::" + root + "?ref=" + hash, version, nil
-				}
+			return "git::" + root + "?ref=" + hash, version, nil
+		}
 
-				// get latest hash for root
-				log.Print(root)
-			} else {
-				if strings.Contains(newModule, "github.com") {
-					if version != "" {
-						hash, err = f.GetGithubHash(
-							strings.TrimPrefix(newModule, "https://"),
-							version,
-						)
-						if err != nil {
-							return "", "", err
-						}
-					} else {
-						hash, version, err = f.GetGithubLatestHash(newModule)
-						if err != nil {
-							return "", "", err
-						}
-					}
-					return "git::" + root + "?ref=" + hash, version, nil
-				}
+	case "bitbucket":
+		{
+			return f.UpdateGitSource(module, p)
+		}
 
-				log.Info().Msgf("git != github")
-			}
+	case "tfr":
+		{
+			return f.UpdateTfrSource(module, p)
 		}
 
 	case "registry":
@@ -321,10 +373,10 @@ func (f *Flags) UpdateSource(module string, moduleType string, version string) (
 			newModule := "github.com" + "/" + splits[0] + "/" + "terraform" + "-" + splits[2] + "-" + splits[1] + ".git"
 
 			if subDir == "" {
-				return f.UpdateGithubSource(version, newModule)
+				return f.UpdateGithubSource(version, newModule, p)
 			}
 
-			return f.WithSubDir(version, newModule, subDir)
+			return f.WithSubDir(version, newModule, subDir, p)
 
 		}
 
@@ -338,11 +390,11 @@ func (f *Flags) UpdateSource(module string, moduleType string, version string) (
 				// e.g. jameswoolfenden/terraform-http-ip
 				newModule := root + ".git"
 
-				return f.WithSubDir(version, newModule, subDir)
+				return f.WithSubDir(version, newModule, subDir, p)
 			}
 
 			newModule = module + ".git"
-			return f.UpdateGithubSource(version, newModule)
+			return f.UpdateGithubSource(version, newModule, p)
 		}
 
 	case "local", "shallow", "archive", "s3", "gcs", "mercurial":
@@ -360,8 +412,152 @@ func (f *Flags) UpdateSource(module string, moduleType string, version string) (
 	return newModule, version, nil
 }
 
-func (f *Flags) WithSubDir(version string, newModule string, subdir string) (string, string, error) {
-	url, version, err := f.UpdateGithubSource(version, newModule)
+// gitSource is a git module source split into the parts UpdateGitSource
+// rewrites.
+type gitSource struct {
+	prefix string   // "git::", or "" for git@host:path
+	repo   string   // the URL git ls-remote is given
+	subdir string   // the part after //, if any
+	ref    string   // the ?ref= value, if any
+	query  []string // the other query parameters, in order
+}
+
+// parseGitSource splits a git module source such as
+// git::https://gitlab.com/org/vpc.git//modules/nat?ref=v1.2.0. A bitbucket.org
+// shorthand source becomes the git::https:// form Terraform would clone.
+func parseGitSource(module string) gitSource {
+	var s gitSource
+	rest := module
+	if after, ok := strings.CutPrefix(rest, "git::"); ok {
+		s.prefix, rest = "git::", after
+	}
+	bitbucket := strings.HasPrefix(rest, "bitbucket.org/")
+	if bitbucket {
+		s.prefix, rest = "git::", "https://"+rest
+	}
+
+	base, query, _ := strings.Cut(rest, "?")
+	start := 0
+	if i := strings.Index(base, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	s.repo = base
+	if i := strings.Index(base[start:], "//"); i >= 0 {
+		s.repo, s.subdir = base[:start+i], base[start+i+2:]
+	}
+	if bitbucket && !strings.HasSuffix(s.repo, ".git") {
+		s.repo += ".git"
+	}
+
+	for _, param := range strings.Split(query, "&") {
+		if ref, ok := strings.CutPrefix(param, "ref="); ok {
+			s.ref = ref
+		} else if param != "" {
+			s.query = append(s.query, param)
+		}
+	}
+	return s
+}
+
+// withRef renders the source pinned to ref.
+func (s gitSource) withRef(ref string) string {
+	source := s.prefix + s.repo
+	if s.subdir != "" {
+		source += "//" + s.subdir
+	}
+	return source + "?" + strings.Join(append([]string{"ref=" + ref}, s.query...), "&")
+}
+
+// isCommitSHA reports whether ref is a full 40-character commit SHA.
+func isCommitSHA(ref string) bool {
+	return len(ref) == 40 && strings.Trim(ref, "0123456789abcdefABCDEF") == ""
+}
+
+// UpdateGitSource pins a module from a git host other than GitHub, such as
+// GitLab, Bitbucket or a self-hosted server, to the commit of a tag, found
+// with git ls-remote so the user's git credentials apply. Without --update it
+// pins the tag ?ref= names; with it, or when the tag is gone, it moves to the
+// newest tag p allows. ls-remote has no release dates, so a cooldown leaves
+// the module alone rather than moving it.
+func (f *Flags) UpdateGitSource(module string, p *Policy) (string, string, error) {
+	src := parseGitSource(module)
+
+	out, err := gitLsRemoteTags(src.repo)
+	if err != nil {
+		return "", "", err
+	}
+	order, shas := lsRemoteTags(out)
+
+	current := src.ref
+	if isCommitSHA(src.ref) {
+		current = ""
+		for _, tag := range order {
+			if strings.EqualFold(shas[tag], src.ref) {
+				current = tag
+				break
+			}
+		}
+		if !f.Update {
+			if current == "" {
+				log.Info().Msgf("module source %s is pinned to a commit no tag points at, leaving unchanged", module)
+			}
+			return module, current, nil
+		}
+	}
+
+	if !f.Update && current != "" {
+		if sha, ok := shas[current]; ok {
+			return src.withRef(sha), current, nil
+		}
+		log.Warn().Str("module", src.repo).Str("version", current).Msg("version tag not found, upgrading to latest")
+	}
+
+	if days := f.cooldown(SourceTerraform); days > 0 {
+		return "", "", &cooldownError{name: src.repo, days: days}
+	}
+
+	sha, tag, err := pickLsRemoteTag(src.repo, out, p, current)
+	if err != nil {
+		return "", "", err
+	}
+	return src.withRef(sha), tag, nil
+}
+
+// UpdateTfrSource updates a Terragrunt tfr:// source. A module on
+// registry.terraform.io is pinned to a commit of its GitHub repository, as
+// registry modules in .tf files are; one on another registry keeps its
+// ?version=, moved to the newest release.
+func (f *Flags) UpdateTfrSource(module string, p *Policy) (string, string, error) {
+	base, query, _ := strings.Cut(strings.TrimPrefix(module, "tfr://"), "?")
+	host, path, _ := strings.Cut(base, "/")
+
+	var version string
+	var params []string
+	for _, param := range strings.Split(query, "&") {
+		if v, ok := strings.CutPrefix(param, "version="); ok {
+			version = v
+		} else if param != "" {
+			params = append(params, param)
+		}
+	}
+
+	if host == "" || strings.EqualFold(host, defaultProviderHost) {
+		if version != "" {
+			version = "v" + strings.TrimPrefix(version, "v")
+		}
+		return f.UpdateSource(path, "registry", version, p)
+	}
+
+	name, _, _ := strings.Cut(path, "//")
+	latest, err := registryModuleVersion(strings.ToLower(host), name, f.cooldown(SourceTerraform), p, version, "")
+	if err != nil {
+		return "", "", err
+	}
+	return "tfr://" + host + "/" + path + "?" + strings.Join(append([]string{"version=" + latest}, params...), "&"), latest, nil
+}
+
+func (f *Flags) WithSubDir(version string, newModule string, subdir string, p *Policy) (string, string, error) {
+	url, version, err := f.UpdateGithubSource(version, newModule, p)
 
 	urlsplit := strings.Split(url, ".git")
 	newUrl := urlsplit[0] + ".git" + "//" + subdir + urlsplit[1]
@@ -369,13 +565,13 @@ func (f *Flags) WithSubDir(version string, newModule string, subdir string) (str
 	return newUrl, version, err
 }
 
-func (f *Flags) UpdateGithubSource(version string, newModule string) (string, string, error) {
+func (f *Flags) UpdateGithubSource(version string, newModule string, p *Policy) (string, string, error) {
 	var hash string
 
 	var err error
 
 	if f.Update {
-		hash, version, err = f.GetGithubLatestHash(newModule)
+		hash, version, err = f.GetGithubLatestHash(newModule, version, p)
 		if err != nil {
 			return "", "", err
 		}
@@ -385,13 +581,13 @@ func (f *Flags) UpdateGithubSource(version string, newModule string) (string, st
 			if err != nil {
 				// Tag not found — upgrade to latest available version.
 				log.Warn().Str("module", newModule).Str("version", version).Msg("version tag not found, upgrading to latest")
-				hash, version, err = f.GetGithubLatestHash(newModule)
+				hash, version, err = f.GetGithubLatestHash(newModule, version, p)
 				if err != nil {
 					return "", "", err
 				}
 			}
 		} else {
-			hash, version, err = f.GetGithubLatestHash(newModule)
+			hash, version, err = f.GetGithubLatestHash(newModule, "", p)
 			if err != nil {
 				return "", "", err
 			}
@@ -401,7 +597,9 @@ func (f *Flags) UpdateGithubSource(version string, newModule string) (string, st
 	return "git::https://" + newModule + "?ref=" + hash, version, nil
 }
 
-func (f *Flags) GetGithubLatestHash(newModule string) (string, string, error) {
+// GetGithubLatestHash returns the commit and name of the newest tag of a
+// GitHub-hosted module that p lets current move to.
+func (f *Flags) GetGithubLatestHash(newModule string, current string, p *Policy) (string, string, error) {
 	name := strings.Split(newModule, "github.com/")
 
 	if len(name) < 2 {
@@ -413,29 +611,7 @@ func (f *Flags) GetGithubLatestHash(newModule string) (string, string, error) {
 		return "", "", fmt.Errorf("modules string doesnt end in .git")
 	}
 
-	payload, err := GetLatestTag(action[0], f.GitHubToken)
-
-	if err != nil {
-		return "", "", err
-	}
-
-	assertedPayload, ok := payload.(map[string]interface{})
-
-	if !ok {
-		return "", "", fmt.Errorf("type assertion failed")
-	}
-
-	version, ok := assertedPayload["name"].(string)
-
-	if !ok {
-		return "", "", fmt.Errorf("type assertion failed")
-	}
-
-	commit := assertedPayload["commit"].(map[string]interface{})
-
-	hash := commit["sha"].(string)
-
-	return hash, version, nil
+	return f.latestGithubTag(action[0], SourceTerraform, p, current)
 }
 
 func (f *Flags) GetGithubHash(newModule string, tag string) (string, error) {
@@ -452,7 +628,7 @@ func (f *Flags) GetGithubHash(newModule string, tag string) (string, error) {
 	valid := semver.IsValid(tag)
 
 	if valid {
-		url = "https://api.github.com/repos/" + action[0] + "/git/ref/tags/" + tag
+		url = githubAPI("/repos/" + action[0] + "/git/ref/tags/" + tag)
 
 		// Use cached version if cache is available
 		var err error
@@ -466,7 +642,7 @@ func (f *Flags) GetGithubHash(newModule string, tag string) (string, error) {
 			// retry as version is truncated
 			if strings.Count(tag, ".") == 1 {
 				tag = tag + ".0"
-				url = "https://api.github.com/repos/" + action[0] + "/git/ref/tags/" + tag
+				url = githubAPI("/repos/" + action[0] + "/git/ref/tags/" + tag)
 
 				if f.Cache != nil {
 					payload, err = GetGithubBodyWithCache(f.GitHubToken, url, f.Cache)
@@ -1,7 +1,11 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -57,6 +61,7 @@ func TestFlags_GetType(t *testing.T) {
 
 		{"Modules in Package Sub-directories", fields{}, args{"hashicorp/consul/aws//modules/consul-cluster"}, "registry", false},
 		{"Modules 2", fields{}, args{"git::https://example.com/network.git//modules/vpc"}, "git", false},
+		{"scp-style ssh", fields{}, args{"git@gitlab.com:group/subgroup/vpc.git?ref=v1.0.0"}, "git", false},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -169,7 +174,7 @@ func TestFlags_UpdateSource(t *testing.T) {
 		{"Bitbucket", fields{}, args{"bitbucket.org/hashicorp/terraform-consul-aws", "bitbucket", ""},
 			"",
 			"",
-			false},
+			true},
 
 		{"Shallow", fields{}, args{"git::https://github.com/terraform-aws-modules/terraform-aws-memory-db.git?depth=1", "shallow", ""},
 			"git::https://github.com/terraform-aws-modules/terraform-aws-memory-db.git?depth=1",
@@ -226,7 +231,7 @@ func TestFlags_UpdateSource(t *testing.T) {
 				Entries:     tt.fields.Entries,
 				Update:      tt.fields.Update,
 			}
-			got, got1, err := myFlags.UpdateSource(tt.args.module, tt.args.moduleType, tt.args.version)
+			got, got1, err := myFlags.UpdateSource(tt.args.module, tt.args.moduleType, tt.args.version, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("UpdateSource() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -296,7 +301,7 @@ func TestFlags_UpdateGithubSource(t *testing.T) {
 				Update:          tt.fields.Update,
 				ContinueOnError: tt.fields.ContinueOnError,
 			}
-			got, got1, err := myFlags.UpdateGithubSource(tt.args.version, tt.args.newModule)
+			got, got1, err := myFlags.UpdateGithubSource(tt.args.version, tt.args.newModule, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("UpdateGithubSource() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -480,3 +485,116 @@ func TestUpdateSource_TagRefKeepsVersion(t *testing.T) {
 		t.Errorf("tag ?ref= should keep version %q, got %q", "v0.0.3", version)
 	}
 }
+
+// gitTagRepo creates a git repository with a lightweight tag v1.0.0 and an
+// annotated tag v1.1.0 on the commit after it, and returns its path and the
+// commit each tag points at.
+func gitTagRepo(t *testing.T) (dir string, shas map[string]string) {
+	t.Helper()
+	dir = t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		args = append([]string{"-C", dir, "-c", "user.name=ghat", "-c", "user.email=ghat@example.com"}, args...)
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "one")
+	git("tag", "v1.0.0")
+	git("commit", "-q", "--allow-empty", "-m", "two")
+	git("tag", "-a", "v1.1.0", "-m", "v1.1.0")
+	return dir, map[string]string{
+		"v1.0.0": git("rev-parse", "v1.0.0^{commit}"),
+		"v1.1.0": git("rev-parse", "v1.1.0^{commit}"),
+	}
+}
+
+func TestParseGitSource(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		module string
+		want   gitSource
+		pinned string
+	}{
+		{"git::https://gitlab.com/org/vpc.git//modules/nat?ref=v1.2.0&depth=1",
+			gitSource{prefix: "git::", repo: "https://gitlab.com/org/vpc.git", subdir: "modules/nat", ref: "v1.2.0", query: []string{"depth=1"}},
+			"git::https://gitlab.com/org/vpc.git//modules/nat?ref=abc&depth=1"},
+		{"git@gitlab.com:group/sub/vpc.git?ref=v1.2.0",
+			gitSource{repo: "git@gitlab.com:group/sub/vpc.git", ref: "v1.2.0"},
+			"git@gitlab.com:group/sub/vpc.git?ref=abc"},
+		{"bitbucket.org/acme/vpc//nat",
+			gitSource{prefix: "git::", repo: "https://bitbucket.org/acme/vpc.git", subdir: "nat"},
+			"git::https://bitbucket.org/acme/vpc.git//nat?ref=abc"},
+	}
+	for _, tt := range tests {
+		got := parseGitSource(tt.module)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseGitSource(%q) = %+v, want %+v", tt.module, got, tt.want)
+		}
+		if pinned := got.withRef("abc"); pinned != tt.pinned {
+			t.Errorf("withRef() = %q, want %q", pinned, tt.pinned)
+		}
+	}
+}
+
+func TestFlags_UpdateGitSource(t *testing.T) {
+	t.Parallel()
+
+	dir, shas := gitTagRepo(t)
+	repo := "git::file://" + dir
+
+	tests := []struct {
+		name    string
+		flags   Flags
+		module  string
+		want    string
+		wantTag string
+		wantErr bool
+	}{
+		{"pins ref", Flags{}, repo + "//modules/nat?ref=v1.0.0",
+			repo + "//modules/nat?ref=" + shas["v1.0.0"], "v1.0.0", false},
+		{"update", Flags{Update: true}, repo + "?ref=v1.0.0",
+			repo + "?ref=" + shas["v1.1.0"], "v1.1.0", false},
+		{"missing tag upgrades", Flags{}, repo + "?ref=v0.9.0",
+			repo + "?ref=" + shas["v1.1.0"], "v1.1.0", false},
+		{"pinned commit names its tag", Flags{}, repo + "?ref=" + shas["v1.0.0"],
+			repo + "?ref=" + shas["v1.0.0"], "v1.0.0", false},
+		{"pinned commit update", Flags{Update: true}, repo + "?ref=" + shas["v1.0.0"],
+			repo + "?ref=" + shas["v1.1.0"], "v1.1.0", false},
+		{"cooldown", Flags{Update: true, Cooldown: map[string]uint{SourceTerraform: 7}}, repo + "?ref=v1.0.0",
+			"", "", true},
+	}
+	for _, tt := range tests {
+		f := tt.flags
+		got, tag, err := f.UpdateSource(tt.module, "git", "", nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: UpdateSource() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want || tag != tt.wantTag {
+			t.Errorf("%s: UpdateSource() = %q, %q, want %q, %q", tt.name, got, tag, tt.want, tt.wantTag)
+		}
+	}
+}
+
+func TestFlags_UpdateGitSource_OptionURL(t *testing.T) {
+	t.Parallel()
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	f := Flags{}
+	_, _, err := f.UpdateSource("git::--upload-pack=touch "+marker+"?ref=v1.0.0", "git", "", nil)
+	var urlErr *gitURLError
+	if !errors.As(err, &urlErr) {
+		t.Errorf("UpdateSource() error = %v, want a gitURLError", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("git ran the --upload-pack command")
+	}
+	if _, err := gitTagSHA("--upload-pack=touch "+marker, "v1.0.0"); !errors.As(err, &urlErr) {
+		t.Errorf("gitTagSHA() error = %v, want a gitURLError", err)
+	}
+}
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// _responseCache and _offline are process-wide, like _httpClient: every
+// lookup ghat makes (GitHub, GitLab, the Terraform and provider registries,
+// the audit ecosystems) goes through a client from lookupClient, so recording
+// and replaying responses there covers them all without threading the cache
+// through each helper.
+var (
+	_responseCache atomic.Pointer[Cache]
+	_offline       atomic.Bool
+)
+
+// lookupClient returns a client for a lookup --offline must be able to
+// replay. Downloads, writes and other libraries' requests go around it.
+func lookupClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &cachingTransport{}, Timeout: timeout}
+}
+
+// SetResponseCache records successful GET responses into c, or with offline
+// set serves every request from c alone, ignoring TTL. A nil c with offline
+// unset turns both off.
+func SetResponseCache(c *Cache, offline bool) {
+	_responseCache.Store(c)
+	_offline.Store(offline)
+}
+
+// Offline reports whether lookups are being served from the cache only.
+func Offline() bool {
+	return _offline.Load()
+}
+
+type offlineMissError struct {
+	key string
+}
+
+func (e *offlineMissError) Error() string {
+	return fmt.Sprintf("offline: %s is not in the cache", e.key)
+}
+
+type offlineCacheDisabledError struct{}
+
+func (e *offlineCacheDisabledError) Error() string {
+	return "--offline resolves from the cache and cannot be combined with --no-cache"
+}
+
+// cachedResponse is an HTTP response as stored in the cache.
+type cachedResponse struct {
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   string            `json:"body"`
+}
+
+// recordedHeaders are the response headers callers read: the content type
+// and GitHub's pagination links.
+var recordedHeaders = []string{"Content-Type", "Link"}
+
+// credentialHeaders carry a token; a response fetched with one is recorded
+// under that token, so only a run holding the same token replays it.
+var credentialHeaders = []string{"Authorization", "PRIVATE-TOKEN"}
+
+type cachingTransport struct {
+	base http.RoundTripper // nil means http.DefaultTransport
+}
+
+// responseKey is the cache key of a request: its method and URL, and a
+// fingerprint of the token it carries, if any.
+func responseKey(req *http.Request) string {
+	key := "http:" + req.Method + " " + req.URL.String()
+	h := sha256.New()
+	var authenticated bool
+	for _, name := range credentialHeaders {
+		if v := req.Header.Get(name); v != "" {
+			authenticated = true
+			_, _ = io.WriteString(h, name+": "+v+"\n")
+		}
+	}
+	if authenticated {
+		key += " as:" + hex.EncodeToString(h.Sum(nil))[:16]
+	}
+	return key
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	cache := _responseCache.Load()
+	if cache == nil && !Offline() {
+		return base.RoundTrip(req)
+	}
+
+	key := responseKey(req)
+	if Offline() {
+		if req.Method != http.MethodGet || cache == nil {
+			return nil, &offlineMissError{key: key}
+		}
+		raw, ok := cache.getRaw(key)
+		if !ok {
+			return nil, &offlineMissError{key: key}
+		}
+		var cached cachedResponse
+		if err := json.Unmarshal(raw, &cached); err != nil {
+			return nil, &offlineMissError{key: key}
+		}
+		resp := &http.Response{
+			Status:        fmt.Sprintf("%d %s", cached.Status, http.StatusText(cached.Status)),
+			StatusCode:    cached.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          io.NopCloser(strings.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}
+		for k, v := range cached.Header {
+			resp.Header.Set(k, v)
+		}
+		return resp, nil
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	cached := cachedResponse{Status: resp.StatusCode, Header: map[string]string{}, Body: string(body)}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			cached.Header[h] = v
+		}
+	}
+	_ = cache.Set(key, cached)
+	return resp, nil
+}
+
+// recorded runs fetch and caches its result under key, or offline returns
+// the cached result without calling fetch. It covers lookups that do not go
+// through lookupClient, such as go-containerregistry's and git's.
+func recorded[T any](key string, fetch func() (T, error)) (T, error) {
+	var zero T
+	cache := _responseCache.Load()
+	if Offline() {
+		if cache == nil {
+			return zero, &offlineMissError{key: key}
+		}
+		raw, ok := cache.getRaw(key)
+		if !ok {
+			return zero, &offlineMissError{key: key}
+		}
+		var v T
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return zero, &offlineMissError{key: key}
+		}
+		return v, nil
+	}
+
+	v, err := resolve(key, fetch)
+	if err == nil && cache != nil {
+		_ = cache.Set(key, v)
+	}
+	return v, err
+}
+
+// useResponseCache points the process-wide transport at f.Cache, recording
+// responses or, with --offline, serving only from it. Offline opens the cache
+// for commands that do not otherwise use one, but refuses --no-cache.
+func (f *Flags) useResponseCache() error {
+	if f.Offline && f.Cache == nil {
+		cache, err := NewCache(f.CacheTTL, true)
+		if err != nil {
+			return err
+		}
+		f.Cache = cache
+	}
+	if f.Cache == nil || !f.Cache.IsEnabled() {
+		if f.Offline {
+			return &offlineCacheDisabledError{}
+		}
+		SetResponseCache(nil, false)
+		return nil
+	}
+	SetResponseCache(f.Cache, f.Offline)
+	return nil
+}
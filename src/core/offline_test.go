@@ -0,0 +1,195 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachingTransport_Offline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Link", `<https://example.com/next>; rel="next"`)
+		_, _ = w.Write([]byte(`{"tag_name":"v1.2.3"}`))
+	}))
+	t.Cleanup(func() { SetResponseCache(nil, false) })
+
+	// A negative TTL expires every entry as it is written: offline must
+	// still serve it.
+	cache := newCacheIn(t.TempDir(), -time.Hour)
+	SetResponseCache(cache, false)
+	if _, err := GetGithubBody("", srv.URL+"/releases/latest"); err != nil {
+		t.Fatalf("GetGithubBody() online error = %v", err)
+	}
+	srv.Close()
+
+	SetResponseCache(cache, true)
+	body, next, err := getPagedGithubBody("", srv.URL+"/releases/latest")
+	if err != nil {
+		t.Fatalf("getPagedGithubBody() offline error = %v", err)
+	}
+	if m, _ := body.(map[string]interface{}); m["tag_name"] != "v1.2.3" {
+		t.Errorf("getPagedGithubBody() offline body = %v", body)
+	}
+	if next != "https://example.com/next" {
+		t.Errorf("getPagedGithubBody() offline next = %q, want the recorded Link", next)
+	}
+
+	_, err = GetGithubBody("", srv.URL+"/releases/other")
+	var miss *offlineMissError
+	if !errors.As(err, &miss) {
+		t.Errorf("GetGithubBody() offline miss error = %v, want *core.offlineMissError", err)
+	}
+}
+
+func TestCachingTransport_Credentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"private":true}`))
+	}))
+	t.Cleanup(func() { SetResponseCache(nil, false) })
+	if _, ok := http.DefaultTransport.(*cachingTransport); ok {
+		t.Fatal("http.DefaultTransport should be left alone")
+	}
+
+	dir := t.TempDir()
+	cache := newCacheIn(dir, time.Hour)
+	get := func(token string) error {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/projects/1", nil)
+		if token != "" {
+			req.Header.Set("PRIVATE-TOKEN", token)
+		}
+		resp, err := lookupClient(time.Second).Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	SetResponseCache(cache, false)
+	if err := get("alice"); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if info, _ := e.Info(); info.Mode().Perm() != 0600 {
+			t.Errorf("cache entry %s has mode %v, want 0600", e.Name(), info.Mode().Perm())
+		}
+	}
+
+	SetResponseCache(cache, true)
+	if err := get("alice"); err != nil {
+		t.Errorf("offline with the recording token: %v", err)
+	}
+	for _, token := range []string{"", "mallory"} {
+		if err := get(token); err == nil {
+			t.Errorf("offline with token %q replayed a response recorded with another", token)
+		}
+	}
+}
+
+func TestRecorded(t *testing.T) {
+	t.Cleanup(func() { SetResponseCache(nil, false) })
+	cache := newCacheIn(t.TempDir(), time.Hour)
+
+	calls := 0
+	fetch := func() (string, error) {
+		calls++
+		return "sha256:abc", nil
+	}
+
+	SetResponseCache(cache, true)
+	if _, err := recorded("registry:digest nginx:1.27", fetch); err == nil || calls != 0 {
+		t.Errorf("recorded() offline miss = (%v, %d calls), want an error and no fetch", err, calls)
+	}
+
+	SetResponseCache(cache, false)
+	if got, err := recorded("registry:digest nginx:1.27", fetch); got != "sha256:abc" || err != nil {
+		t.Fatalf("recorded() online = (%q, %v)", got, err)
+	}
+
+	SetResponseCache(cache, true)
+	if got, err := recorded("registry:digest nginx:1.27", fetch); got != "sha256:abc" || err != nil || calls != 1 {
+		t.Errorf("recorded() offline hit = (%q, %v, %d calls), want the cached digest", got, err, calls)
+	}
+}
+
+func TestFlags_UseResponseCache(t *testing.T) {
+	t.Cleanup(func() { SetResponseCache(nil, false) })
+
+	f := &Flags{Offline: true, Cache: &Cache{enabled: false}}
+	err := f.useResponseCache()
+	if got := fmt.Sprintf("%T", err); got != "*core.offlineCacheDisabledError" {
+		t.Errorf("useResponseCache() with --no-cache error = %v (%s)", err, got)
+	}
+
+	f = &Flags{Offline: true, Cache: newCacheIn(t.TempDir(), time.Hour)}
+	if err := f.useResponseCache(); err != nil || !Offline() {
+		t.Errorf("useResponseCache() = %v, Offline() = %v", err, Offline())
+	}
+
+	f = &Flags{}
+	if err := f.useResponseCache(); err != nil || Offline() || _responseCache.Load() != nil {
+		t.Errorf("useResponseCache() without a cache left offline state behind: %v", err)
+	}
+}
+
+func TestCache_ExportImport(t *testing.T) {
+	t.Parallel()
+
+	src := newCacheIn(t.TempDir(), time.Hour)
+	if err := src.Set("https://api.github.com/repos/actions/checkout", map[string]string{"default_branch": "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Set("registry:digest nginx:1.27", "sha256:abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if n, err := src.Export(&archive); n != 2 || err != nil {
+		t.Fatalf("Export() = (%d, %v), want 2 entries", n, err)
+	}
+
+	dst := newCacheIn(t.TempDir(), time.Hour)
+	if n, err := dst.Import(bytes.NewReader(archive.Bytes())); n != 2 || err != nil {
+		t.Fatalf("Import() = (%d, %v), want 2 entries", n, err)
+	}
+	if got, ok := dst.Get("registry:digest nginx:1.27"); !ok || got != "sha256:abc" {
+		t.Errorf("imported entry = (%v, %v)", got, ok)
+	}
+}
+
+func TestCache_ImportRejectsPaths(t *testing.T) {
+	t.Parallel()
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	data := []byte(`{"data":"x","expires_at":"2030-01-01T00:00:00Z","url":"x"}`)
+	if err := tw.WriteHeader(&tar.Header{Name: "../escaped", Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tw.Write(data)
+	_ = tw.Close()
+	_ = gz.Close()
+
+	dir := filepath.Join(t.TempDir(), "cache")
+	c := newCacheIn(dir, time.Hour)
+	if n, err := c.Import(&archive); n != 0 || err != nil {
+		t.Errorf("Import() = (%d, %v), want the entry skipped", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escaped")); !os.IsNotExist(err) {
+		t.Error("Import() wrote outside the cache directory")
+	}
+	if _, err := c.Import(bytes.NewReader([]byte("not an archive"))); err == nil {
+		t.Error("Import() of a non-gzip stream should error")
+	}
+}
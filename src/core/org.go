@@ -20,7 +20,7 @@ type OrgFlags struct {
 	Owner       string
 	Repos       []string // explicit list; if set, Owner/Limit are ignored
 	Token       string   // PAT for Provider (clone/push/PR)
-	GitHubToken string   // separate PAT for api.github.com lookups during the sweep
+	GitHubToken string   // separate PAT for GitHub API lookups during the sweep
 	Branch      string
 	Offset      int
 	Limit       int
@@ -169,12 +169,18 @@ func (o *OrgFlags) processRepo(host hostProvider, repo hostRepo) RepoResult {
 	if ghToken == "" && !strings.EqualFold(o.Provider, "gitlab") {
 		ghToken = o.Token
 	}
+	// Pin the API root to the caller's choice so a cloned repo's .ghat.yml
+	// cannot redirect the token to another host.
 	myFlags := &Flags{
 		Directory:       dir,
 		GitHubToken:     ghToken,
 		DryRun:          false,
 		ContinueOnError: true,
 		Silent:          true,
+		GitHubAPIURL:    GitHubAPIURL(),
+	}
+	if strings.EqualFold(o.Provider, "gitlab") {
+		myFlags.GitLabURL, myFlags.GitLabToken = o.BaseURL, o.Token
 	}
 	var days uint
 	myFlags.Days = &days
@@ -237,7 +243,7 @@ func (o *OrgFlags) processRepo(host hostProvider, repo hostRepo) RepoResult {
 		return result
 	}
 
-	prURL, mergeID, err := host.CreatePR(repo, o.Branch, base)
+	prURL, mergeID, err := host.CreatePR(repo, o.Branch, base, prDescription(dir, myFlags.Changes))
 	if err != nil {
 		if open, prURL, mergeID, _ := host.PRExists(repo, o.Branch); open {
 			result.Status = "pr-open"
@@ -324,7 +330,7 @@ func (f *Flags) CreateLocalPR(dir string) (string, bool, error) {
 		return existingPRUrl, true, nil
 	}
 
-	prURL, mergeID, err := host.CreatePR(repo, branch, base)
+	prURL, mergeID, err := host.CreatePR(repo, branch, base, prDescription(dir, f.Changes))
 	if err != nil {
 		if open, u, mid, _ := host.PRExists(repo, branch); open {
 			if f.AutoMerge && mid != "" {
@@ -351,7 +357,7 @@ func parseRemoteURL(remoteURL string) (provider, repoName, baseURL string, err e
 			return "", "", "", fmt.Errorf("cannot parse SSH remote: %s", remoteURL)
 		}
 		repo := strings.TrimSuffix(path, ".git")
-		if strings.Contains(host, "github") {
+		if strings.Contains(host, "github") || isGitHubWebHost(host) {
 			return "github", repo, "", nil
 		}
 		return "gitlab", repo, "https://" + host, nil
@@ -362,7 +368,7 @@ func parseRemoteURL(remoteURL string) (provider, repoName, baseURL string, err e
 		return "", "", "", fmt.Errorf("cannot parse remote URL: %w", err)
 	}
 	repo := strings.TrimSuffix(strings.TrimPrefix(u.Path, "/"), ".git")
-	if strings.Contains(u.Hostname(), "github") {
+	if strings.Contains(u.Hostname(), "github") || isGitHubWebHost(u.Hostname()) {
 		return "github", repo, "", nil
 	}
 	return "gitlab", repo, u.Scheme + "://" + u.Host, nil
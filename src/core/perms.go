@@ -0,0 +1,352 @@
+package core
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed permissions.yml
+var defaultPermissionsData []byte
+
+// permissionRules map well-known actions and run: commands to the
+// GITHUB_TOKEN scopes they need.
+type permissionRules struct {
+	actions  []actionPermissions
+	commands []commandPermissions
+}
+
+type actionPermissions struct {
+	uses   string
+	when   *regexp.Regexp // nil: always applies
+	scopes map[string]string
+}
+
+type commandPermissions struct {
+	match  *regexp.Regexp
+	scopes map[string]string
+}
+
+type permissionRulesError struct {
+	err error
+}
+
+func (e *permissionRulesError) Error() string {
+	return fmt.Sprintf("invalid permissions mapping: %v", e.err)
+}
+
+func (e *permissionRulesError) Unwrap() error { return e.err }
+
+type permsParseError struct {
+	file string
+	err  error
+}
+
+func (e *permsParseError) Error() string {
+	return fmt.Sprintf("failed to parse workflow %s: %v", e.file, e.err)
+}
+
+// loadPermissionRules parses a permissions.yml mapping.
+func loadPermissionRules(data []byte) (*permissionRules, error) {
+	var raw struct {
+		Actions []struct {
+			Uses        string            `yaml:"uses"`
+			When        string            `yaml:"when"`
+			Permissions map[string]string `yaml:"permissions"`
+		} `yaml:"actions"`
+		Commands []struct {
+			Match       string            `yaml:"match"`
+			Permissions map[string]string `yaml:"permissions"`
+		} `yaml:"commands"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, &permissionRulesError{err}
+	}
+
+	rules := &permissionRules{}
+	for _, a := range raw.Actions {
+		rule := actionPermissions{uses: a.Uses, scopes: a.Permissions}
+		if a.When != "" {
+			re, err := regexp.Compile(a.When)
+			if err != nil {
+				return nil, &permissionRulesError{err}
+			}
+			rule.when = re
+		}
+		rules.actions = append(rules.actions, rule)
+	}
+	for _, c := range raw.Commands {
+		re, err := regexp.Compile(c.Match)
+		if err != nil {
+			return nil, &permissionRulesError{err}
+		}
+		rules.commands = append(rules.commands, commandPermissions{match: re, scopes: c.Permissions})
+	}
+	return rules, nil
+}
+
+// scopeRank orders permission levels so merging keeps the broader one.
+var scopeRank = map[string]int{"none": 0, "read": 1, "write": 2}
+
+// mergeScopes raises each scope in dst to at least its level in src.
+func mergeScopes(dst, src map[string]string) {
+	for scope, level := range src {
+		if scopeRank[level] > scopeRank[dst[scope]] {
+			dst[scope] = level
+		}
+	}
+}
+
+// stepPermissions returns the scopes a step needs, and false when it uses
+// an action the mapping does not know.
+func (r *permissionRules) stepPermissions(step *yaml.Node) (map[string]string, bool) {
+	scopes := map[string]string{}
+	known := true
+
+	if uses := scalarValue(step, "uses"); uses != "" &&
+		!strings.HasPrefix(uses, "./") && !strings.HasPrefix(uses, "docker://") {
+		action, _, _ := strings.Cut(uses, "@")
+		_, action = splitUsesHost(action)
+
+		var with []string
+		if w := findMappingValue(step, "with"); w != nil && w.Kind == yaml.MappingNode {
+			for i := 1; i < len(w.Content); i += 2 {
+				with = append(with, w.Content[i-1].Value+": "+w.Content[i].Value)
+			}
+		}
+
+		known = false
+		for _, rule := range r.actions {
+			if !strings.EqualFold(action, rule.uses) && !strings.HasPrefix(strings.ToLower(action), strings.ToLower(rule.uses)+"/") {
+				continue
+			}
+			known = true
+			if rule.when == nil || slices.ContainsFunc(with, rule.when.MatchString) {
+				mergeScopes(scopes, rule.scopes)
+			}
+		}
+	}
+
+	if run := scalarValue(step, "run"); run != "" {
+		for _, rule := range r.commands {
+			if rule.match.MatchString(run) {
+				mergeScopes(scopes, rule.scopes)
+			}
+		}
+	}
+	return scopes, known
+}
+
+// scalarValue returns the scalar value of key in mapping n, or "".
+func scalarValue(n *yaml.Node, key string) string {
+	if v := findMappingValue(n, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// mappingKey returns the key node for key in mapping n, or nil.
+func mappingKey(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+// lastLine returns the last source line a block-style value occupies.
+func lastLine(n *yaml.Node) int {
+	last := n.Line
+	for _, c := range n.Content {
+		if l := lastLine(c); l > last {
+			last = l
+		}
+	}
+	return last
+}
+
+// renderPermissions formats a permissions: block at indent, with scopes one
+// step further in.
+func renderPermissions(indent, step string, scopes map[string]string) []string {
+	if len(scopes) == 0 {
+		return []string{indent + "permissions: {}"}
+	}
+	names := make([]string, 0, len(scopes))
+	for name := range scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{indent + "permissions:"}
+	for _, name := range names {
+		lines = append(lines, indent+step+name+": "+scopes[name])
+	}
+	return lines
+}
+
+// lineEdit replaces lines [start, end) of a file, 0-indexed.
+type lineEdit struct {
+	start, end int
+	lines      []string
+}
+
+// rewritePermissions gives every job in a workflow its own least-privilege
+// permissions: block, computed from the actions and commands its steps use,
+// and reduces the workflow-level block to {} — or read-all while a reusable
+// workflow call still inherits it. Jobs that already declare permissions
+// keep them unless tighten is set; without tighten, explicit workflow-level
+// scopes are carried down into each job too. A job using an action the
+// mapping does not know is left as it is, and so is the workflow-level block
+// while such a job inherits it, since ghat can't tell what the action needs.
+// It returns the rewritten workflow and the actions the mapping did not know.
+func rewritePermissions(content []byte, rules *permissionRules, tighten bool) (string, []string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return "", nil, err
+	}
+	jobs := findMappingValue(&root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return string(content), nil, nil
+	}
+
+	inherited := map[string]string{}
+	topKey, topVal := mappingKey(&root, "permissions"), findMappingValue(&root, "permissions")
+	if !tighten && topVal != nil && topVal.Kind == yaml.MappingNode {
+		for i := 1; i < len(topVal.Content); i += 2 {
+			inherited[topVal.Content[i-1].Value] = topVal.Content[i].Value
+		}
+	}
+
+	var edits []lineEdit
+	var unknown []string
+	inheriting, keepTop := false, false
+	for i := 1; i < len(jobs.Content); i += 2 {
+		key, job := jobs.Content[i-1], jobs.Content[i]
+		if job.Kind != yaml.MappingNode || len(job.Content) == 0 {
+			continue
+		}
+		permKey, permVal := mappingKey(job, "permissions"), findMappingValue(job, "permissions")
+		if scalarValue(job, "uses") != "" {
+			// A reusable workflow call's needs live in the called workflow.
+			inheriting = inheriting || permKey == nil
+			continue
+		}
+		if permKey != nil && !tighten {
+			continue
+		}
+
+		scopes := map[string]string{}
+		mergeScopes(scopes, inherited)
+		unmapped := false
+		if steps := findMappingValue(job, "steps"); steps != nil {
+			for _, step := range steps.Content {
+				s, known := rules.stepPermissions(step)
+				mergeScopes(scopes, s)
+				if !known {
+					unmapped = true
+					unknown = append(unknown, key.Value+": "+scalarValue(step, "uses"))
+				}
+			}
+		}
+		if unmapped {
+			keepTop = keepTop || permKey == nil
+			continue
+		}
+
+		indent := strings.Repeat(" ", job.Content[0].Column-1)
+		step := strings.Repeat(" ", max(job.Content[0].Column-key.Column, 2))
+		block := renderPermissions(indent, step, scopes)
+		if permKey != nil {
+			edits = append(edits, lineEdit{start: permKey.Line - 1, end: lastLine(permVal), lines: block})
+		} else {
+			edits = append(edits, lineEdit{start: key.Line, end: key.Line, lines: block})
+		}
+	}
+
+	target := "{}"
+	if inheriting {
+		target = "read-all"
+	}
+	switch {
+	case keepTop:
+		// A job ghat left alone still inherits it.
+	case topKey == nil:
+		jobsLine := mappingKey(&root, "jobs").Line - 1
+		edits = append(edits, lineEdit{start: jobsLine, end: jobsLine, lines: []string{"permissions: " + target, ""}})
+	case inheriting && len(inherited) > 0:
+		// Keep the explicit scopes the reusable workflow call inherits.
+	case topVal.Kind == yaml.ScalarNode && topVal.Value == target,
+		target == "{}" && topVal.Kind == yaml.MappingNode && len(topVal.Content) == 0:
+		// Already there.
+	default:
+		edits = append(edits, lineEdit{start: topKey.Line - 1, end: lastLine(topVal), lines: []string{"permissions: " + target}})
+	}
+
+	lines := strings.Split(string(content), "\n")
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		lines = slices.Replace(lines, e.start, e.end, e.lines...)
+	}
+	return strings.Join(lines, "\n"), unknown, nil
+}
+
+// Perms rewrites each workflow's permissions: to the least privilege its
+// jobs need.
+func (f *Flags) Perms() error {
+	rules, err := loadPermissionRules(defaultPermissionsData)
+	if err != nil {
+		return err
+	}
+	for _, file := range f.GetGHA() {
+		if !strings.Contains(filepath.ToSlash(file), githubWorkflowPath) {
+			continue
+		}
+		if err := f.updatePermissions(file, rules); err != nil {
+			if f.ContinueOnError {
+				log.Warn().Err(err).Msg("skipping workflow")
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Flags) updatePermissions(file string, rules *permissionRules) error {
+	buffer, err := os.ReadFile(file)
+	if err != nil {
+		return &ghaFileError{file}
+	}
+	replacement, unknown, err := rewritePermissions(buffer, rules, f.Tighten)
+	if err != nil {
+		return &permsParseError{file: file, err: err}
+	}
+	for _, u := range unknown {
+		log.Warn().Str("file", file).Msgf("no permissions mapping for %s; leaving the job's permissions as they are", u)
+	}
+	if replacement == string(buffer) {
+		return nil
+	}
+
+	f.printDiff(file, string(buffer), replacement)
+	if !f.DryRun {
+		if err := os.WriteFile(file, []byte(replacement), 0644); err != nil {
+			return &writeGHAError{file}
+		}
+	}
+	return nil
+}
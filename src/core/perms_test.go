@@ -0,0 +1,153 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewritePermissions(t *testing.T) {
+	t.Parallel()
+
+	rules, err := loadPermissionRules(defaultPermissionsData)
+	if err != nil {
+		t.Fatalf("loadPermissionRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		tighten bool
+		content string
+		want    string
+		unknown []string
+	}{
+		{
+			name: "adds job blocks and empties the workflow block",
+			content: "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n" +
+				"      - uses: actions/checkout@v4\n" +
+				"      - run: |\n          go test ./...\n          git push origin HEAD\n",
+			want: "on: push\npermissions: {}\n\njobs:\n  build:\n    permissions:\n      contents: write\n" +
+				"    runs-on: ubuntu-latest\n    steps:\n" +
+				"      - uses: actions/checkout@v4\n" +
+				"      - run: |\n          go test ./...\n          git push origin HEAD\n",
+		},
+		{
+			name: "keeps explicit scopes",
+			content: "permissions:\n  issues: write\njobs:\n" +
+				"  a:\n    permissions: write-all\n    steps:\n      - uses: actions/checkout@v4\n" +
+				"  b:\n    steps:\n      - uses: github/codeql-action/upload-sarif@v3\n",
+			want: "permissions: {}\njobs:\n" +
+				"  a:\n    permissions: write-all\n    steps:\n      - uses: actions/checkout@v4\n" +
+				"  b:\n    permissions:\n      issues: write\n      security-events: write\n" +
+				"    steps:\n      - uses: github/codeql-action/upload-sarif@v3\n",
+		},
+		{
+			name:    "tighten",
+			tighten: true,
+			content: "permissions:\n  issues: write\njobs:\n" +
+				"  a:\n    permissions: write-all\n    steps:\n      - uses: actions/checkout@v4\n" +
+				"  b:\n    permissions:\n      contents: write\n      packages: write\n" +
+				"    steps:\n      - uses: docker/login-action@v3\n        with:\n          registry: docker.io\n" +
+				"      - uses: org/custom@v1\n",
+			want: "permissions: {}\njobs:\n" +
+				"  a:\n    permissions:\n      contents: read\n    steps:\n      - uses: actions/checkout@v4\n" +
+				"  b:\n    permissions:\n      contents: write\n      packages: write\n" +
+				"    steps:\n      - uses: docker/login-action@v3\n        with:\n          registry: docker.io\n" +
+				"      - uses: org/custom@v1\n",
+			unknown: []string{"b: org/custom@v1"},
+		},
+		{
+			name: "unknown action keeps the job and what it inherits",
+			content: "permissions:\n  contents: write\njobs:\n" +
+				"  a:\n    steps:\n      - uses: actions/checkout@v4\n" +
+				"  b:\n    steps:\n      - uses: org/release@v1\n",
+			want: "permissions:\n  contents: write\njobs:\n" +
+				"  a:\n    permissions:\n      contents: write\n    steps:\n      - uses: actions/checkout@v4\n" +
+				"  b:\n    steps:\n      - uses: org/release@v1\n",
+			unknown: []string{"b: org/release@v1"},
+		},
+		{
+			name:    "reusable workflow call inherits read-all",
+			tighten: true,
+			content: "permissions: write-all\njobs:\n  call:\n    uses: org/shared/.github/workflows/ci.yml@v1\n",
+			want:    "permissions: read-all\njobs:\n  call:\n    uses: org/shared/.github/workflows/ci.yml@v1\n",
+		},
+		{
+			name:    "already minimal",
+			content: "permissions: {}\njobs:\n  a:\n    permissions:\n      contents: read\n    steps:\n      - uses: actions/checkout@v4\n",
+			want:    "permissions: {}\njobs:\n  a:\n    permissions:\n      contents: read\n    steps:\n      - uses: actions/checkout@v4\n",
+		},
+	}
+	for _, tt := range tests {
+		got, unknown, err := rewritePermissions([]byte(tt.content), rules, tt.tighten)
+		if err != nil {
+			t.Fatalf("%s: rewritePermissions() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: rewritePermissions() =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(unknown, tt.unknown) {
+			t.Errorf("%s: unknown = %q, want %q", tt.name, unknown, tt.unknown)
+		}
+	}
+}
+
+func TestStepPermissions_When(t *testing.T) {
+	t.Parallel()
+
+	rules, err := loadPermissionRules([]byte("actions:\n  - uses: aws-actions/configure-aws-credentials\n" +
+		"    when: role-to-assume\n    permissions: {id-token: write}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		with string
+		want string
+	}{
+		{with: "role-to-assume: arn:aws:iam::123456789012:role/ci", want: "    permissions:\n      id-token: write\n"},
+		{with: "aws-access-key-id: AKIA", want: "    permissions: {}\n"},
+	}
+	for _, tt := range tests {
+		content := "jobs:\n  a:\n    steps:\n      - uses: aws-actions/configure-aws-credentials@v4\n        with:\n          " + tt.with + "\n"
+		got, _, err := rewritePermissions([]byte(content), rules, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(got, "  a:\n"+tt.want) {
+			t.Errorf("rewritePermissions() with %q =\n%s\nwant job block %q", tt.with, got, tt.want)
+		}
+	}
+}
+
+func TestFlags_Perms(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	if err := os.MkdirAll(filepath.Dir(workflow), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := "jobs:\n  a:\n    steps:\n      - uses: actions/checkout@v4\n"
+	if err := os.WriteFile(workflow, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Flags{Directory: dir, Entries: []string{workflow}, DryRun: true, Silent: true}
+	if err := f.Perms(); err != nil {
+		t.Fatalf("Perms() dry run error = %v", err)
+	}
+	if got, _ := os.ReadFile(workflow); string(got) != content {
+		t.Errorf("Perms() --dry-run wrote the workflow:\n%s", got)
+	}
+
+	f.DryRun = false
+	if err := f.Perms(); err != nil {
+		t.Fatalf("Perms() error = %v", err)
+	}
+	want := "permissions: {}\n\njobs:\n  a:\n    permissions:\n      contents: read\n    steps:\n      - uses: actions/checkout@v4\n"
+	if got, _ := os.ReadFile(workflow); string(got) != want {
+		t.Errorf("Perms() wrote\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// privilegedTriggers run with the base repository's secrets and a writable
+// GITHUB_TOKEN, yet can be started by someone without write access.
+var privilegedTriggers = []string{
+	"pull_request_target", "workflow_run", "issue_comment", "issues", "discussion", "discussion_comment",
+}
+
+// outsiderTriggers are the events someone without write access can cause,
+// including fork pull requests.
+var outsiderTriggers = append([]string{"pull_request", "pull_request_review", "pull_request_review_comment"}, privilegedTriggers...)
+
+var (
+	// tempDirRe matches a path under the runner's temp directory rather than
+	// the workspace.
+	tempDirRe = regexp.MustCompile(`runner\.temp|RUNNER_TEMP|^/tmp\b|\s/tmp\b`)
+	// unzipRe matches an unzip or tar extraction in a script.
+	unzipRe = regexp.MustCompile(`\bunzip\b|\btar\s+(?:-\S*x|x)`)
+	// ghRunDownloadRe matches gh run download of the triggering run.
+	ghRunDownloadRe = regexp.MustCompile(`\bgh\s+run\s+download\b.*workflow_run\.id`)
+	// setupCacheRe matches the setup-* actions that restore a dependency cache.
+	setupCacheRe = regexp.MustCompile(`^actions/setup-(?:node|python|go|java|dotnet)@`)
+)
+
+// WorkflowRisk is a job or step that opens a privilege-escalation path.
+type WorkflowRisk struct {
+	// Job is the job key, e.g. "deploy".
+	Job string
+	// Line is the 1-indexed source line of the step or runs-on: key.
+	Line int
+	// Desc explains the risk.
+	Desc string
+}
+
+// workflowTriggers returns the event names in a workflow's on: block.
+func workflowTriggers(root *yaml.Node) []string {
+	on := findMappingValue(root, "on")
+	if on == nil {
+		return nil
+	}
+	switch on.Kind {
+	case yaml.ScalarNode:
+		return []string{on.Value}
+	case yaml.SequenceNode:
+		var out []string
+		for _, n := range on.Content {
+			out = append(out, n.Value)
+		}
+		return out
+	case yaml.MappingNode:
+		var out []string
+		for i := 0; i < len(on.Content); i += 2 {
+			out = append(out, on.Content[i].Value)
+		}
+		return out
+	}
+	return nil
+}
+
+// firstTrigger returns the first of triggers that is in set, or "".
+func firstTrigger(triggers, set []string) string {
+	for _, t := range triggers {
+		if slices.Contains(set, t) {
+			return t
+		}
+	}
+	return ""
+}
+
+// usesAction reports whether uses names action at any ref.
+func usesAction(uses, action string) bool {
+	return strings.HasPrefix(strings.ToLower(uses), action+"@")
+}
+
+// isSelfHosted reports whether a runs-on: value requests a self-hosted runner.
+func isSelfHosted(runsOn *yaml.Node) bool {
+	if runsOn == nil {
+		return false
+	}
+	if labels := findMappingValue(runsOn, "labels"); labels != nil {
+		runsOn = labels
+	}
+	for _, n := range append([]*yaml.Node{runsOn}, runsOn.Content...) {
+		if n.Kind == yaml.ScalarNode && strings.EqualFold(strings.TrimSpace(n.Value), "self-hosted") {
+			return true
+		}
+	}
+	return false
+}
+
+// checksOutPRHead reports whether a job checks out the pull request's head,
+// putting attacker-controlled files in the workspace.
+func checksOutPRHead(steps *yaml.Node) bool {
+	if steps == nil {
+		return false
+	}
+	for _, step := range steps.Content {
+		if !usesAction(scalarValue(step, "uses"), "actions/checkout") {
+			continue
+		}
+		ref := scalarValue(findMappingValue(step, "with"), "ref")
+		if strings.Contains(ref, "github.event.pull_request.head") || strings.Contains(ref, "github.head_ref") ||
+			strings.Contains(ref, "refs/pull/") {
+			return true
+		}
+	}
+	return false
+}
+
+// artifactDownload describes a step that downloads the triggering
+// workflow_run's artifacts, and whether it writes them into the workspace.
+func artifactDownload(step *yaml.Node) (string, bool) {
+	uses := scalarValue(step, "uses")
+	with := findMappingValue(step, "with")
+	path := scalarValue(with, "path")
+	switch {
+	case usesAction(uses, "actions/download-artifact"):
+		if !strings.Contains(scalarValue(with, "run-id"), "workflow_run.id") {
+			return "", false
+		}
+	case usesAction(uses, "dawidd6/action-download-artifact"):
+	case ghRunDownloadRe.MatchString(scalarValue(step, "run")):
+		return "gh run download", !tempDirRe.MatchString(scalarValue(step, "run"))
+	default:
+		return "", false
+	}
+	action, _, _ := strings.Cut(uses, "@")
+	return action, !tempDirRe.MatchString(path)
+}
+
+// analyzeArtifactPoisoning finds workflow_run jobs that download the
+// triggering run's artifacts into the workspace, or unzip them there. The
+// triggering run may be a fork pull request's, so its artifacts are
+// attacker-controlled and can overwrite scripts the job then executes.
+func analyzeArtifactPoisoning(job string, steps *yaml.Node) []WorkflowRisk {
+	var out []WorkflowRisk
+	downloaded := false
+	for _, step := range steps.Content {
+		if action, toWorkspace := artifactDownload(step); action != "" {
+			downloaded = true
+			if toWorkspace {
+				out = append(out, WorkflowRisk{Job: job, Line: step.Line,
+					Desc: action + " writes the triggering workflow_run's artifacts into the workspace"})
+			}
+			continue
+		}
+		run := scalarValue(step, "run")
+		if downloaded && unzipRe.MatchString(run) && !tempDirRe.MatchString(run) {
+			out = append(out, WorkflowRisk{Job: job, Line: step.Line,
+				Desc: "the triggering workflow_run's artifacts are extracted into the workspace"})
+		}
+	}
+	return out
+}
+
+// analyzeCachePoisoning finds cache restores in a privileged workflow keyed
+// on values an outsider controls: untrusted github context, or hashFiles()
+// over a checked-out pull request head.
+func analyzeCachePoisoning(job string, steps *yaml.Node) []WorkflowRisk {
+	prHead := checksOutPRHead(steps)
+	var out []WorkflowRisk
+	for _, step := range steps.Content {
+		uses := scalarValue(step, "uses")
+		with := findMappingValue(step, "with")
+		switch {
+		case usesAction(uses, "actions/cache"), usesAction(uses, "actions/cache/restore"):
+			for _, input := range []string{"key", "restore-keys"} {
+				value := scalarValue(with, input)
+				for _, ref := range contextRefRe.FindAllString(value, -1) {
+					if untrusted(ref) {
+						out = append(out, WorkflowRisk{Job: job, Line: step.Line,
+							Desc: "actions/cache " + input + " uses " + ref + ", which an outsider controls"})
+					}
+				}
+				if prHead && strings.Contains(value, "hashFiles(") {
+					out = append(out, WorkflowRisk{Job: job, Line: step.Line,
+						Desc: "actions/cache " + input + " hashes files from the checked-out pull request head"})
+				}
+			}
+		case prHead && setupCacheRe.MatchString(strings.ToLower(uses)) && scalarValue(with, "cache") != "" &&
+			scalarValue(with, "cache") != "false":
+			action, _, _ := strings.Cut(uses, "@")
+			out = append(out, WorkflowRisk{Job: job, Line: step.Line,
+				Desc: action + " restores a cache keyed on lockfiles from the checked-out pull request head"})
+		}
+	}
+	return out
+}
+
+// analyzeEscalation returns the workflow's triggers, and the artifact
+// poisoning, cache poisoning and self-hosted runner risks in its jobs.
+// Cache poisoning is only a risk in a privileged workflow and artifact
+// poisoning only under workflow_run; self-hosted runners are recorded
+// whatever the trigger, since whether they are a risk depends on the
+// repository being public.
+func analyzeEscalation(content []byte) (triggers []string, artifact, cache, selfHosted []WorkflowRisk) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, nil, nil, nil
+	}
+	triggers = workflowTriggers(&root)
+	jobs := findMappingValue(&root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return triggers, nil, nil, nil
+	}
+	for i := 1; i < len(jobs.Content); i += 2 {
+		key, job := jobs.Content[i-1], jobs.Content[i]
+		if runsOnKey := mappingKey(job, "runs-on"); runsOnKey != nil && isSelfHosted(findMappingValue(job, "runs-on")) {
+			selfHosted = append(selfHosted, WorkflowRisk{Job: key.Value, Line: runsOnKey.Line,
+				Desc: "job " + key.Value + " runs on a self-hosted runner"})
+		}
+		steps := findMappingValue(job, "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		if slices.Contains(triggers, "workflow_run") {
+			artifact = append(artifact, analyzeArtifactPoisoning(key.Value, steps)...)
+		}
+		if firstTrigger(triggers, privilegedTriggers) != "" {
+			cache = append(cache, analyzeCachePoisoning(key.Value, steps)...)
+		}
+	}
+	return triggers, artifact, cache, selfHosted
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkflowFindings_Escalation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name: "workflow_run artifact into the workspace",
+			content: "on:\n  workflow_run:\n    workflows: [ci]\njobs:\n  report:\n    steps:\n" +
+				"      - uses: actions/download-artifact@v4\n        with:\n          run-id: ${{ github.event.workflow_run.id }}\n" +
+				"      - run: unzip pr.zip && ./report.sh\n",
+			want: []string{
+				"7 ghat-artifact-poisoning actions/download-artifact writes the triggering workflow_run's artifacts into the workspace",
+				"10 ghat-artifact-poisoning the triggering workflow_run's artifacts are extracted into the workspace",
+			},
+		},
+		{
+			name: "workflow_run artifact into runner.temp",
+			content: "on: workflow_run\njobs:\n  report:\n    steps:\n" +
+				"      - uses: dawidd6/action-download-artifact@v6\n        with:\n          path: ${{ runner.temp }}/pr\n" +
+				"      - run: unzip -d \"$RUNNER_TEMP/pr\" pr.zip\n",
+		},
+		{
+			name: "cache keyed on untrusted input in a privileged workflow",
+			content: "on:\n  pull_request_target:\njobs:\n  build:\n    steps:\n" +
+				"      - uses: actions/checkout@v4\n        with:\n          ref: ${{ github.event.pull_request.head.sha }}\n" +
+				"      - uses: actions/cache@v4\n        with:\n          key: deps-${{ github.head_ref }}-${{ hashFiles('go.sum') }}\n" +
+				"      - uses: actions/setup-node@v4\n        with:\n          cache: npm\n",
+			want: []string{
+				"6 ghat-dangerous-trigger ci.yml: pull_request_target with PR head checkout",
+				"9 ghat-cache-poisoning actions/cache key uses github.head_ref, which an outsider controls",
+				"9 ghat-cache-poisoning actions/cache key hashes files from the checked-out pull request head",
+				"12 ghat-cache-poisoning actions/setup-node restores a cache keyed on lockfiles from the checked-out pull request head",
+			},
+		},
+		{
+			name: "cache on push is fine",
+			content: "on: push\njobs:\n  build:\n    steps:\n" +
+				"      - uses: actions/cache@v4\n        with:\n          key: deps-${{ github.head_ref }}\n",
+		},
+		{
+			name:    "self-hosted runner on pull_request",
+			content: "on: [push, pull_request]\njobs:\n  build:\n    runs-on: [self-hosted, linux]\n    steps:\n      - run: make\n",
+			want: []string{
+				"4 ghat-self-hosted-runner job build runs on a self-hosted runner but is triggered by pull_request; in a public repository outsiders can run code on it",
+			},
+		},
+		{
+			name:    "self-hosted runner on push",
+			content: "on: push\njobs:\n  build:\n    runs-on: self-hosted\n    steps:\n      - run: make\n",
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, f := range WorkflowFindings("ci.yml", []byte("permissions: {}\n"+tt.content)) {
+			if f.Rule != RulePin {
+				got = append(got, fmt.Sprintf("%d %s %s", f.Line-1, f.Rule, f.Message))
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: WorkflowFindings() =\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSelfHostedRunner(t *testing.T) {
+	t.Parallel()
+
+	wf := map[string][]byte{"ci.yml": []byte("on: push\njobs:\n  build:\n    runs-on: [self-hosted]\n")}
+	tests := []struct {
+		name string
+		repo map[string]interface{}
+		wf   map[string][]byte
+		want checkOutcome
+	}{
+		{"none", nil, nil, checkSkip},
+		{"public", map[string]interface{}{"private": false}, wf, checkFail},
+		{"private", map[string]interface{}{"private": true}, wf, checkSkip},
+		{"unknown", nil, wf, checkSkip},
+		{"hosted", map[string]interface{}{"private": false}, map[string][]byte{"ci.yml": []byte("on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n")}, checkPass},
+	}
+	for _, tt := range tests {
+		if got := checkSelfHostedRunner(tt.wf, tt.repo); got.outcome != tt.want {
+			t.Errorf("%s: checkSelfHostedRunner() = %+v, want outcome %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckWorkflowRisks_SortedFiles(t *testing.T) {
+	t.Parallel()
+
+	body := []byte("on: push\njobs:\n  build:\n    runs-on: [self-hosted]\n")
+	wf := map[string][]byte{"c.yml": body, "a.yml": body, "b.yml": body}
+	for i := 0; i < 10; i++ {
+		got := checkSelfHostedRunner(wf, map[string]interface{}{"private": false})
+		if !strings.HasPrefix(got.detail, "a.yml: ") {
+			t.Fatalf("checkSelfHostedRunner() detail = %q, want a.yml first", got.detail)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/mod/semver"
+)
+
+// The allow: values of a Policy, from the widest update to the narrowest.
+const (
+	AllowMajor = "major"
+	AllowMinor = "minor"
+	AllowPatch = "patch"
+)
+
+// Policy limits the versions a pinner moves a dependency to. Ecosystem and
+// Name are globs in which * also matches /, so "hashicorp/*" and
+// "*/terraform-aws-*" both work; Name is the dependency as --report names it.
+type Policy struct {
+	Ecosystem      string   `yaml:"ecosystem"`       // e.g. "gha" or "terraform-*"; empty matches any
+	Name           string   `yaml:"name"`            // e.g. "actions/setup-node"; empty matches any
+	Allow          string   `yaml:"allow"`           // widest update from the current version: major (default), minor or patch
+	IgnoreVersions []string `yaml:"ignore_versions"` // versions never picked, as globs, e.g. "5.3.0" or "6.*"
+	MaxVersion     string   `yaml:"max_version"`     // highest version picked, e.g. "5.x", "4.2" or "3.1.4"
+	Freeze         bool     `yaml:"freeze"`          // leave the dependency exactly as it is
+}
+
+type policyError struct {
+	name string
+}
+
+func (e *policyError) Error() string {
+	return fmt.Sprintf("no release of %s satisfies its .ghat.yml policy", e.name)
+}
+
+type invalidPolicyError struct {
+	policy Policy
+	reason string
+}
+
+func (e *invalidPolicyError) Error() string {
+	return fmt.Sprintf("invalid .ghat.yml policy for %s %s: %s", e.policy.Ecosystem, e.policy.Name, e.reason)
+}
+
+// bareMajorRe matches a version that is only a major number, e.g. v4.
+var bareMajorRe = regexp.MustCompile(`^v?(\d+)$`)
+
+// validatePolicies rejects policies whose allow: or max_version: would
+// otherwise silently match nothing.
+func validatePolicies(policies []Policy) error {
+	for _, p := range policies {
+		switch p.Allow {
+		case "", AllowMajor, AllowMinor, AllowPatch:
+		default:
+			return &invalidPolicyError{policy: p, reason: fmt.Sprintf("allow %q is not major, minor or patch", p.Allow)}
+		}
+		if p.MaxVersion != "" && maxVersionLimit(p.MaxVersion) == "" {
+			return &invalidPolicyError{policy: p, reason: fmt.Sprintf("max_version %q is not a version", p.MaxVersion)}
+		}
+	}
+	return nil
+}
+
+// globMatch reports whether s matches glob, case-insensitively. An empty
+// glob matches anything.
+func globMatch(glob, s string) bool {
+	if glob == "" {
+		return true
+	}
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	ok, _ := regexp.MatchString(b.String(), s)
+	return ok
+}
+
+// policy returns the last .ghat.yml policy that matches a dependency, so a
+// repository's own .ghat.yml overrides ~/.ghat.yml, or nil if none does.
+func (f *Flags) policy(ecosystem, name string) *Policy {
+	var match *Policy
+	for i := range f.Policies {
+		if globMatch(f.Policies[i].Ecosystem, ecosystem) && globMatch(f.Policies[i].Name, name) {
+			match = &f.Policies[i]
+		}
+	}
+	return match
+}
+
+// frozen reports whether a policy freezes a dependency, logging that it is
+// being left alone.
+func (f *Flags) frozen(ecosystem, name string) bool {
+	if p := f.policy(ecosystem, name); p != nil && p.Freeze {
+		log.Info().Str("ecosystem", ecosystem).Str("name", name).Msg("frozen by .ghat.yml policy, leaving as is")
+		return true
+	}
+	return false
+}
+
+// constrains reports whether p narrows which version a pinner may pick.
+func (p *Policy) constrains() bool {
+	return p != nil && ((p.Allow != "" && p.Allow != AllowMajor) || len(p.IgnoreVersions) > 0 || p.MaxVersion != "")
+}
+
+// policyVersion is coerceSemver that also takes a bare major version, as
+// floating action tags (v4) and partial component versions (1) are written.
+func policyVersion(v string) string {
+	if c := coerceSemver(v); c != "" {
+		return c
+	}
+	if m := bareMajorRe.FindStringSubmatch(strings.TrimSpace(v)); m != nil {
+		return "v" + m[1] + ".0.0"
+	}
+	return ""
+}
+
+// maxVersionLimit turns a max_version into a semver prefix: 5.x and 5 give
+// v5, 4.2 gives v4.2. It returns "" if max is not a version.
+func maxVersionLimit(max string) string {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(max), "v"), ".")
+	for len(parts) > 0 && (parts[len(parts)-1] == "x" || parts[len(parts)-1] == "*") {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) == 0 || len(parts) > 3 {
+		return ""
+	}
+	limit := "v" + strings.Join(parts, ".")
+	if !semver.IsValid(limit) {
+		return ""
+	}
+	return limit
+}
+
+// withinMax reports whether v is no higher than max, comparing only as many
+// parts as max gives, so 5.x allows every 5.y.z.
+func withinMax(v, max string) bool {
+	limit := maxVersionLimit(max)
+	if limit == "" {
+		return false
+	}
+	switch strings.Count(limit, ".") {
+	case 0:
+		return semver.Compare(semver.Major(v), limit) <= 0
+	case 1:
+		return semver.Compare(semver.MajorMinor(v), limit) <= 0
+	}
+	return semver.Compare(v, limit) <= 0
+}
+
+// permits reports whether p lets a dependency on current move to candidate.
+// allow: only applies when current is a version; ignore_versions and
+// max_version always do.
+func (p *Policy) permits(current, candidate string) bool {
+	if !p.constrains() {
+		return true
+	}
+	v := policyVersion(candidate)
+	if v == "" {
+		return false
+	}
+	for _, glob := range p.IgnoreVersions {
+		if globMatch(strings.TrimPrefix(glob, "v"), strings.TrimPrefix(candidate, "v")) {
+			return false
+		}
+	}
+	if p.MaxVersion != "" && !withinMax(v, p.MaxVersion) {
+		return false
+	}
+	cur := policyVersion(current)
+	if cur == "" {
+		return true
+	}
+	switch p.Allow {
+	case AllowMinor:
+		return semver.Major(v) == semver.Major(cur)
+	case AllowPatch:
+		return semver.MajorMinor(v) == semver.MajorMinor(cur)
+	}
+	return true
+}
+
+// permitted returns the versions p lets current move to, keeping their order.
+func (p *Policy) permitted(current string, versions []string) []string {
+	var out []string
+	for _, v := range versions {
+		if p.permits(current, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// githubTagWithin returns the commit and name of the newest stable tag of
+// action that p lets current move to and, with a cooldown, whose commit is
+// at least days old.
+func githubTagWithin(action, token string, days uint, p *Policy, current string) (sha, tag string, err error) {
+	tagged, err := githubTags(action, token)
+	if err != nil {
+		return "", "", err
+	}
+	shas, names := tagCommits(tagged)
+	allowed := p.permitted(current, stableVersionsDesc(names))
+	if len(allowed) == 0 {
+		return "", "", &policyError{name: action}
+	}
+	if days == 0 {
+		return shas[allowed[0]], allowed[0], nil
+	}
+	tag, err = newestPublishedBefore(action, days, allowed, func(t string) (time.Time, error) {
+		return githubCommitDate(action, shas[t], token)
+	})
+	return shas[tag], tag, err
+}
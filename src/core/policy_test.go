@@ -0,0 +1,153 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	policyMinorSHA = "6666666666666666666666666666666666666666"
+	policyMajorSHA = "7777777777777777777777777777777777777777"
+)
+
+// fakePolicyGitHub serves org/setup, whose latest release is v3.0.0 and
+// whose tags also hold v2.5.0 and v2.4.0.
+func fakePolicyGitHub(t *testing.T) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/org/setup/releases/latest", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v3.0.0"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/org/setup/git/ref/tags/v3.0.0", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"object":{"sha":"%s","type":"commit"}}`, policyMajorSHA)
+	})
+	mux.HandleFunc("/api/v3/repos/org/setup/tags", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `[{"name":"v3.0.0","commit":{"sha":"%s"}},{"name":"v2.5.0","commit":{"sha":"%s"}},`+
+			`{"name":"v2.4.0","commit":{"sha":"%s"}}]`, policyMajorSHA, policyMinorSHA, policyMinorSHA)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL + "/api/v3")
+}
+
+func TestPolicy_Permits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		policy    *Policy
+		current   string
+		candidate string
+		want      bool
+	}{
+		{name: "no policy", current: "v1.0.0", candidate: "v9.0.0", want: true},
+		{name: "allow major", policy: &Policy{Allow: AllowMajor}, current: "v1.0.0", candidate: "v9.0.0", want: true},
+		{name: "allow minor, same major", policy: &Policy{Allow: AllowMinor}, current: "v4", candidate: "v4.3.1", want: true},
+		{name: "allow minor, next major", policy: &Policy{Allow: AllowMinor}, current: "v4.1.0", candidate: "v5.0.0"},
+		{name: "allow patch", policy: &Policy{Allow: AllowPatch}, current: "1.2.0", candidate: "1.2.9", want: true},
+		{name: "allow patch, next minor", policy: &Policy{Allow: AllowPatch}, current: "1.2.0", candidate: "1.3.0"},
+		{name: "allow without a current version", policy: &Policy{Allow: AllowPatch}, current: "main", candidate: "v2.0.0", want: true},
+		{name: "provider constraint", policy: &Policy{Allow: AllowMinor}, current: "~> 5.0", candidate: "5.80.0", want: true},
+		{name: "ignored", policy: &Policy{IgnoreVersions: []string{"5.3.0"}}, candidate: "v5.3.0"},
+		{name: "ignored glob", policy: &Policy{IgnoreVersions: []string{"v6.*"}}, candidate: "6.1.0"},
+		{name: "max major", policy: &Policy{MaxVersion: "5.x"}, candidate: "5.99.1", want: true},
+		{name: "over max major", policy: &Policy{MaxVersion: "5.x"}, candidate: "6.0.0"},
+		{name: "max minor", policy: &Policy{MaxVersion: "4.2"}, candidate: "v4.2.7", want: true},
+		{name: "over max patch", policy: &Policy{MaxVersion: "3.1.4"}, candidate: "3.1.5"},
+		{name: "not a version", policy: &Policy{MaxVersion: "5"}, candidate: "latest"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.permits(tt.current, tt.candidate); got != tt.want {
+			t.Errorf("%s: permits(%q, %q) = %v, want %v", tt.name, tt.current, tt.candidate, got, tt.want)
+		}
+	}
+}
+
+func TestFlags_Policy(t *testing.T) {
+	t.Parallel()
+
+	f := &Flags{Policies: []Policy{
+		{Name: "hashicorp/*", MaxVersion: "5"},
+		{Ecosystem: "terraform-*", Name: "hashicorp/aws", Freeze: true},
+		{Ecosystem: SourceGHA, Name: "actions/*", Allow: AllowMinor},
+	}}
+	tests := []struct {
+		ecosystem, name string
+		want            int // index into f.Policies, or -1
+	}{
+		{SourceProvider, "hashicorp/aws", 1},
+		{SourceProvider, "hashicorp/google", 0},
+		{SourceGHA, "Actions/cache/restore", 2},
+		{SourcePreCommit, "actions/cache", -1},
+	}
+	for _, tt := range tests {
+		got := f.policy(tt.ecosystem, tt.name)
+		if (tt.want < 0 && got != nil) || (tt.want >= 0 && got != &f.Policies[tt.want]) {
+			t.Errorf("policy(%q, %q) = %+v, want policy %d", tt.ecosystem, tt.name, got, tt.want)
+		}
+	}
+	if !f.frozen(SourceProvider, "hashicorp/aws") || f.frozen(SourceProvider, "hashicorp/google") {
+		t.Error("frozen() should only hold for hashicorp/aws")
+	}
+}
+
+func TestValidatePolicies(t *testing.T) {
+	t.Parallel()
+
+	if err := validatePolicies([]Policy{{Allow: AllowPatch, MaxVersion: "v2.x"}}); err != nil {
+		t.Errorf("validatePolicies() error = %v", err)
+	}
+	for _, p := range []Policy{{Allow: "minors"}, {MaxVersion: "five"}} {
+		if err := validatePolicies([]Policy{p}); err == nil {
+			t.Errorf("validatePolicies(%+v) should fail", p)
+		}
+	}
+}
+
+func TestLoadConfig_Policies(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	content := "policies:\n  - ecosystem: gha\n    name: actions/setup-node\n    allow: minor\n" +
+		"  - name: nginx\n    freeze: true\n    ignore_versions: [\"1.27.*\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, ".ghat.yml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := LoadConfig(dir).Policies
+	if len(got) != 2 || got[0].Allow != AllowMinor || !got[1].Freeze || got[1].IgnoreVersions[0] != "1.27.*" {
+		t.Errorf("Policies = %+v", got)
+	}
+}
+
+func TestUpdateGHA_Policy(t *testing.T) {
+	fakePolicyGitHub(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ci.yml")
+	content := "permissions: read-all\njobs:\n  test:\n    steps:\n" +
+		"      - uses: org/setup@v2.1.0\n      - uses: org/frozen@v1\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var days uint
+	f := &Flags{Days: &days, Silent: true, Policies: []Policy{
+		{Ecosystem: SourceGHA, Name: "org/setup", Allow: AllowMinor},
+		{Ecosystem: SourceGHA, Name: "org/frozen", Freeze: true},
+	}}
+	if err := f.UpdateGHA(file); err != nil {
+		t.Fatalf("UpdateGHA() error = %v", err)
+	}
+	got, _ := os.ReadFile(file)
+	if !strings.Contains(string(got), "org/setup@"+policyMinorSHA+" # v2.5.0") ||
+		!strings.Contains(string(got), "org/frozen@v1\n") {
+		t.Errorf("UpdateGHA() wrote\n%s", got)
+	}
+}
@@ -66,6 +66,14 @@ type revPin struct {
 // the set of repo URLs the line-parser recognised, so the caller can detect a
 // mismatch between yaml.Unmarshal and this parser.
 func rewritePreCommitRevs(data string, pins map[string]revPin) (string, map[string]bool) {
+	out, seen, _ := rewritePreCommitRevChanges(data, pins)
+	return out, seen
+}
+
+// rewritePreCommitRevChanges is rewritePreCommitRevs that also returns a
+// Change (without File) for every rev: line it rewrites.
+func rewritePreCommitRevChanges(data string, pins map[string]revPin) (string, map[string]bool, []Change) {
+	var changes []Change
 	lines := strings.Split(data, "\n")
 	seen := map[string]bool{}
 	var currentRepo string
@@ -106,11 +114,22 @@ func rewritePreCommitRevs(data string, pins map[string]revPin) (string, map[stri
 			continue
 		}
 
-		indent := line[:strings.Index(line, "rev:")]
+		revAt := strings.Index(line, "rev:")
+		indent := line[:revAt]
 		lines[i] = indent + "rev: " + p.sha + " # " + p.tag
+		if lines[i] != line {
+			oldRef := strings.Trim(strings.TrimSpace(strings.TrimPrefix(bare, "rev:")), `"'`)
+			sha, tag := parsePinnedRef(line[revAt+len("rev:"):])
+			name := currentRepo
+			if p.newURL != "" {
+				name = p.newURL
+			}
+			changes = append(changes, Change{Line: i + 1, Ecosystem: SourcePreCommit, Name: name,
+				OldRef: oldRef, NewRef: p.sha, Tag: p.tag, Mutation: isTagMutation(sha, tag, p.sha, p.tag)})
+		}
 	}
 
-	return strings.Join(lines, "\n"), seen
+	return strings.Join(lines, "\n"), seen, changes
 }
 
 func (f *Flags) UpdateHooks() error {
@@ -144,6 +163,7 @@ func (f *Flags) UpdateHooks() error {
 	// caller's git credential helpers — so self-hosted GitLab / Gitea /
 	// Bitbucket just work if `git clone` would.
 	pins := map[string]revPin{}
+	revs := currentRevs(data)
 
 	for _, item := range m.Repos {
 		if !strings.Contains(item.Repo, "://") {
@@ -158,37 +178,51 @@ func (f *Flags) UpdateHooks() error {
 			newURL = sub
 			repoURL = sub
 		}
+		if f.frozen(SourcePreCommit, repoURL) {
+			continue
+		}
+		policy := f.policy(SourcePreCommit, repoURL)
 
 		if strings.HasPrefix(repoURL, GitHubPrefix) {
 			// pre-commit accepts `https://github.com/org/repo.git` but the
 			// REST API does not — /repos/org/repo.git/tags is a 404.
 			action := strings.TrimSuffix(strings.TrimPrefix(repoURL, GitHubPrefix), ".git")
-			tag, err := GetLatestTag(action, f.GitHubToken)
+			sha, tag, err := f.latestGithubTag(action, SourcePreCommit, policy, revs[item.Repo])
 
 			if err != nil {
-				log.Info().Msgf("failed to find %s", item.Repo)
+				log.Info().Err(err).Msgf("failed to find %s", item.Repo)
 				continue
 			}
 
-			myTag := tag.(map[string]interface{})
-			commit := myTag["commit"].(map[string]interface{})
-			pins[item.Repo] = revPin{
-				sha:    commit["sha"].(string),
-				tag:    myTag["name"].(string),
-				newURL: newURL,
+			pin := revPin{sha: sha, tag: tag, newURL: newURL}
+			if _, err := f.observeTag(LedgerEntry{Kind: SourcePreCommit, Name: repoURL, Tag: pin.tag, SHA: pin.sha}); err != nil {
+				continue
 			}
+			pins[item.Repo] = pin
 			continue
 		}
 
-		sha, tag, err := getLatestTagViaGit(repoURL)
+		if days := f.cooldown(SourcePreCommit); days > 0 {
+			log.Warn().Str("repo", item.Repo).Uint("cooldown", days).
+				Msg("git ls-remote has no release dates to apply the cooldown to, leaving rev as is")
+			continue
+		}
+		sha, tag, err := getLatestTagViaGit(repoURL, policy, revs[item.Repo])
 		if err != nil {
 			log.Info().Err(err).Msgf("failed to resolve %s via git ls-remote", item.Repo)
 			continue
 		}
+		if _, err := f.observeTag(LedgerEntry{Kind: SourcePreCommit, Name: repoURL, Tag: tag, SHA: sha}); err != nil {
+			continue
+		}
 		pins[item.Repo] = revPin{sha: sha, tag: tag, newURL: newURL}
 	}
 
-	replacement, seen := rewritePreCommitRevs(string(data), pins)
+	replacement, seen, changes := rewritePreCommitRevChanges(string(data), pins)
+	for _, c := range changes {
+		c.File = *config
+		f.recordChange(c)
+	}
 	for repo := range pins {
 		if !seen[repo] {
 			log.Warn().Str("repo", repo).Msg("resolved pin but line-parser found no matching repo: entry — please report this")
@@ -209,24 +243,98 @@ func (f *Flags) UpdateHooks() error {
 	return nil
 }
 
+// currentRevs returns the version each repo in a pre-commit config is on:
+// its rev, or for a rev pinned to a SHA, the tag in the comment after it.
+func currentRevs(data []byte) map[string]string {
+	revs := map[string]string{}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return revs
+	}
+	repos := findMappingValue(&root, "repos")
+	if repos == nil || repos.Kind != yaml.SequenceNode {
+		return revs
+	}
+	for _, repo := range repos.Content {
+		rev := findMappingValue(repo, "rev")
+		if rev == nil {
+			continue
+		}
+		revs[scalarValue(repo, "repo")] = rev.Value
+		if _, tag := parsePinnedRef(rev.Value + " " + rev.LineComment); tag != "" {
+			revs[scalarValue(repo, "repo")] = tag
+		}
+	}
+	return revs
+}
+
 // getLatestTagViaGit shells out to `git ls-remote --tags`. Git is a hard
 // dependency of pre-commit anyway, and exec means we get the user's
 // credential helpers (osxkeychain / GCM / .netrc) for free — go-git would
 // need explicit auth plumbing per host. --sort is client-side (git ≥2.18).
-func getLatestTagViaGit(repoURL string) (sha, tag string, err error) {
-	// #nosec G204 — repoURL comes from a tracked .pre-commit-config.yaml the
-	// user is already trusting pre-commit to clone; passed as a discrete
-	// argv element, never through a shell.
-	cmd := exec.Command("git", "ls-remote", "--tags", "--sort=-version:refname", repoURL)
-	out, err := cmd.Output()
+// A policy that constrains the version limits it to the tags p lets current
+// move to.
+func getLatestTagViaGit(repoURL string, p *Policy, current string) (sha, tag string, err error) {
+	out, err := gitLsRemoteTags(repoURL)
+	if err != nil {
+		return "", "", err
+	}
+	return pickLsRemoteTag(repoURL, out, p, current)
+}
+
+type gitURLError struct {
+	url string
+}
+
+func (e *gitURLError) Error() string {
+	return fmt.Sprintf("%q is not a git repository URL", e.url)
+}
+
+// checkGitURL rejects a repository URL git would read as an option, such as
+// the --upload-pack=<command> a source of "git::--upload-pack=..." yields.
+func checkGitURL(repoURL string) error {
+	if repoURL == "" || strings.HasPrefix(repoURL, "-") {
+		return &gitURLError{url: repoURL}
+	}
+	return nil
+}
+
+// gitLsRemoteTags returns the output of `git ls-remote --tags` for repoURL,
+// highest version first.
+func gitLsRemoteTags(repoURL string) (string, error) {
+	if err := checkGitURL(repoURL); err != nil {
+		return "", err
+	}
+	out, err := recorded("git:ls-remote "+repoURL, func() (string, error) {
+		// #nosec G204 — repoURL comes from a tracked config file the user is
+		// already trusting their tools to clone; passed as a discrete argv
+		// element after --, never through a shell.
+		cmd := exec.Command("git", "ls-remote", "--tags", "--sort=-version:refname", "--", repoURL)
+		out, err := cmd.Output()
+		return string(out), err
+	})
 	if err != nil {
 		var ee *exec.ExitError
 		if errors.As(err, &ee) {
-			return "", "", fmt.Errorf("git ls-remote %s: %w: %s", repoURL, err, strings.TrimSpace(string(ee.Stderr)))
+			return "", fmt.Errorf("git ls-remote %s: %w: %s", repoURL, err, strings.TrimSpace(string(ee.Stderr)))
 		}
-		return "", "", fmt.Errorf("git ls-remote %s: %w", repoURL, err)
+		return "", fmt.Errorf("git ls-remote %s: %w", repoURL, err)
 	}
-	return parseLsRemoteTags(string(out))
+	return out, nil
+}
+
+// pickLsRemoteTag picks the newest tag in gitLsRemoteTags output that p lets
+// current move to.
+func pickLsRemoteTag(repoURL, out string, p *Policy, current string) (sha, tag string, err error) {
+	if !p.constrains() {
+		return parseLsRemoteTags(out)
+	}
+	order, shas := lsRemoteTags(out)
+	allowed := p.permitted(current, stableVersionsDesc(order))
+	if len(allowed) == 0 {
+		return "", "", &policyError{name: repoURL}
+	}
+	return shas[allowed[0]], allowed[0], nil
 }
 
 // parseLsRemoteTags picks the highest tag from `git ls-remote --tags
@@ -234,8 +342,18 @@ func getLatestTagViaGit(repoURL string) (sha, tag string, err error) {
 // tag object and once peeled (`^{}`) to the commit; the peeled SHA is the one
 // pre-commit needs. Lightweight tags appear once and already point at the
 // commit. Sort order puts the highest version first regardless of which form
-// arrives first, so build both maps and then read order[0].
+// arrives first, so lsRemoteTags builds both maps before order[0] is read.
 func parseLsRemoteTags(out string) (sha, tag string, err error) {
+	order, shas := lsRemoteTags(out)
+	if len(order) == 0 {
+		return "", "", fmt.Errorf("no tags")
+	}
+	return shas[order[0]], order[0], nil
+}
+
+// lsRemoteTags returns the tags in `git ls-remote --tags` output in the
+// order git listed them, and the commit each points at.
+func lsRemoteTags(out string) ([]string, map[string]string) {
 	peeled := map[string]string{}
 	direct := map[string]string{}
 	var order []string
@@ -257,15 +375,10 @@ func parseLsRemoteTags(out string) (sha, tag string, err error) {
 		order = append(order, name)
 	}
 
-	if len(order) == 0 {
-		return "", "", fmt.Errorf("no tags")
-	}
-
-	tag = order[0]
-	if s, ok := peeled[tag]; ok {
-		return s, tag, nil
+	for tag, sha := range peeled {
+		direct[tag] = sha
 	}
-	return direct[tag], tag, nil
+	return order, direct
 }
 
 func (f *Flags) GetHook() (*string, error) {
@@ -1,14 +1,11 @@
 package core
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
-	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
@@ -35,10 +32,12 @@ type ProviderVersionsResponse struct {
 type ProviderInfo struct {
 	Name           string
 	Source         string
+	Host           string // registry the provider comes from, e.g. registry.terraform.io
 	Namespace      string
 	Type           string
 	CurrentVersion string
 	LatestVersion  string
+	Constraint     string // version written back: LatestVersion, or with --keep-constraints the bumped constraint
 }
 
 // UpdateProviders updates all Terraform providers in the directory
@@ -48,18 +47,16 @@ func (f *Flags) UpdateProviders() error {
 		return err
 	}
 
-	for _, file := range terraform {
-		err = f.UpdateProvider(file)
-		if err != nil {
+	return eachFile(terraform, func(file string) error {
+		if err := f.UpdateProvider(file); err != nil {
 			if f.ContinueOnError {
 				log.Warn().Err(err).Str("file", file).Msg("Failed to update providers, continuing")
-				continue
+				return nil
 			}
 			return err
 		}
-	}
-
-	return nil
+		return nil
+	})
 }
 
 // UpdateProvider updates providers in a single Terraform file
@@ -75,7 +72,7 @@ func (f *Flags) UpdateProvider(file string) error {
 	}
 
 	root := inFile.Body()
-	modified := false
+	var updated []*ProviderInfo
 
 	// Find terraform blocks
 	for _, block := range root.Blocks() {
@@ -92,8 +89,23 @@ func (f *Flags) UpdateProvider(file string) error {
 							continue
 						}
 
-						// Get latest version
-						latestVersion, err := getLatestProviderVersion(provider.Namespace, provider.Type)
+						if f.frozen(SourceProvider, provider.Source) {
+							continue
+						}
+
+						// Get latest version, or the latest old enough for the cooldown,
+						// allowed by the provider's policy and, when keeping constraints,
+						// under their upper bounds
+						keep := f.KeepConstraints && hasVersionConstraint(provider.CurrentVersion)
+						var bound string
+						if keep {
+							bound = upperBounds(provider.CurrentVersion)
+						}
+						latestVersion, err := getLatestProviderVersion(provider.Host, provider.Namespace, provider.Type)
+						policy := f.policy(SourceProvider, provider.Source)
+						if days := f.cooldown(SourceProvider); days > 0 || policy.constrains() || bound != "" {
+							latestVersion, err = providerVersionWithin(provider.Host, provider.Namespace, provider.Type, days, policy, provider.CurrentVersion, bound)
+						}
 						if err != nil {
 							log.Warn().Err(err).
 								Str("provider", provider.Source).
@@ -102,13 +114,24 @@ func (f *Flags) UpdateProvider(file string) error {
 						}
 
 						provider.LatestVersion = latestVersion
+						provider.Constraint = latestVersion
+						needsUpdate := shouldUpdateProvider(provider)
+						if keep {
+							provider.Constraint, err = bumpConstraints(provider.CurrentVersion, latestVersion)
+							if err != nil {
+								log.Warn().Err(err).Str("provider", provider.Source).Msg("Failed to keep version constraint")
+								continue
+							}
+							needsUpdate = !sameConstraints(provider.Constraint, provider.CurrentVersion)
+						}
 
 						// Check if update is needed
-						if shouldUpdateProvider(provider) {
+						if needsUpdate {
 							log.Info().
 								Str("provider", provider.Source).
 								Str("current", provider.CurrentVersion).
 								Str("latest", provider.LatestVersion).
+								Str("constraint", provider.Constraint).
 								Msg("Updating provider")
 
 							// Update the version in the HCL
@@ -117,7 +140,12 @@ func (f *Flags) UpdateProvider(file string) error {
 								log.Warn().Err(err).Str("provider", name).Msg("Failed to update version")
 								continue
 							}
-							modified = true
+							updated = append(updated, provider)
+							f.recordChange(Change{
+								File: file, Line: providerLine(string(src), name), Ecosystem: SourceProvider,
+								Name: provider.Source, OldRef: provider.CurrentVersion, NewRef: provider.Constraint,
+								Tag: provider.LatestVersion,
+							})
 						} else {
 							log.Info().
 								Str("provider", provider.Source).
@@ -130,7 +158,7 @@ func (f *Flags) UpdateProvider(file string) error {
 		}
 	}
 
-	if !modified {
+	if len(updated) == 0 {
 		log.Info().Str("file", file).Msg("No provider updates needed")
 		return nil
 	}
@@ -147,13 +175,26 @@ func (f *Flags) UpdateProvider(file string) error {
 		log.Info().Str("file", file).Msg("Provider versions updated")
 	}
 
-	return nil
+	return f.updateTerraformLock(filepath.Dir(file), updated)
+}
+
+// providerLine returns the 1-based line of the `name = {` entry in a
+// required_providers block, or 0 if it cannot be found.
+func providerLine(src, name string) int {
+	re := regexp.MustCompile(`^\s*"?` + regexp.QuoteMeta(name) + `"?\s*=`)
+	for i, line := range strings.Split(src, "\n") {
+		if re.MatchString(line) {
+			return i + 1
+		}
+	}
+	return 0
 }
 
 // parseProviderBlock extracts provider information from an HCL attribute
 func parseProviderBlock(name string, attr *hclwrite.Attribute) (*ProviderInfo, error) {
 	provider := &ProviderInfo{
 		Name: name,
+		Host: defaultProviderHost,
 	}
 
 	// Parse the provider configuration object
@@ -169,6 +210,7 @@ func parseProviderBlock(name string, attr *hclwrite.Attribute) (*ProviderInfo, e
 			provider.Namespace = parts[0]
 			provider.Type = parts[1]
 		} else if len(parts) == 3 {
+			provider.Host = strings.ToLower(parts[0])
 			provider.Namespace = parts[1]
 			provider.Type = parts[2]
 		}
@@ -235,32 +277,35 @@ func extractPattern(content, pattern string) []string {
 // stable version of a provider. namespace is e.g. "hashicorp", providerType
 // is e.g. "aws".
 func GetLatestProviderVersion(namespace, providerType string) (string, error) {
-	return getLatestProviderVersion(namespace, providerType)
+	return getLatestProviderVersion(defaultProviderHost, namespace, providerType)
 }
 
-// getLatestProviderVersion queries the Terraform Registry API
-func getLatestProviderVersion(namespace, providerType string) (string, error) {
-	url := fmt.Sprintf("https://registry.terraform.io/v1/providers/%s/%s/versions", namespace, providerType)
+// getLatestProviderVersion queries the provider registry on host, e.g.
+// registry.terraform.io or registry.opentofu.org
+func getLatestProviderVersion(host, namespace, providerType string) (string, error) {
+	return resolve("terraform-provider "+host+"/"+namespace+"/"+providerType, func() (string, error) {
+		return fetchLatestProviderVersion(host, namespace, providerType)
+	})
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("failed to query registry: %w", err)
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
+// providerVersions reads a provider's versions list from the registry on
+// host, found through service discovery.
+func providerVersions(host, namespace, providerType string) (ProviderVersionsResponse, error) {
+	return resolve("terraform-provider-versions "+host+"/"+namespace+"/"+providerType, func() (ProviderVersionsResponse, error) {
+		var versionsResp ProviderVersionsResponse
+		base, err := registryService(host, serviceProviders)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to close response body")
+			return versionsResp, err
 		}
-	}(resp.Body)
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("registry returned status %d", resp.StatusCode)
-	}
+		err = registryGetJSON(host, base+namespace+"/"+providerType+"/versions", &versionsResp)
+		return versionsResp, err
+	})
+}
 
-	var versionsResp ProviderVersionsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&versionsResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+func fetchLatestProviderVersion(host, namespace, providerType string) (string, error) {
+	versionsResp, err := providerVersions(host, namespace, providerType)
+	if err != nil {
+		return "", err
 	}
 
 	if len(versionsResp.Versions) == 0 {
@@ -340,13 +385,14 @@ func hasVersionConstraint(version string) bool {
 	return false
 }
 
-// updateProviderVersion updates the version attribute in the provider block
+// updateProviderVersion sets the version attribute in the provider block to
+// provider.Constraint
 func updateProviderVersion(body *hclwrite.Body, providerName string, provider *ProviderInfo) error {
 	// Build new provider configuration as HCL source
 	newConfig := fmt.Sprintf(`%s = {
     source  = %q
     version = %q
-  }`, providerName, provider.Source, provider.LatestVersion)
+  }`, providerName, provider.Source, provider.Constraint)
 
 	// Parse it as a complete attribute
 	parsed, diags := hclwrite.ParseConfig([]byte(newConfig), "", hcl.Pos{})
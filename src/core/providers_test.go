@@ -38,7 +38,7 @@ func TestGetLatestProviderVersion(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			version, err := getLatestProviderVersion(tt.namespace, tt.provider)
+			version, err := getLatestProviderVersion(defaultProviderHost, tt.namespace, tt.provider)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getLatestProviderVersion() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -16,14 +16,22 @@ type Registry struct {
 }
 
 const (
-	registryBaseURL = "https://registry.terraform.io/v1/modules/"
-	successStatus   = 200
-	defaultTimeout  = 30 * time.Second
+	successStatus  = 200
+	defaultTimeout = 30 * time.Second
 )
 
+// IsRegistryModule reports whether module, [host/]namespace/name/provider,
+// is on its registry, registry.terraform.io when no host is given.
 func (myRegistry *Registry) IsRegistryModule(module string) (bool, error) {
-	module = url.PathEscape(module)
-	urlBuilt := registryBaseURL + module + "/versions"
+	host, path, _, err := registrySource(module)
+	if err != nil {
+		return false, err
+	}
+	base, err := registryService(host, serviceModules)
+	if err != nil {
+		return false, err
+	}
+	urlBuilt := base + url.PathEscape(path) + "/versions"
 	result, err := IsOK(urlBuilt)
 
 	myRegistry.Registry = result
@@ -58,7 +66,7 @@ func IsOK(rawURL string) (bool, error) {
 		return false, &requestFailedError{err: err}
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := lookupClient(defaultTimeout).Do(req)
 
 	if err != nil {
 		return false, &httpClientError{err: err}
@@ -95,17 +103,21 @@ func (myRegistry *Registry) GetLatest(module string) (*string, error) {
 	}
 
 	if found {
+		host, path, _, _ := registrySource(module)
+		base, err := registryService(host, serviceModules)
+		if err != nil {
+			return nil, &registryModuleError{module, err}
+		}
+
 		// Add URL sanitization
-		urlBuilt, err := url.JoinPath(registryBaseURL, url.PathEscape(module))
+		urlBuilt, err := url.JoinPath(base, url.PathEscape(path))
 
 		if err != nil {
 			return nil, &urlJoinError{err: err}
 		}
 
 		// Add timeout to prevent hanging requests
-		client := &http.Client{
-			Timeout: defaultTimeout,
-		}
+		client := lookupClient(defaultTimeout)
 
 		resp, err := client.Get(urlBuilt)
 
@@ -150,3 +162,59 @@ func (myRegistry *Registry) GetLatest(module string) (*string, error) {
 
 	return &myRegistry.LatestVersion, nil
 }
+
+// registryModuleVersions is the registry's versions document for a module.
+type registryModuleVersions struct {
+	Modules []struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	} `json:"modules"`
+}
+
+// registryModuleVersion returns the newest stable version of a module, e.g.
+// terraform-aws-modules/vpc/aws, on the registry at host that p lets current
+// move to, that meets bound if set and, with a cooldown, that was published
+// at least days ago.
+func registryModuleVersion(host, module string, days uint, p *Policy, current, bound string) (string, error) {
+	base, err := registryService(host, serviceModules)
+	if err != nil {
+		return "", err
+	}
+	doc, err := resolve("terraform-module-versions "+host+"/"+module, func() (registryModuleVersions, error) {
+		var doc registryModuleVersions
+		err := registryGetJSON(host, base+module+"/versions", &doc)
+		return doc, err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var versions []string
+	for _, m := range doc.Modules {
+		for _, v := range m.Versions {
+			if ok, _ := satisfiesConstraints(v.Version, bound); bound == "" || ok {
+				versions = append(versions, v.Version)
+			}
+		}
+	}
+	allowed := p.permitted(current, stableVersionsDesc(versions))
+	if len(allowed) == 0 {
+		if p.constrains() {
+			return "", &policyError{name: module}
+		}
+		return "", &boundError{name: module, bound: bound}
+	}
+	if days == 0 {
+		return allowed[0], nil
+	}
+	return newestPublishedBefore(module, days, allowed, func(version string) (time.Time, error) {
+		return resolve("terraform-module-published "+host+"/"+module+" "+version, func() (time.Time, error) {
+			var v struct {
+				PublishedAt time.Time `json:"published_at"`
+			}
+			err := registryGetJSON(host, base+module+"/"+version, &v)
+			return v.PublishedAt, err
+		})
+	})
+}
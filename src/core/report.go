@@ -0,0 +1,130 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ReportJSON is the only --report format the pinners emit.
+const ReportJSON = "json"
+
+const (
+	SourceSubmodule = "submodule"
+	SourceProvider  = "terraform-provider"
+	SourceImage     = "image" // container:/services: images inside GHA workflows
+)
+
+// Change is one dependency reference rewritten (or, with --dry-run, that
+// would be rewritten) by a pinner.
+type Change struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	OldRef    string `json:"old_ref"`
+	NewRef    string `json:"new_ref"`
+	Tag       string `json:"tag,omitempty"`
+	Mutation  bool   `json:"mutation"`
+	// Breaking lists the inputs the step passes that stop working at NewRef.
+	Breaking []string `json:"breaking,omitempty"`
+}
+
+// Report is the --report json document.
+type Report struct {
+	DryRun  bool     `json:"dry_run"`
+	Changes []Change `json:"changes"`
+}
+
+type reportFormatError struct {
+	format string
+}
+
+func (e *reportFormatError) Error() string {
+	return fmt.Sprintf("unsupported --report format %q (supported: %s)", e.format, ReportJSON)
+}
+
+// changesMu guards Flags.Changes; updaters may record from several goroutines.
+var changesMu sync.Mutex
+
+func (f *Flags) recordChange(c Change) {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+	f.Changes = append(f.Changes, c)
+}
+
+// recordReplace records c once for every line of content that contains old,
+// mirroring the strings.ReplaceAll the caller is about to make.
+func (f *Flags) recordReplace(content, old string, c Change) {
+	for i, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, old) {
+			c.Line = i + 1
+			f.recordChange(c)
+		}
+	}
+}
+
+// recordImageChange records an image being pinned to digest, unless it is
+// already pinned to that digest.
+func (f *Flags) recordImageChange(file, content, ecosystem, imageStr string, ref ImageReference, digest string, mutation bool) {
+	old := ref.Tag
+	if at := strings.Index(imageStr, "@"); at >= 0 {
+		old = imageStr[at+1:]
+		if old == digest {
+			return
+		}
+	}
+	f.recordReplace(content, imageStr, Change{
+		File: file, Ecosystem: ecosystem, Name: imageDisplayName(ref),
+		OldRef: old, NewRef: digest, Tag: ref.Tag, Mutation: mutation,
+	})
+}
+
+// reportToStdout reports whether the JSON report owns stdout, in which case
+// diffs and progress lines must stay off it.
+func (f *Flags) reportToStdout() bool {
+	return f.Report != "" && f.ReportFile == ""
+}
+
+// validateReport rejects unknown --report formats before anything is rewritten.
+func (f *Flags) validateReport() error {
+	if f.Report != "" && f.Report != ReportJSON {
+		return &reportFormatError{format: f.Report}
+	}
+	return nil
+}
+
+// WriteReport writes the recorded changes as JSON to f.ReportFile, or to
+// stdout when no file is set. It is a no-op unless --report was given.
+func (f *Flags) WriteReport() error {
+	if f.Report == "" {
+		return nil
+	}
+	if err := f.validateReport(); err != nil {
+		return err
+	}
+
+	changesMu.Lock()
+	report := Report{DryRun: f.DryRun, Changes: append([]Change{}, f.Changes...)}
+	changesMu.Unlock()
+
+	var w io.Writer = os.Stdout
+	if f.ReportFile != "" {
+		file, err := os.Create(f.ReportFile)
+		if err != nil {
+			return fmt.Errorf("failed to create report %s: %w", f.ReportFile, err)
+		}
+		defer file.Close() //nolint:errcheck
+		w = file
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
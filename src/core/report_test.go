@@ -0,0 +1,99 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFlags_ValidateReport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"", false},
+		{ReportJSON, false},
+		{"sarif", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+			f := &Flags{Report: tt.format}
+			err := f.validateReport()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var formatErr *reportFormatError
+			if tt.wantErr && !errors.As(err, &formatErr) {
+				t.Errorf("validateReport() error type = %T, want *reportFormatError", err)
+			}
+		})
+	}
+}
+
+func TestFlags_RecordReplace(t *testing.T) {
+	t.Parallel()
+
+	content := "a: foo@v1\nb: bar\nc: foo@v1\n"
+	f := &Flags{}
+	f.recordReplace(content, "foo@v1", Change{File: "x.yml", Name: "foo"})
+
+	if len(f.Changes) != 2 {
+		t.Fatalf("recordReplace() recorded %d changes, want 2: %+v", len(f.Changes), f.Changes)
+	}
+	if f.Changes[0].Line != 1 || f.Changes[1].Line != 3 {
+		t.Errorf("recordReplace() lines = %d, %d, want 1, 3", f.Changes[0].Line, f.Changes[1].Line)
+	}
+}
+
+func TestFlags_RecordImageChange(t *testing.T) {
+	t.Parallel()
+
+	const digest = "sha256:abc"
+	ref := ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.27"}
+
+	f := &Flags{}
+	f.recordImageChange("Dockerfile", "FROM nginx:1.27@"+digest+"\n", SourceDockerfile, "nginx:1.27@"+digest, ref, digest, false)
+	if len(f.Changes) != 0 {
+		t.Errorf("recordImageChange() recorded an already pinned image: %+v", f.Changes)
+	}
+
+	f.recordImageChange("Dockerfile", "FROM nginx:1.27\n", SourceDockerfile, "nginx:1.27", ref, digest, false)
+	if len(f.Changes) != 1 {
+		t.Fatalf("recordImageChange() recorded %d changes, want 1", len(f.Changes))
+	}
+	if got := f.Changes[0]; got.OldRef != "1.27" || got.NewRef != digest || got.Line != 1 {
+		t.Errorf("recordImageChange() = %+v", got)
+	}
+}
+
+func TestFlags_WriteReport(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "report.json")
+	f := &Flags{Report: ReportJSON, ReportFile: file, DryRun: true}
+	f.recordChange(Change{File: "ci.yml", Line: 4, Ecosystem: SourceGHA, Name: "actions/checkout",
+		OldRef: "v4", NewRef: "0123456789abcdef0123456789abcdef01234567", Tag: "v4.2.2"})
+
+	if err := f.WriteReport(); err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report is not valid JSON: %v\n%s", err, data)
+	}
+	if !got.DryRun || len(got.Changes) != 1 || !reflect.DeepEqual(got.Changes[0], f.Changes[0]) {
+		t.Errorf("WriteReport() = %+v, want %+v", got, f.Changes)
+	}
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// resolverWorkers bounds how many files each pinner processes at once, and so
+// how many upstream lookups are in flight.
+const resolverWorkers = 8
+
+// Resolver memoises upstream lookups — latest releases, tag SHAs, image
+// digests, registry versions — so a run that sees actions/checkout in sixty
+// workflows, or in a workflow and a pre-commit config, asks once. Callers of a
+// key already in flight wait for that lookup rather than starting their own,
+// and a rate-limited lookup pauses every other lookup until the limit resets.
+type Resolver struct {
+	mu      sync.Mutex
+	calls   map[string]*resolverCall
+	paused  time.Time
+	fetches atomic.Int64
+}
+
+type resolverCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// NewResolver returns an empty resolver.
+func NewResolver() *Resolver {
+	return &Resolver{calls: map[string]*resolverCall{}}
+}
+
+// _resolver is process-wide, like _httpClient, so deep helpers such as
+// resolveTagSHA share it without a Flags. Nil (the LSP, and helpers called
+// outside Action) resolves every lookup afresh.
+var _resolver atomic.Pointer[Resolver]
+
+// SetResolver installs r for every subsequent lookup; nil turns memoisation off.
+func SetResolver(r *Resolver) {
+	_resolver.Store(r)
+}
+
+// useResolver installs a resolver for the process unless one already is, so
+// org's per-repo runs share one.
+func useResolver() {
+	_resolver.CompareAndSwap(nil, NewResolver())
+}
+
+// Fetches reports how many lookups actually went upstream.
+func (r *Resolver) Fetches() int64 {
+	return r.fetches.Load()
+}
+
+// resolve returns the memoised result for key, calling fetch at most once per
+// key while it succeeds. Failures are not memoised, so a later caller retries.
+func resolve[T any](key string, fetch func() (T, error)) (T, error) {
+	r := _resolver.Load()
+	if r == nil {
+		return fetch()
+	}
+	v, err := r.do(key, func() (interface{}, error) { return fetch() })
+	t, _ := v.(T)
+	return t, err
+}
+
+func (r *Resolver) do(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	r.mu.Lock()
+	if c, ok := r.calls[key]; ok {
+		r.mu.Unlock()
+		<-c.done
+		return c.val, c.err
+	}
+	c := &resolverCall{done: make(chan struct{})}
+	r.calls[key] = c
+	r.mu.Unlock()
+
+	c.val, c.err = r.fetch(key, fetch)
+	if c.err != nil {
+		r.mu.Lock()
+		delete(r.calls, key)
+		r.mu.Unlock()
+	}
+	close(c.done)
+	return c.val, c.err
+}
+
+// fetch calls fetch, retrying a RateLimitError after the limit resets (or
+// with exponential backoff when the reset time is unknown) and holding every
+// other lookup back meanwhile.
+func (r *Resolver) fetch(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		r.waitForRateLimit()
+		r.fetches.Add(1)
+		v, err := fetch()
+
+		var rle *RateLimitError
+		if err == nil || !errors.As(err, &rle) || attempt == maxRetries {
+			return v, err
+		}
+
+		wait := time.Until(rle.ResetTime)
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		log.Warn().Str("lookup", key).Int("attempt", attempt+1).Dur("wait", wait).Msg("Rate limited, pausing lookups")
+
+		r.mu.Lock()
+		if until := time.Now().Add(wait); until.After(r.paused) {
+			r.paused = until
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *Resolver) waitForRateLimit() {
+	r.mu.Lock()
+	wait := time.Until(r.paused)
+	r.mu.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// eachFile runs update over files, resolverWorkers at a time, and returns the
+// first error in file order. As with the serial loops it replaces, no file is
+// started once one has failed; callers that continue on error return nil.
+func eachFile(files []string, update func(string) error) error {
+	errs := make([]error, len(files))
+	sem := make(chan struct{}, resolverWorkers)
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+
+	for i, file := range files {
+		sem <- struct{}{}
+		if failed.Load() {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(idx int, file string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if errs[idx] = update(file); errs[idx] != nil {
+				failed.Store(true)
+			}
+		}(i, file)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
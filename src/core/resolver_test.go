@@ -0,0 +1,149 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingTagServer serves actions/checkout@v4.2.2 as includeSHA and counts
+// the requests each path receives.
+func countingTagServer(t *testing.T) map[string]*atomic.Int64 {
+	t.Helper()
+	hits := map[string]*atomic.Int64{
+		"/api/v3/repos/actions/checkout/releases/latest":     {},
+		"/api/v3/repos/actions/checkout/git/ref/tags/v4.2.2": {},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/actions/checkout/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path].Add(1)
+		_, _ = w.Write([]byte(`{"tag_name":"v4.2.2"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/actions/checkout/git/ref/tags/v4.2.2", func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path].Add(1)
+		time.Sleep(10 * time.Millisecond) // keep the lookup in flight for concurrent callers
+		_, _ = fmt.Fprintf(w, `{"object":{"sha":"%s","type":"commit"}}`, includeSHA)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL + "/api/v3")
+	return hits
+}
+
+func TestResolver_Deduplicates(t *testing.T) {
+	hits := countingTagServer(t)
+	t.Cleanup(func() { SetResolver(nil) })
+	SetResolver(NewResolver())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sha, err := resolveTagSHA("actions/checkout", "v4.2.2", ""); sha != includeSHA || err != nil {
+				t.Errorf("resolveTagSHA() = (%q, %v)", sha, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := hits["/api/v3/repos/actions/checkout/git/ref/tags/v4.2.2"].Load(); n != 1 {
+		t.Errorf("20 concurrent resolveTagSHA() calls made %d requests, want 1", n)
+	}
+}
+
+func TestResolver_RateLimit(t *testing.T) {
+	r := NewResolver()
+	calls := 0
+	v, err := r.do("k", func() (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, &RateLimitError{ResetTime: time.Now().Add(20 * time.Millisecond)}
+		}
+		return "ok", nil
+	})
+	if v != "ok" || err != nil || r.Fetches() != 2 {
+		t.Errorf("do() after a rate limit = (%v, %v) in %d fetches, want ok in 2", v, err, r.Fetches())
+	}
+}
+
+func TestResolver_ErrorsNotMemoised(t *testing.T) {
+	t.Parallel()
+
+	r := NewResolver()
+	fail := errors.New("registry unavailable")
+	if _, err := r.do("k", func() (interface{}, error) { return nil, fail }); !errors.Is(err, fail) {
+		t.Fatalf("do() error = %v, want %v", err, fail)
+	}
+	if v, err := r.do("k", func() (interface{}, error) { return "ok", nil }); v != "ok" || err != nil {
+		t.Errorf("do() after a failure = (%v, %v), want a fresh lookup", v, err)
+	}
+	if v, _ := r.do("k", func() (interface{}, error) { return "stale", nil }); v != "ok" {
+		t.Errorf("do() = %v, want the memoised ok", v)
+	}
+}
+
+func TestUpdateGHAS_SharedResolver(t *testing.T) {
+	hits := countingTagServer(t)
+	t.Cleanup(func() { SetResolver(nil) })
+	SetResolver(NewResolver())
+
+	dir := filepath.Join(t.TempDir(), ".github", "workflows")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var days uint
+	f := &Flags{Days: &days, Silent: true}
+	for i := 0; i < 12; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("ci%d.yml", i))
+		content := "permissions: read-all\njobs:\n  b:\n    steps:\n      - uses: actions/checkout@v4\n"
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		f.Entries = append(f.Entries, file)
+	}
+
+	if err := f.UpdateGHAS(); err != nil {
+		t.Fatalf("UpdateGHAS() error = %v", err)
+	}
+	for path, n := range hits {
+		if n.Load() != 1 {
+			t.Errorf("%s requested %d times across 12 workflows, want 1", path, n.Load())
+		}
+	}
+	for _, file := range f.Entries {
+		got, _ := os.ReadFile(file)
+		if !strings.Contains(string(got), "actions/checkout@"+includeSHA+" # v4.2.2") {
+			t.Errorf("%s not pinned:\n%s", file, got)
+		}
+	}
+}
+
+func TestEachFile(t *testing.T) {
+	t.Parallel()
+
+	files := []string{"a", "b", "c"}
+	var seen atomic.Int64
+	err := eachFile(files, func(file string) error {
+		seen.Add(1)
+		if file != "a" {
+			return fmt.Errorf("failed %s", file)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "failed b" {
+		t.Errorf("eachFile() error = %v, want the first failure in file order", err)
+	}
+	if seen.Load() < 2 {
+		t.Errorf("eachFile() ran %d files, want at least 2", seen.Load())
+	}
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jameswoolfenden/ghat/src/version"
+)
+
+// Output formats for commands that report findings.
+const (
+	FormatText  = "text"
+	FormatSARIF = "sarif"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	ghatInfoURI  = "https://github.com/JamesWoolfenden/ghat"
+)
+
+// sarifRuleInfo describes each rule id in the SARIF tool.driver.rules table.
+var sarifRuleInfo = map[string]struct {
+	name, description, level string
+}{
+	RulePin:              {"UnpinnedDependency", "Dependency is not pinned to an immutable SHA or digest", LevelWarning},
+	RulePermissions:      {"WorkflowPermissions", "Workflow GITHUB_TOKEN permissions are undeclared or write-all", LevelWarning},
+	RuleDangerousTrigger: {"DangerousTrigger", "Untrusted input can reach a privileged workflow", LevelError},
+	RuleUnpinnedInstall:  {"UnpinnedInstall", "Script installs a tool from a moving target", LevelWarning},
+	RuleAuditRisk:        {"AuditRisk", "Dependency failed ghat audit supply-chain checks", LevelError},
+	RuleAuditStale:       {"AuditStale", "Dependency looks unmaintained", LevelWarning},
+	RuleImpostorCommit:   {"ImpostorCommit", "Pinned SHA is not on any branch or tag of the named repository", LevelError},
+	RulePinComment:       {"PinCommentMismatch", "Pinned SHA does not match the tag in its trailing comment", LevelError},
+	RuleArtifactPoison:   {"ArtifactPoisoning", "A workflow_run job unpacks the triggering run's artifacts into its workspace", LevelError},
+	RuleCachePoison:      {"CachePoisoning", "A privileged workflow restores a cache keyed on values an outsider controls", LevelError},
+	RuleSelfHostedRunner: {"SelfHostedRunner", "Outsiders can run code on a self-hosted runner", LevelWarning},
+	RuleActionInputs:     {"ActionInputs", "Step inputs do not match the action.yml of the pinned version", LevelWarning},
+	RuleRetiredRuntime:   {"RetiredRuntime", "Pinned action version runs on a retired Node runtime", LevelWarning},
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string        `json:"id"`
+	Name                 string        `json:"name"`
+	ShortDescription     sarifMessage  `json:"shortDescription"`
+	DefaultConfiguration sarifRuleConf `json:"defaultConfiguration"`
+}
+
+type sarifRuleConf struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId"`
+	RuleIndex    int                `json:"ruleIndex"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type unknownFormatError struct {
+	format string
+}
+
+func (e *unknownFormatError) Error() string {
+	return fmt.Sprintf("unknown output format %q (valid: %s, %s)", e.format, FormatText, FormatSARIF)
+}
+
+// validateFormat rejects unknown --format values before any work is done.
+func validateFormat(format string) error {
+	switch format {
+	case "", FormatText, FormatSARIF:
+		return nil
+	}
+	return &unknownFormatError{format: format}
+}
+
+// WriteSARIF writes findings as a SARIF 2.1.0 log with a single ghat run.
+// File paths are made relative to root so code scanning can map them onto
+// the checkout (%SRCROOT%).
+func WriteSARIF(w io.Writer, root string, findings []Finding) error {
+	var ids []string
+	seen := map[string]bool{}
+	for _, f := range findings {
+		if !seen[f.Rule] {
+			seen[f.Rule] = true
+			ids = append(ids, f.Rule)
+		}
+	}
+	sort.Strings(ids)
+
+	index := map[string]int{}
+	rules := make([]sarifRule, 0, len(ids))
+	for i, id := range ids {
+		index[id] = i
+		info := sarifRuleInfo[id]
+		rules = append(rules, sarifRule{
+			ID:                   id,
+			Name:                 info.name,
+			ShortDescription:     sarifMessage{Text: info.description},
+			DefaultConfiguration: sarifRuleConf{Level: info.level},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		line := f.Line
+		if line < 1 {
+			line = 1
+		}
+		r := sarifResult{
+			RuleID:    f.Rule,
+			RuleIndex: index[f.Rule],
+			Level:     f.Level,
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: relSlashPath(root, f.File), URIBaseID: "%SRCROOT%"},
+				Region:           sarifRegion{StartLine: line},
+			}}},
+		}
+		if f.Suppressed {
+			r.Suppressions = []sarifSuppression{{Kind: "inSource", Justification: f.SuppressReason}}
+		}
+		results = append(results, r)
+	}
+
+	doc := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name: "ghat", Version: version.Version, InformationURI: ghatInfoURI, Rules: rules,
+			}},
+			Results: results,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write SARIF: %w", err)
+	}
+	return nil
+}
+
+// relSlashPath returns file relative to root, slash-separated.
+func relSlashPath(root, file string) string {
+	absRoot, err1 := filepath.Abs(root)
+	absFile, err2 := filepath.Abs(file)
+	if err1 == nil && err2 == nil {
+		if rel, err := filepath.Rel(absRoot, absFile); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	}
+	return filepath.ToSlash(file)
+}
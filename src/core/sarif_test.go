@@ -0,0 +1,63 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	findings := []Finding{
+		{Rule: RulePin, Level: LevelWarning, File: filepath.Join(root, ".github", "workflows", "ci.yml"), Line: 7,
+			Message: "actions/checkout@v4 is not pinned to an immutable SHA", Suppressed: true, SuppressReason: "trusted"},
+		{Rule: RuleAuditRisk, Level: LevelError, File: filepath.Join(root, "go.mod"), Line: 0, Message: "risky"},
+		{Rule: RulePin, Level: LevelWarning, File: filepath.Join(root, "Dockerfile"), Line: 1, Message: "golang:1.22"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, root, findings); err != nil {
+		t.Fatalf("WriteSARIF() error = %v", err)
+	}
+
+	var got sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.Version != sarifVersion || len(got.Runs) != 1 {
+		t.Fatalf("WriteSARIF() version = %q, runs = %d", got.Version, len(got.Runs))
+	}
+
+	run := got.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != RuleAuditRisk || run.Tool.Driver.Rules[1].ID != RulePin {
+		t.Errorf("rules = %+v, want [%s %s]", run.Tool.Driver.Rules, RuleAuditRisk, RulePin)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("results = %d, want 3", len(run.Results))
+	}
+
+	first := run.Results[0]
+	loc := first.Locations[0].PhysicalLocation
+	if first.RuleIndex != 1 || loc.ArtifactLocation.URI != ".github/workflows/ci.yml" || loc.Region.StartLine != 7 {
+		t.Errorf("result 0 = %+v", first)
+	}
+	if len(first.Suppressions) != 1 || first.Suppressions[0].Kind != "inSource" || first.Suppressions[0].Justification != "trusted" {
+		t.Errorf("result 0 suppressions = %+v", first.Suppressions)
+	}
+	if second := run.Results[1]; second.Locations[0].PhysicalLocation.Region.StartLine != 1 || len(second.Suppressions) != 0 {
+		t.Errorf("result 1 = %+v, want startLine 1 and no suppressions", second)
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	t.Parallel()
+
+	for format, wantErr := range map[string]bool{"": false, FormatText: false, FormatSARIF: false, "xml": true} {
+		if err := validateFormat(format); (err != nil) != wantErr {
+			t.Errorf("validateFormat(%q) error = %v, wantErr %v", format, err, wantErr)
+		}
+	}
+}
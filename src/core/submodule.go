@@ -20,6 +20,7 @@ type Submodule struct {
 	URL            string
 	Suppressed     bool
 	SuppressReason string
+	Line           int // 1-based line of the [submodule] header
 }
 
 // parseGitModules reads a .gitmodules file. The format is git-config INI but
@@ -36,7 +37,9 @@ func parseGitModules(file string) ([]Submodule, error) {
 	var cur *Submodule
 
 	sc := bufio.NewScanner(f)
+	lineNo := 0
 	for sc.Scan() {
+		lineNo++
 		raw := sc.Text()
 		line := strings.TrimSpace(raw)
 		if line == "" || strings.HasPrefix(line, ";") {
@@ -48,7 +51,7 @@ func parseGitModules(file string) ([]Submodule, error) {
 			}
 			head := strings.TrimSpace(strings.SplitN(line, "#", 2)[0])
 			name := strings.Trim(strings.TrimPrefix(strings.TrimSuffix(head, "]"), "[submodule"), ` "`)
-			cur = &Submodule{Name: name}
+			cur = &Submodule{Name: name, Line: lineNo}
 			cur.Suppressed, cur.SuppressReason = parseSuppression(raw)
 			continue
 		}
@@ -114,22 +117,17 @@ func gitErr(op string, err error) error {
 
 // latestSubmoduleSHA resolves the newest tag for a submodule URL and returns
 // the commit it points at. GitHub goes via the REST API (so --token and the
-// cache apply); everything else falls back to `git ls-remote` like sift does.
-func (f *Flags) latestSubmoduleSHA(url string) (sha, tag string, err error) {
+// cache apply); everything else falls back to `git ls-remote` like sift does,
+// which has no dates, so a cooldown leaves those submodules alone. A gitlink
+// records no version, so a policy's allow: does not apply.
+func (f *Flags) latestSubmoduleSHA(url string, p *Policy) (sha, tag string, err error) {
 	if action, ok := strings.CutPrefix(url, GitHubPrefix); ok {
-		action = strings.TrimSuffix(action, ".git")
-		t, err := GetLatestTag(action, f.GitHubToken)
-		if err != nil {
-			return "", "", err
-		}
-		m, ok := t.(map[string]any)
-		if !ok {
-			return "", "", &castToMapError{object: "tag"}
-		}
-		commit := m["commit"].(map[string]any)
-		return commit["sha"].(string), m["name"].(string), nil
+		return f.latestGithubTag(strings.TrimSuffix(action, ".git"), SourceSubmodule, p, "")
+	}
+	if days := f.cooldown(SourceSubmodule); days > 0 {
+		return "", "", &cooldownError{name: url, days: days}
 	}
-	return getLatestTagViaGit(url)
+	return getLatestTagViaGit(url, p, "")
 }
 
 func (f *Flags) UpdateSubmodules() error {
@@ -159,24 +157,34 @@ func (f *Flags) UpdateSubmodules() error {
 			continue
 		}
 
+		if f.frozen(SourceSubmodule, s.Path) {
+			continue
+		}
+
 		current, err := currentGitlinkSHA(dir, s.Path)
 		if err != nil {
 			log.Info().Err(err).Msgf("skip %s", s.Path)
 			continue
 		}
 
-		latest, tag, err := f.latestSubmoduleSHA(s.URL)
+		latest, tag, err := f.latestSubmoduleSHA(s.URL, f.policy(SourceSubmodule, s.Path))
 		if err != nil {
 			log.Info().Err(err).Msgf("failed to resolve %s", s.URL)
 			continue
 		}
 
 		if current == latest {
-			fmt.Printf("  %-30s %s (at %s)\n", s.Path, current[:12], tag)
+			if !f.reportToStdout() {
+				fmt.Printf("  %-30s %s (at %s)\n", s.Path, current[:12], tag)
+			}
 			continue
 		}
 
-		fmt.Printf("~ %-30s %s -> %s (%s)\n", s.Path, current[:12], latest[:12], tag)
+		if !f.reportToStdout() {
+			fmt.Printf("~ %-30s %s -> %s (%s)\n", s.Path, current[:12], latest[:12], tag)
+		}
+		f.recordChange(Change{File: config, Line: s.Line, Ecosystem: SourceSubmodule, Name: s.Path,
+			OldRef: current, NewRef: latest, Tag: tag})
 		changed++
 
 		if f.DryRun {
@@ -191,7 +199,7 @@ func (f *Flags) UpdateSubmodules() error {
 		}
 	}
 
-	if f.DryRun && changed > 0 {
+	if f.DryRun && changed > 0 && !f.reportToStdout() {
 		fmt.Printf("\ndry-run: %d submodule(s) would be re-pinned\n", changed)
 	}
 	return nil
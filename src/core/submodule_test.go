@@ -14,12 +14,12 @@ func TestParseGitmodules(t *testing.T) {
 	}
 
 	want := []Submodule{
-		{Name: "pyca.cryptography", Path: "pyca-cryptography", URL: "https://github.com/pyca/cryptography.git"},
-		{Name: "krb5", Path: "krb5", URL: "https://github.com/krb5/krb5"},
-		{Name: "gost-engine", Path: "gost-engine", URL: "https://github.com/gost-engine/engine"},
-		{Name: "fuzz/corpora", Path: "fuzz/corpora", URL: "https://github.com/openssl/fuzz-corpora"},
-		{Name: "wycheproof", Path: "wycheproof", URL: "https://github.com/google/wycheproof", Suppressed: true, SuppressReason: "tracks main intentionally"},
-		{Name: "tlsfuzzer", Path: "tlsfuzzer", URL: "https://github.com/tlsfuzzer/tlsfuzzer", Suppressed: true},
+		{Name: "pyca.cryptography", Path: "pyca-cryptography", URL: "https://github.com/pyca/cryptography.git", Line: 1},
+		{Name: "krb5", Path: "krb5", URL: "https://github.com/krb5/krb5", Line: 6},
+		{Name: "gost-engine", Path: "gost-engine", URL: "https://github.com/gost-engine/engine", Line: 10},
+		{Name: "fuzz/corpora", Path: "fuzz/corpora", URL: "https://github.com/openssl/fuzz-corpora", Line: 14},
+		{Name: "wycheproof", Path: "wycheproof", URL: "https://github.com/google/wycheproof", Suppressed: true, SuppressReason: "tracks main intentionally", Line: 18},
+		{Name: "tlsfuzzer", Path: "tlsfuzzer", URL: "https://github.com/tlsfuzzer/tlsfuzzer", Suppressed: true, Line: 21},
 	}
 
 	if !reflect.DeepEqual(got, want) {
@@ -0,0 +1,366 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// untrustedContexts are the github context properties an outside
+// contributor controls: titles, bodies, branch names and commit metadata.
+// A * segment matches any key or index.
+var untrustedContexts = []string{
+	"github.head_ref",
+	"github.event.issue.title",
+	"github.event.issue.body",
+	"github.event.pull_request.title",
+	"github.event.pull_request.body",
+	"github.event.pull_request.head.ref",
+	"github.event.pull_request.head.label",
+	"github.event.pull_request.head.repo.default_branch",
+	"github.event.comment.body",
+	"github.event.review.body",
+	"github.event.review_comment.body",
+	"github.event.discussion.title",
+	"github.event.discussion.body",
+	"github.event.pages.*.page_name",
+	"github.event.commits.*.message",
+	"github.event.commits.*.author.email",
+	"github.event.commits.*.author.name",
+	"github.event.head_commit.message",
+	"github.event.head_commit.author.email",
+	"github.event.head_commit.author.name",
+	"github.event.workflow_run.head_branch",
+	"github.event.workflow_run.display_title",
+	"github.event.workflow_run.head_commit.message",
+	"github.event.workflow_run.head_commit.author.email",
+	"github.event.workflow_run.head_commit.author.name",
+	"github.event.workflow_run.pull_requests.*.head.ref",
+}
+
+var (
+	// exprRe matches a ${{ }} expression, capturing its body.
+	exprRe = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+	// contextRefRe matches a property reference to a context taint can
+	// flow through inside an expression body.
+	contextRefRe = regexp.MustCompile(`\b(?:github|env|steps|needs)(?:\.[A-Za-z0-9_-]+|\.\*|\[[^\]]*\])+`)
+	// indexRe matches an index or quoted key in a property reference.
+	indexRe = regexp.MustCompile(`\[[^\]]*\]`)
+	// shellVarRe matches a $NAME or ${NAME} reference in a script.
+	shellVarRe = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+	// githubFileWriteRe matches a script line appending to $GITHUB_ENV or
+	// $GITHUB_OUTPUT.
+	githubFileWriteRe = regexp.MustCompile(`>>\s*"?\$\{?(GITHUB_ENV|GITHUB_OUTPUT)\b`)
+	// assignmentRe captures NAME in the NAME=value written to those files.
+	assignmentRe = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_-]*)=`)
+)
+
+// Sinks a TaintPath can end in.
+const (
+	SinkRun          = "run:"
+	SinkGitHubScript = "github-script"
+	SinkGitHubEnv    = "$GITHUB_ENV"
+	SinkGitHubOutput = "$GITHUB_OUTPUT"
+)
+
+// TaintPath is a flow of attacker-controlled data from a github context
+// property, through any env vars and step or job outputs it is copied into,
+// to a sink that evaluates it as code.
+type TaintPath struct {
+	// Path is the chain of nodes the data passed through, source first,
+	// e.g. ["github.event.issue.title", "env.TITLE"].
+	Path []string
+	// Sink is one of the Sink* constants.
+	Sink string
+	// Job is the job key the sink is in; empty for a composite action.
+	Job string
+	// Line is the 1-indexed source line of the sink.
+	Line int
+}
+
+// String renders the path from its source to its sink.
+func (p TaintPath) String() string {
+	return strings.Join(append(slices.Clone(p.Path), p.Sink), " → ")
+}
+
+// Message describes the path for a finding.
+func (p TaintPath) Message() string {
+	if p.Job == "" {
+		return "untrusted input reaches a script: " + p.String()
+	}
+	return fmt.Sprintf("untrusted input reaches a script in job %s: %s", p.Job, p)
+}
+
+// refSegments splits a property reference into lower-case segments, turning
+// indexes into * and quoted keys into plain ones.
+func refSegments(ref string) []string {
+	ref = indexRe.ReplaceAllStringFunc(ref, func(m string) string {
+		key := strings.Trim(m[1:len(m)-1], `'" `)
+		if key == "" || strings.Trim(key, "0123456789*") == "" {
+			return ".*"
+		}
+		return "." + key
+	})
+	return strings.Split(strings.ToLower(ref), ".")
+}
+
+// untrusted reports whether ref names an untrusted context property, or an
+// object containing one, such as github.event.issue.
+func untrusted(ref string) bool {
+	segs := refSegments(ref)
+	for _, source := range untrustedContexts {
+		pattern := strings.Split(source, ".")
+		if len(segs) > len(pattern) {
+			continue
+		}
+		match := true
+		for i, s := range segs {
+			if s != pattern[i] && s != "*" && pattern[i] != "*" {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// taintScope resolves the references visible at one point of a workflow to
+// the untrusted path each carries. A nil path marks a name known to be
+// clean, so it shadows a tainted one further out.
+type taintScope struct {
+	env   []map[string][]string // env: scopes, innermost last
+	steps map[string][]string   // steps.ID.outputs.NAME in the current job
+	needs map[string][]string   // needs.JOB.outputs.NAME
+}
+
+// trace returns the path by which ref carries untrusted data, or nil.
+func (s taintScope) trace(ref string) []string {
+	if untrusted(ref) {
+		return []string{ref}
+	}
+	segs := strings.Split(indexRe.ReplaceAllString(ref, ""), ".")
+	switch {
+	case segs[0] == "env" && len(segs) > 1:
+		return s.traceEnv(segs[1])
+	case segs[0] == "steps" && len(segs) > 3:
+		return s.steps[strings.Join(segs[:4], ".")]
+	case segs[0] == "needs" && len(segs) > 3:
+		return s.needs[strings.Join(segs[:4], ".")]
+	}
+	return nil
+}
+
+// traceEnv returns the untrusted path of env var name, or nil.
+func (s taintScope) traceEnv(name string) []string {
+	for i := len(s.env) - 1; i >= 0; i-- {
+		if path, ok := s.env[i][name]; ok {
+			return path
+		}
+	}
+	return nil
+}
+
+// traceExprs returns the untrusted path of every context reference in the
+// ${{ }} expressions in text.
+func (s taintScope) traceExprs(text string) [][]string {
+	var paths [][]string
+	for _, m := range exprRe.FindAllStringSubmatch(text, -1) {
+		for _, ref := range contextRefRe.FindAllString(m[1], -1) {
+			if path := s.trace(ref); path != nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// taintEnv evaluates an env: mapping in scope s.
+func (s taintScope) taintEnv(env *yaml.Node) map[string][]string {
+	out := map[string][]string{}
+	if env == nil || env.Kind != yaml.MappingNode {
+		return out
+	}
+	for i := 1; i < len(env.Content); i += 2 {
+		name := env.Content[i-1].Value
+		out[name] = nil
+		if paths := s.traceExprs(env.Content[i].Value); len(paths) > 0 {
+			out[name] = append(slices.Clone(paths[0]), "env."+name)
+		}
+	}
+	return out
+}
+
+// with returns s with an innermost env: scope added.
+func (s taintScope) with(env map[string][]string) taintScope {
+	s.env = append(slices.Clone(s.env), env)
+	return s
+}
+
+// scriptLine returns the source line of line idx of a scalar's value.
+func scriptLine(n *yaml.Node, idx int) int {
+	if n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return n.Line + 1 + idx
+	}
+	return n.Line + idx
+}
+
+// taintSteps follows untrusted data through one job's (or composite
+// action's) steps, recording every path that reaches a sink in paths and
+// the step outputs it taints in scope.steps.
+func taintSteps(job string, steps *yaml.Node, scope taintScope, paths *[]TaintPath) {
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return
+	}
+	exported := map[string][]string{} // written to $GITHUB_ENV by earlier steps
+	scope = scope.with(exported)
+	report := func(path []string, sink string, line int) {
+		*paths = append(*paths, TaintPath{Path: path, Sink: sink, Job: job, Line: line})
+	}
+
+	for _, step := range steps.Content {
+		if step.Kind != yaml.MappingNode {
+			continue
+		}
+		stepScope := scope.with(scope.taintEnv(findMappingValue(step, "env")))
+		id := scalarValue(step, "id")
+
+		if uses := scalarValue(step, "uses"); strings.HasPrefix(uses, "actions/github-script@") {
+			if script := findMappingValue(findMappingValue(step, "with"), "script"); script != nil && script.Kind == yaml.ScalarNode {
+				for idx, line := range strings.Split(script.Value, "\n") {
+					for _, path := range stepScope.traceExprs(line) {
+						report(path, SinkGitHubScript, scriptLine(script, idx))
+					}
+				}
+			}
+		}
+
+		run := findMappingValue(step, "run")
+		if run == nil || run.Kind != yaml.ScalarNode {
+			continue
+		}
+		for idx, line := range strings.Split(run.Value, "\n") {
+			sink := SinkRun
+			write := githubFileWriteRe.FindStringSubmatch(line)
+			switch {
+			case write == nil:
+			case write[1] == "GITHUB_ENV":
+				sink = SinkGitHubEnv
+			default:
+				sink = SinkGitHubOutput
+			}
+			found := stepScope.traceExprs(line)
+			for _, path := range found {
+				report(path, sink, scriptLine(run, idx))
+			}
+			if write == nil {
+				continue
+			}
+
+			// A shell variable is safe in a script, but not once it is
+			// written into a file GitHub parses line by line.
+			for _, m := range shellVarRe.FindAllStringSubmatch(exprRe.ReplaceAllString(line, ""), -1) {
+				if path := stepScope.traceEnv(m[1]); path != nil && !strings.HasPrefix(m[1], "GITHUB_") {
+					report(path, sink, scriptLine(run, idx))
+					found = append(found, path)
+				}
+			}
+			if len(found) == 0 {
+				continue
+			}
+			name := assignmentRe.FindStringSubmatch(line)
+			switch {
+			case name == nil:
+			case sink == SinkGitHubEnv:
+				exported[name[1]] = append(slices.Clone(found[0]), "env."+name[1])
+			case id != "":
+				output := "steps." + id + ".outputs." + name[1]
+				scope.steps[output] = append(slices.Clone(found[0]), output)
+			}
+		}
+	}
+}
+
+// TaintAnalysis follows attacker-controlled github context properties
+// through a workflow's env: blocks at every level, step outputs written to
+// $GITHUB_OUTPUT, job outputs and env vars exported through $GITHUB_ENV, and
+// returns every path that ends in a sink: a run: script or github-script
+// body that interpolates it with ${{ }}, or a $GITHUB_ENV or $GITHUB_OUTPUT
+// write. Referencing an env var as $NAME inside a script is not a sink,
+// since the shell does not re-parse its value. Composite action steps are
+// followed the same way.
+func TaintAnalysis(content []byte) []TaintPath {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil
+	}
+
+	var paths []TaintPath
+	workflow := taintScope{needs: map[string][]string{}}
+	workflow = workflow.with(workflow.taintEnv(findMappingValue(&root, "env")))
+
+	if runs := findMappingValue(&root, "runs"); runs != nil {
+		scope := workflow
+		scope.steps = map[string][]string{}
+		taintSteps("", findMappingValue(runs, "steps"), scope, &paths)
+	}
+
+	jobs := findMappingValue(&root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return paths
+	}
+	index := map[string]*yaml.Node{}
+	for i := 1; i < len(jobs.Content); i += 2 {
+		index[jobs.Content[i-1].Value] = jobs.Content[i]
+	}
+
+	// Visit jobs in needs: order so their outputs are known downstream.
+	visited := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		job, ok := index[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+		if needs := findMappingValue(job, "needs"); needs != nil {
+			for _, n := range append([]*yaml.Node{needs}, needs.Content...) {
+				if n.Kind == yaml.ScalarNode {
+					visit(n.Value)
+				}
+			}
+		}
+
+		scope := workflow
+		scope.steps = map[string][]string{}
+		scope = scope.with(scope.taintEnv(findMappingValue(job, "env")))
+		taintSteps(name, findMappingValue(job, "steps"), scope, &paths)
+
+		if outputs := findMappingValue(job, "outputs"); outputs != nil && outputs.Kind == yaml.MappingNode {
+			for i := 1; i < len(outputs.Content); i += 2 {
+				if traced := scope.traceExprs(outputs.Content[i].Value); len(traced) > 0 {
+					output := "needs." + name + ".outputs." + outputs.Content[i-1].Value
+					workflow.needs[output] = append(slices.Clone(traced[0]), output)
+				}
+			}
+		}
+	}
+	for i := 0; i < len(jobs.Content); i += 2 {
+		visit(jobs.Content[i].Value)
+	}
+
+	slices.SortFunc(paths, func(a, b TaintPath) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return strings.Compare(a.String(), b.String())
+	})
+	return slices.CompactFunc(paths, func(a, b TaintPath) bool {
+		return a.Line == b.Line && a.String() == b.String()
+	})
+}
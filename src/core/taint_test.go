@@ -0,0 +1,95 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTaintAnalysis(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "direct interpolation",
+			content: "jobs:\n  a:\n    steps:\n      - run: echo ${{ github.event.issue.title }}\n",
+			want:    []string{"4 a: github.event.issue.title → run:"},
+		},
+		{
+			name:    "trusted properties are ignored",
+			content: "jobs:\n  a:\n    steps:\n      - run: echo ${{ github.event.pull_request.number }} ${{ github.sha }}\n",
+		},
+		{
+			name: "env at every level",
+			content: "env:\n  TITLE: ${{ github.event.pull_request.title }}\njobs:\n  a:\n    env:\n      REF: ${{ github.head_ref }}\n    steps:\n" +
+				"      - env:\n          BODY: ${{ github.event.comment.body }}\n          TITLE: fixed\n" +
+				"        run: |\n          echo \"$BODY\"\n          echo ${{ env.BODY }} ${{ env.REF }} ${{ env.TITLE }}\n" +
+				"      - run: echo ${{ env.TITLE }}\n",
+			want: []string{
+				"13 a: github.event.comment.body → env.BODY → run:",
+				"13 a: github.head_ref → env.REF → run:",
+				"14 a: github.event.pull_request.title → env.TITLE → run:",
+			},
+		},
+		{
+			name: "step and job outputs",
+			content: "jobs:\n  deploy:\n    needs: [meta]\n    steps:\n" +
+				"      - run: ./deploy ${{ needs.meta.outputs.branch }}\n" +
+				"  meta:\n    outputs:\n      branch: ${{ steps.b.outputs.name }}\n    steps:\n" +
+				"      - id: b\n        env:\n          HEAD: ${{ github.event.workflow_run.head_branch }}\n" +
+				"        run: echo \"name=$HEAD\" >> \"$GITHUB_OUTPUT\"\n",
+			want: []string{
+				"5 deploy: github.event.workflow_run.head_branch → env.HEAD → steps.b.outputs.name → needs.meta.outputs.branch → run:",
+				"13 meta: github.event.workflow_run.head_branch → env.HEAD → $GITHUB_OUTPUT",
+			},
+		},
+		{
+			name: "GITHUB_ENV and github-script",
+			content: "jobs:\n  a:\n    steps:\n" +
+				"      - run: echo \"MSG=${{ github.event.head_commit.message }}\" >> $GITHUB_ENV\n" +
+				"      - uses: actions/github-script@v7\n        with:\n          script: |\n" +
+				"            console.log(\"${{ env.MSG }}\")\n            console.log(\"${{ toJSON(github.event.issue) }}\")\n",
+			want: []string{
+				"4 a: github.event.head_commit.message → $GITHUB_ENV",
+				"8 a: github.event.head_commit.message → env.MSG → github-script",
+				"9 a: github.event.issue → github-script",
+			},
+		},
+		{
+			name:    "composite action",
+			content: "runs:\n  using: composite\n  steps:\n    - shell: bash\n      run: echo ${{ github.event.commits[0].message }}\n",
+			want:    []string{"5 : github.event.commits[0].message → run:"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range TaintAnalysis([]byte(tt.content)) {
+			got = append(got, fmt.Sprintf("%d %s: %s", p.Line, p.Job, p))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: TaintAnalysis() =\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWorkflowFindings_Taint(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("permissions: {}\njobs:\n  a:\n    steps:\n" +
+		"      - run: echo ${{ github.event.issue.title }} ${{ github.event.issue.body }}\n")
+	var got []string
+	for _, f := range WorkflowFindings("ci.yml", content) {
+		got = append(got, fmt.Sprintf("%d %s", f.Line, f.Message))
+	}
+	want := []string{
+		"5 untrusted input reaches a script in job a: github.event.issue.body → run:",
+		"5 untrusted input reaches a script in job a: github.event.issue.title → run:",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WorkflowFindings() = %q, want %q", got, want)
+	}
+}
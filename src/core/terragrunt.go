@@ -0,0 +1,419 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rs/zerolog/log"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// TerragruntFile is the configuration Terragrunt reads in each unit.
+const TerragruntFile = "terragrunt.hcl"
+
+type terragruntSourceError struct {
+	source string
+}
+
+func (e *terragruntSourceError) Error() string {
+	return fmt.Sprintf("%s is built from expressions ghat can't rewrite", e.source)
+}
+
+// isTerragruntFile reports whether path is a Terragrunt configuration rather
+// than Terraform.
+func isTerragruntFile(path string) bool {
+	return filepath.Base(path) == TerragruntFile
+}
+
+// GetTerragrunt returns the terragrunt.hcl files among the entries and the
+// files they reach through include, dependency and dependencies blocks. When
+// a directory is being scanned, rather than one file, files outside it are
+// left alone.
+func (f *Flags) GetTerragrunt() []string {
+	var root string
+	if f.File == "" && f.Directory != "" {
+		root, _ = filepath.Abs(f.Directory)
+	}
+
+	seen := map[string]bool{}
+	var files []string
+	var visit func(path string)
+	visit = func(path string) {
+		abs, err := filepath.Abs(path)
+		if err != nil || seen[abs] {
+			return
+		}
+		seen[abs] = true
+		if root != "" {
+			if rel, err := filepath.Rel(root, abs); err != nil || strings.HasPrefix(rel, "..") {
+				return
+			}
+		}
+		if info, err := os.Stat(abs); err != nil || info.IsDir() {
+			return
+		}
+		files = append(files, path)
+		for _, next := range terragruntReferences(path) {
+			visit(next)
+		}
+	}
+
+	for _, entry := range f.Entries {
+		if isTerragruntFile(entry) {
+			visit(entry)
+		}
+	}
+	return files
+}
+
+// terragruntReferences returns the files a Terragrunt configuration includes
+// or depends on, as far as their paths can be worked out without running
+// Terragrunt.
+func terragruntReferences(file string) []string {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	parsed, diags := hclsyntax.ParseConfig(src, file, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil
+	}
+	body := parsed.Body.(*hclsyntax.Body)
+	ctx, _ := terragruntContext(file, body)
+
+	dir := filepath.Dir(file)
+	var paths []string
+	add := func(path string) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, TerragruntFile)
+		}
+		paths = append(paths, path)
+	}
+
+	for _, block := range body.Blocks {
+		var attr string
+		switch block.Type {
+		case "include":
+			attr = "path"
+		case "dependency":
+			attr = "config_path"
+		case "dependencies":
+			attr = "paths"
+		default:
+			continue
+		}
+		a := block.Body.Attributes[attr]
+		if a == nil {
+			continue
+		}
+		val, diags := a.Expr.Value(ctx)
+		if diags.HasErrors() || !val.IsWhollyKnown() || val.IsNull() {
+			log.Debug().Str("file", file).Str("block", block.Type).Msg("can't resolve path, not following it")
+			continue
+		}
+		switch {
+		case val.Type() == cty.String:
+			add(val.AsString())
+		case val.CanIterateElements():
+			for it := val.ElementIterator(); it.Next(); {
+				if _, v := it.Element(); v.Type() == cty.String && !v.IsNull() {
+					add(v.AsString())
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// terragruntContext returns an evaluation context for a Terragrunt file with
+// the locals that can be worked out without running Terragrunt, and those
+// locals' attributes. Functions that need Terragrunt itself, such as
+// read_terragrunt_config, are missing, so locals that use them stay unknown.
+func terragruntContext(file string, body *hclsyntax.Body) (*hcl.EvalContext, map[string]*hclsyntax.Attribute) {
+	dir, _ := filepath.Abs(filepath.Dir(file))
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{},
+		Functions: terragruntFunctions(dir),
+	}
+
+	attrs := map[string]*hclsyntax.Attribute{}
+	for _, block := range body.Blocks {
+		if block.Type == "locals" {
+			for name, attr := range block.Body.Attributes {
+				attrs[name] = attr
+			}
+		}
+	}
+
+	// Locals can refer to each other in any order, so keep evaluating until
+	// a pass resolves nothing new.
+	locals := map[string]cty.Value{}
+	for progress := true; progress; {
+		progress = false
+		ctx.Variables["local"] = cty.ObjectVal(locals)
+		for name, attr := range attrs {
+			if _, done := locals[name]; done {
+				continue
+			}
+			val, diags := attr.Expr.Value(ctx)
+			if diags.HasErrors() || !val.IsWhollyKnown() {
+				continue
+			}
+			locals[name] = val
+			progress = true
+		}
+	}
+	ctx.Variables["local"] = cty.ObjectVal(locals)
+	return ctx, attrs
+}
+
+// terragruntFunctions are the Terragrunt and Terraform functions source and
+// include paths are usually built with, evaluated for a file in dir.
+func terragruntFunctions(dir string) map[string]function.Function {
+	stringFunc := func(impl func(string) string) function.Function {
+		return function.New(&function.Spec{
+			Params: []function.Parameter{{Name: "s", Type: cty.String}},
+			Type:   function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+				return cty.StringVal(impl(args[0].AsString())), nil
+			},
+		})
+	}
+
+	return map[string]function.Function{
+		"find_in_parent_folders": function.New(&function.Spec{
+			VarParam: &function.Parameter{Name: "args", Type: cty.String},
+			Type:     function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+				name := TerragruntFile
+				if len(args) > 0 {
+					name = args[0].AsString()
+				}
+				for d := filepath.Dir(dir); ; d = filepath.Dir(d) {
+					if _, err := os.Stat(filepath.Join(d, name)); err == nil {
+						return cty.StringVal(filepath.Join(d, name)), nil
+					}
+					if d == filepath.Dir(d) {
+						break
+					}
+				}
+				if len(args) > 1 {
+					return args[1], nil
+				}
+				return cty.NilVal, fmt.Errorf("no %s above %s", name, dir)
+			},
+		}),
+		"get_terragrunt_dir": function.New(&function.Spec{
+			Type: function.StaticReturnType(cty.String),
+			Impl: func([]cty.Value, cty.Type) (cty.Value, error) {
+				return cty.StringVal(dir), nil
+			},
+		}),
+		"get_env": function.New(&function.Spec{
+			Params:   []function.Parameter{{Name: "name", Type: cty.String}},
+			VarParam: &function.Parameter{Name: "default", Type: cty.String},
+			Type:     function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+				if v, ok := os.LookupEnv(args[0].AsString()); ok {
+					return cty.StringVal(v), nil
+				}
+				if len(args) > 1 {
+					return args[1], nil
+				}
+				return cty.NilVal, fmt.Errorf("%s is not set", args[0].AsString())
+			},
+		}),
+		"dirname":    stringFunc(filepath.Dir),
+		"basename":   stringFunc(filepath.Base),
+		"format":     stdlib.FormatFunc,
+		"join":       stdlib.JoinFunc,
+		"lower":      stdlib.LowerFunc,
+		"upper":      stdlib.UpperFunc,
+		"replace":    stdlib.ReplaceFunc,
+		"trimprefix": stdlib.TrimPrefixFunc,
+		"trimsuffix": stdlib.TrimSuffixFunc,
+		"trimspace":  stdlib.TrimSpaceFunc,
+		"lookup":     stdlib.LookupFunc,
+		"merge":      stdlib.MergeFunc,
+	}
+}
+
+// UpdateTerragrunt pins the terraform { source } of a Terragrunt file. A
+// source built from locals is resolved, and the ref is rewritten where it is
+// written: in the source itself, or in the local it comes from.
+func (f *Flags) UpdateTerragrunt(file string) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s", file)
+	}
+
+	parsed, diags := hclsyntax.ParseConfig(src, file, hcl.InitialPos)
+	if diags.HasErrors() {
+		log.Warn().Str("file", file).Str("diags", diags.Error()).Msg("skipping unparseable HCL")
+		return nil
+	}
+	body := parsed.Body.(*hclsyntax.Body)
+	ctx, locals := terragruntContext(file, body)
+
+	content := string(src)
+	for _, block := range body.Blocks {
+		if block.Type != "terraform" {
+			continue
+		}
+		attr := block.Body.Attributes["source"]
+		if attr == nil {
+			continue
+		}
+		val, diags := attr.Expr.Value(ctx)
+		if diags.HasErrors() || !val.IsWhollyKnown() || val.Type() != cty.String {
+			log.Info().Str("file", file).Msg("can't resolve terraform source, leaving unchanged")
+			continue
+		}
+
+		source := val.AsString()
+		name, _, _ := strings.Cut(source, "?")
+		if f.frozen(SourceTerraform, name) {
+			continue
+		}
+		myType, err := f.GetType(source)
+		if err != nil {
+			log.Info().Msgf("source type failure %s", source)
+			continue
+		}
+		newSource, tag, err := f.UpdateSource(source, myType, "", f.policy(SourceTerraform, name))
+		if err != nil {
+			log.Warn().Err(err).Str("source", source).Msg("failed to update module source, leaving unchanged")
+			continue
+		}
+		if newSource == source {
+			continue
+		}
+
+		rng, exprEnd, text, err := terragruntEdit(src, attr, locals, ctx, source, newSource)
+		if err != nil {
+			log.Warn().Err(err).Str("file", file).Msg("failed to update module source, leaving unchanged")
+			continue
+		}
+		content = replaceRange(content, rng, exprEnd, text, tag)
+
+		_, oldRef := sourceRef(source)
+		_, newRef := sourceRef(newSource)
+		f.recordChange(Change{
+			File: file, Line: rng.Start.Line, Ecosystem: SourceTerraform, Name: name,
+			OldRef: oldRef, NewRef: newRef, Tag: tag,
+		})
+	}
+
+	f.printDiff(file, string(src), content)
+
+	if content != string(src) && !f.DryRun {
+		if err := os.WriteFile(file, []byte(content), FilePermissions); err != nil {
+			log.Info().Msgf("failed to write %s", file)
+		}
+	}
+	return nil
+}
+
+// terragruntEdit works out which part of src to replace, and with what, to
+// turn source into newSource, and where the expression holding it ends. A
+// literal source is replaced whole. One built from expressions can only have
+// its ref changed: where the ref is written in the source itself, or in a
+// literal local the source uses.
+func terragruntEdit(src []byte, attr *hclsyntax.Attribute, locals map[string]*hclsyntax.Attribute,
+	ctx *hcl.EvalContext, source, newSource string) (hcl.Range, int, string, error) {
+	rng := attr.Expr.Range()
+	if t, ok := attr.Expr.(*hclsyntax.TemplateExpr); ok && t.IsStringLiteral() {
+		return rng, rng.End.Byte, string(hclwrite.TokensForValue(cty.StringVal(newSource)).Bytes()), nil
+	}
+
+	i, oldRef := sourceRef(source)
+	j, newRef := sourceRef(newSource)
+	if i < 0 || j < 0 || source[:i] != newSource[:j] || source[i+len(oldRef):] != newSource[j+len(newRef):] {
+		return hcl.Range{}, 0, "", &terragruntSourceError{source: source}
+	}
+
+	// the ref and its key, e.g. ref=v1.2.3, as written in the source
+	key := source[strings.LastIndexAny(source[:i], "?&")+1 : i]
+	if at := strings.Index(string(src[rng.Start.Byte:rng.End.Byte]), key+oldRef); at >= 0 {
+		start := rng.Start.Byte + at + len(key)
+		return byteRange(src, start, start+len(oldRef)), rng.End.Byte, newRef, nil
+	}
+
+	for _, traversal := range attr.Expr.Variables() {
+		if traversal.RootName() != "local" || len(traversal) < 2 {
+			continue
+		}
+		step, ok := traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			continue
+		}
+		local := locals[step.Name]
+		if local == nil {
+			continue
+		}
+		t, ok := local.Expr.(*hclsyntax.TemplateExpr)
+		if !ok || !t.IsStringLiteral() {
+			continue
+		}
+		if val, diags := local.Expr.Value(ctx); diags.HasErrors() || val.AsString() != oldRef {
+			continue
+		}
+		return local.Expr.Range(), local.Expr.Range().End.Byte, string(hclwrite.TokensForValue(cty.StringVal(newRef)).Bytes()), nil
+	}
+
+	return hcl.Range{}, 0, "", &terragruntSourceError{source: source}
+}
+
+// sourceRef returns where the ref= value, or the version= of a tfr:// source,
+// starts in a module source, and the value. It returns -1 if there is none.
+func sourceRef(source string) (int, string) {
+	q := strings.Index(source, "?")
+	if q < 0 {
+		return -1, ""
+	}
+	offset := q + 1
+	for _, param := range strings.Split(source[q+1:], "&") {
+		for _, key := range []string{"ref=", "version="} {
+			if v, ok := strings.CutPrefix(param, key); ok {
+				return offset + len(key), v
+			}
+		}
+		offset += len(param) + 1
+	}
+	return -1, ""
+}
+
+// byteRange is the range from start to end in src, with the start line set.
+func byteRange(src []byte, start, end int) hcl.Range {
+	line := 1 + strings.Count(string(src[:start]), "\n")
+	return hcl.Range{Start: hcl.Pos{Byte: start, Line: line}, End: hcl.Pos{Byte: end, Line: line}}
+}
+
+// replaceRange swaps rng of content for text and, when the line is blank or a
+// comment after exprEnd, ends it with #tag as swipe does in .tf files.
+func replaceRange(content string, rng hcl.Range, exprEnd int, text, tag string) string {
+	head, tail := content[:rng.Start.Byte]+text+content[rng.End.Byte:exprEnd], content[exprEnd:]
+	if tag == "" {
+		return head + tail
+	}
+	rest, after, found := strings.Cut(tail, "\n")
+	trimmed := strings.TrimSpace(rest)
+	if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "//") {
+		return head + tail
+	}
+	line := head + " #" + tag
+	if found {
+		line += "\n" + after
+	}
+	return line
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFlags_UpdateTerragrunt(t *testing.T) {
+	t.Parallel()
+
+	repo, shas := gitTagRepo(t)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"literal",
+			"terraform {\n  source = \"git::file://" + repo + "//modules/nat?ref=v1.0.0\" # old\n}\n",
+			"terraform {\n  source = \"git::file://" + repo + "//modules/nat?ref=" + shas["v1.0.0"] + "\" #v1.0.0\n}\n"},
+		{"ref in a local",
+			"locals {\n  repo    = \"git::file://" + repo + "\"\n  version = \"v1.0.0\"\n}\n\n" +
+				"terraform {\n  source = \"${local.repo}?ref=${local.version}\"\n}\n",
+			"locals {\n  repo    = \"git::file://" + repo + "\"\n  version = \"" + shas["v1.0.0"] + "\" #v1.0.0\n}\n\n" +
+				"terraform {\n  source = \"${local.repo}?ref=${local.version}\"\n}\n"},
+		{"ref in the template",
+			"locals {\n  repo = \"git::file://" + repo + "\"\n}\n\n" +
+				"terraform {\n  source = \"${local.repo}//modules/nat?ref=v1.0.0\"\n}\n",
+			"locals {\n  repo = \"git::file://" + repo + "\"\n}\n\n" +
+				"terraform {\n  source = \"${local.repo}//modules/nat?ref=" + shas["v1.0.0"] + "\" #v1.0.0\n}\n"},
+		{"unresolvable",
+			"terraform {\n  source = \"${include.root.locals.repo}?ref=v1.0.0\"\n}\n",
+			"terraform {\n  source = \"${include.root.locals.repo}?ref=v1.0.0\"\n}\n"},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), TerragruntFile)
+		writeFiles(t, filepath.Dir(file), map[string]string{TerragruntFile: tt.content})
+
+		f := &Flags{Silent: true}
+		if err := f.UpdateTerragrunt(file); err != nil {
+			t.Fatalf("%s: UpdateTerragrunt() error = %v", tt.name, err)
+		}
+		got, _ := os.ReadFile(file)
+		if string(got) != tt.want {
+			t.Errorf("%s: UpdateTerragrunt() wrote\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFlags_GetTerragrunt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"root.hcl":           "locals {}\n",
+		"_envcommon/vpc.hcl": "terraform {}\n",
+		"prod/vpc/main.tf":   "",
+		"prod/db/terragrunt.hcl": "include \"root\" {\n  path = find_in_parent_folders(\"root.hcl\")\n}\n\n" +
+			"dependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n",
+		"prod/vpc/terragrunt.hcl": "include \"envcommon\" {\n" +
+			"  path = \"${dirname(find_in_parent_folders(\"root.hcl\"))}/_envcommon/vpc.hcl\"\n}\n",
+	})
+
+	f := &Flags{Directory: dir, Entries: []string{filepath.Join(dir, "prod/db", TerragruntFile), filepath.Join(dir, "prod/vpc/main.tf")}}
+	var got []string
+	for _, file := range f.GetTerragrunt() {
+		rel, _ := filepath.Rel(dir, file)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"prod/db/terragrunt.hcl", "root.hcl", "prod/vpc/terragrunt.hcl", "_envcommon/vpc.hcl"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTerragrunt() = %v, want %v", got, want)
+	}
+
+	// files outside the directory being scanned are left alone
+	f = &Flags{Directory: filepath.Join(dir, "prod"), Entries: []string{filepath.Join(dir, "prod/db", TerragruntFile)}}
+	if got := f.GetTerragrunt(); len(got) != 2 {
+		t.Errorf("GetTerragrunt() = %v, want prod/db and prod/vpc only", got)
+	}
+}
+
+func TestFlags_UpdateTfrSource(t *testing.T) {
+	fakePrivateRegistries(t)
+	t.Setenv("TF_TOKEN_tfe_example_com", "s3cret")
+
+	var days uint
+	f := &Flags{Days: &days}
+	got, tag, err := f.UpdateSource("tfr://tfe.example.com/acme/vpc/aws//modules/nat?version=1.0.0", "tfr", "", nil)
+	if err != nil {
+		t.Fatalf("UpdateSource() error = %v", err)
+	}
+	if got != "tfr://tfe.example.com/acme/vpc/aws//modules/nat?version=1.3.0" || tag != "1.3.0" {
+		t.Errorf("UpdateSource() = %q, %q", got, tag)
+	}
+
+	if kind, _ := f.GetType("tfr:///terraform-aws-modules/vpc/aws?version=5.1.0"); kind != "tfr" {
+		t.Errorf("GetType() = %q, want tfr", kind)
+	}
+	if !strings.HasPrefix(got, "tfr://") {
+		t.Errorf("UpdateSource() = %q, want a tfr:// source", got)
+	}
+}
@@ -0,0 +1,489 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog/log"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// TerraformLockFile is the dependency lock file terraform init keeps next to
+// a module's .tf files.
+const TerraformLockFile = ".terraform.lock.hcl"
+
+// defaultProviderHost is the registry a provider source without a hostname
+// lives on.
+const defaultProviderHost = "registry.terraform.io"
+
+// tfLockMu serialises lock file rewrites, as eachFile updates the .tf files of
+// one directory concurrently.
+var tfLockMu sync.Mutex
+
+// providerLock is one provider block of a .terraform.lock.hcl.
+type providerLock struct {
+	Address     string // e.g. registry.terraform.io/hashicorp/aws
+	Version     string
+	Constraints string
+	Hashes      []string
+}
+
+// providerPackage is the registry's download document for one platform.
+type providerPackage struct {
+	Filename            string `json:"filename"`
+	DownloadURL         string `json:"download_url"`
+	Shasum              string `json:"shasum"`
+	ShasumsURL          string `json:"shasums_url"`
+	ShasumsSignatureURL string `json:"shasums_signature_url"`
+	SigningKeys         struct {
+		GPGPublicKeys []struct {
+			KeyID      string `json:"key_id"`
+			ASCIIArmor string `json:"ascii_armor"`
+		} `json:"gpg_public_keys"`
+	} `json:"signing_keys"`
+}
+
+type providerLockMismatchError struct {
+	count int
+}
+
+func (e *providerLockMismatchError) Error() string {
+	return fmt.Sprintf("%d provider lock entries do not match their constraints", e.count)
+}
+
+type shasumMismatchError struct {
+	file string
+	want string
+	got  string
+}
+
+func (e *shasumMismatchError) Error() string {
+	return fmt.Sprintf("%s has sha256 %s, the registry lists %s", e.file, e.got, e.want)
+}
+
+type shasumsSignatureError struct {
+	url string
+	err error
+}
+
+func (e *shasumsSignatureError) Error() string {
+	return fmt.Sprintf("SHASUMS %s is not signed by the registry's signing keys: %v", e.url, e.err)
+}
+
+func (e *shasumsSignatureError) Unwrap() error {
+	return e.err
+}
+
+// lockPlatforms is --platform, or the platform ghat is running on, as
+// terraform providers lock defaults to.
+func (f *Flags) lockPlatforms() []string {
+	if len(f.LockPlatforms) > 0 {
+		return f.LockPlatforms
+	}
+	return []string{runtime.GOOS + "_" + runtime.GOARCH}
+}
+
+// providerAddress returns the fully qualified lock file address of a
+// required_providers source: aws becomes registry.terraform.io/hashicorp/aws.
+func providerAddress(source string) string {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(source)), "/")
+	switch len(parts) {
+	case 1:
+		return defaultProviderHost + "/hashicorp/" + parts[0]
+	case 2:
+		return defaultProviderHost + "/" + parts[0] + "/" + parts[1]
+	}
+	return strings.Join(parts, "/")
+}
+
+// readTerraformLock parses a .terraform.lock.hcl into its provider entries,
+// keyed by address.
+func readTerraformLock(path string) (map[string]*providerLock, error) {
+	src, err := os.ReadFile(path) // #nosec G304 — a lock file beside scanned .tf files
+	if err != nil {
+		return nil, err
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+	}
+
+	locks := map[string]*providerLock{}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "provider" || len(block.Labels) != 1 {
+			continue
+		}
+		lock := &providerLock{Address: block.Labels[0]}
+		for name, attr := range block.Body.Attributes {
+			value, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+			}
+			switch {
+			case name == "version" && value.Type() == cty.String:
+				lock.Version = value.AsString()
+			case name == "constraints" && value.Type() == cty.String:
+				lock.Constraints = value.AsString()
+			case name == "hashes" && value.CanIterateElements():
+				for it := value.ElementIterator(); it.Next(); {
+					if _, h := it.Element(); h.Type() == cty.String {
+						lock.Hashes = append(lock.Hashes, h.AsString())
+					}
+				}
+			}
+		}
+		locks[lock.Address] = lock
+	}
+	return locks, nil
+}
+
+// renderTerraformLock writes locks in the layout terraform init uses, so a
+// later init leaves the file alone.
+func renderTerraformLock(locks map[string]*providerLock) string {
+	addresses := make([]string, 0, len(locks))
+	for address := range locks {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	var b strings.Builder
+	b.WriteString("# This file is maintained automatically by \"terraform init\".\n")
+	b.WriteString("# Manual edits may be lost in future updates.\n")
+	for _, address := range addresses {
+		lock := locks[address]
+		fmt.Fprintf(&b, "\nprovider %q {\n", address)
+		if lock.Constraints != "" {
+			fmt.Fprintf(&b, "  version     = %q\n  constraints = %q\n", lock.Version, lock.Constraints)
+		} else {
+			fmt.Fprintf(&b, "  version = %q\n", lock.Version)
+		}
+		b.WriteString("  hashes = [\n")
+		for _, h := range lock.Hashes {
+			fmt.Fprintf(&b, "    %q,\n", h)
+		}
+		b.WriteString("  ]\n}\n")
+	}
+	return b.String()
+}
+
+// updateTerraformLock sets the lock entries of the providers shake moved in
+// dir, creating .terraform.lock.hcl if there is none. Hashes still held for a
+// version that has not changed are kept, as terraform init keeps them.
+func (f *Flags) updateTerraformLock(dir string, providers []*ProviderInfo) error {
+	updates := map[string]*providerLock{}
+	for _, p := range providers {
+		hashes, err := providerHashes(p.Host, p.Namespace, p.Type, p.LatestVersion, f.lockPlatforms())
+		if err != nil {
+			log.Warn().Err(err).Str("provider", p.Source).Msg("failed to hash provider packages, lock entry left as is")
+			continue
+		}
+		address := providerAddress(p.Source)
+		updates[address] = &providerLock{Address: address, Version: p.LatestVersion, Constraints: p.Constraint, Hashes: hashes}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	tfLockMu.Lock()
+	defer tfLockMu.Unlock()
+
+	path := filepath.Join(dir, TerraformLockFile)
+	var before string
+	locks, err := readTerraformLock(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		locks = map[string]*providerLock{}
+	case err != nil:
+		return err
+	default:
+		data, _ := os.ReadFile(path) // #nosec G304
+		before = string(data)
+	}
+
+	for address, lock := range updates {
+		if old, ok := locks[address]; ok && old.Version == lock.Version {
+			lock.Hashes = mergeHashes(old.Hashes, lock.Hashes)
+		}
+		locks[address] = lock
+	}
+
+	after := renderTerraformLock(locks)
+	f.printDiff(path, before, after)
+	if f.DryRun || before == after {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(after), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	log.Info().Str("file", path).Int("providers", len(updates)).Msg("provider lock updated")
+	return nil
+}
+
+// mergeHashes returns the sorted union of two hash lists.
+func mergeHashes(a, b []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, h := range append(append([]string{}, a...), b...) {
+		if !seen[h] {
+			seen[h] = true
+			out = append(out, h)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// providerHashes returns the lock file hashes of a provider version: an h1:
+// hash of each platform's package, which ghat downloads and checks against
+// the registry's shasum, and a zh: hash of every package in its SHASUMS file,
+// once that file's signature checks out against the registry's signing keys
+// and it lists the same shasum for each downloaded package. The result is
+// cached, so --offline can still write the lock.
+func providerHashes(host, namespace, providerType, version string, platforms []string) ([]string, error) {
+	key := fmt.Sprintf("terraform-provider-hashes %s/%s/%s %s %s", host, namespace, providerType, version, strings.Join(platforms, ","))
+	return recorded(key, func() ([]string, error) {
+		var hashes []string
+		var downloaded []providerPackage
+		for _, platform := range platforms {
+			goos, arch, ok := strings.Cut(platform, "_")
+			if !ok {
+				return nil, fmt.Errorf("platform %q is not os_arch", platform)
+			}
+			pkg, err := providerDownload(host, namespace, providerType, version, goos, arch)
+			if err != nil {
+				log.Warn().Err(err).Str("provider", namespace+"/"+providerType).Str("platform", platform).Msg("no package for platform")
+				continue
+			}
+			h1, err := hashProviderPackage(pkg)
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, h1)
+			downloaded = append(downloaded, pkg)
+		}
+		if len(downloaded) == 0 {
+			return nil, fmt.Errorf("no packages of %s/%s %s for %s", namespace, providerType, version, strings.Join(platforms, ", "))
+		}
+		zh, err := providerShasums(downloaded[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range downloaded {
+			if !slices.Contains(zh, "zh:"+strings.ToLower(pkg.Shasum)) {
+				return nil, fmt.Errorf("%s has sha256 %s, which its signed SHASUMS file does not list", pkg.Filename, pkg.Shasum)
+			}
+		}
+		return mergeHashes(hashes, zh), nil
+	})
+}
+
+// providerDownload reads the download document for one platform of a
+// provider version from the registry on host, resolving its URLs against the
+// registry.
+func providerDownload(host, namespace, providerType, version, goos, arch string) (providerPackage, error) {
+	var pkg providerPackage
+	base, err := registryService(host, serviceProviders)
+	if err != nil {
+		return pkg, err
+	}
+	u := fmt.Sprintf("%s%s/%s/%s/download/%s/%s", base, namespace, providerType, version, goos, arch)
+	if err := registryGetJSON(host, u, &pkg); err != nil {
+		return pkg, err
+	}
+
+	from, _ := url.Parse(u)
+	for _, ref := range []*string{&pkg.DownloadURL, &pkg.ShasumsURL, &pkg.ShasumsSignatureURL} {
+		if r, err := url.Parse(*ref); err == nil && *ref != "" {
+			*ref = from.ResolveReference(r).String()
+		}
+	}
+	return pkg, nil
+}
+
+// packageClient downloads provider packages around the response cache, which
+// would otherwise hold every zip; providerHashes caches their hashes instead.
+func packageClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Minute}
+}
+
+// hashProviderPackage downloads a provider package, checks it against the
+// registry's sha256 and returns its h1: hash, the hash of its contents that
+// terraform init records.
+func hashProviderPackage(pkg providerPackage) (string, error) {
+	resp, err := packageClient().Get(pkg.DownloadURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", pkg.Filename, err)
+	}
+	defer func(Body io.ReadCloser) { _ = Body.Close() }(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download of %s returned status %d", pkg.Filename, resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp("", "ghat-provider-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	sum := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, sum), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", pkg.Filename, err)
+	}
+	if got := hex.EncodeToString(sum.Sum(nil)); !strings.EqualFold(got, pkg.Shasum) {
+		return "", &shasumMismatchError{file: pkg.Filename, want: pkg.Shasum, got: got}
+	}
+	return dirhash.HashZip(tmp.Name(), dirhash.Hash1)
+}
+
+// providerShasums returns a zh: hash for every package in a provider
+// release's SHASUMS file, after checking the file's detached signature
+// against the signing keys pkg's download document lists, as terraform init
+// does.
+func providerShasums(pkg providerPackage) ([]string, error) {
+	shasums, err := fetchShasumsFile(pkg.ShasumsURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyShasums(pkg, shasums); err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	scanner := bufio.NewScanner(bytes.NewReader(shasums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.HasSuffix(fields[1], ".zip") {
+			hashes = append(hashes, "zh:"+strings.ToLower(fields[0]))
+		}
+	}
+	return hashes, scanner.Err()
+}
+
+// fetchShasumsFile returns the body of a SHASUMS file or its signature.
+func fetchShasumsFile(fileURL string) ([]byte, error) {
+	if fileURL == "" {
+		return nil, errors.New("the registry lists no SHASUMS URL")
+	}
+	resp, err := lookupClient(30 * time.Second).Get(fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", fileURL, err)
+	}
+	defer func(Body io.ReadCloser) { _ = Body.Close() }(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", fileURL, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// verifyShasums checks shasums against its detached signature and the
+// registry's gpg_public_keys for pkg.
+func verifyShasums(pkg providerPackage, shasums []byte) error {
+	var keyring openpgp.EntityList
+	for _, key := range pkg.SigningKeys.GPGPublicKeys {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key.ASCIIArmor))
+		if err != nil {
+			log.Debug().Str("key", key.KeyID).Err(err).Msg("unreadable signing key")
+			continue
+		}
+		keyring = append(keyring, entities...)
+	}
+	if len(keyring) == 0 {
+		return &shasumsSignatureError{url: pkg.ShasumsURL, err: errors.New("the registry lists no signing keys")}
+	}
+	if pkg.ShasumsSignatureURL == "" {
+		return &shasumsSignatureError{url: pkg.ShasumsURL, err: errors.New("the registry lists no signature")}
+	}
+	signature, err := fetchShasumsFile(pkg.ShasumsSignatureURL)
+	if err != nil {
+		return err
+	}
+	if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(shasums), bytes.NewReader(signature), nil); err != nil {
+		return &shasumsSignatureError{url: pkg.ShasumsURL, err: err}
+	}
+	return nil
+}
+
+// VerifyProviderLocks checks, without network calls, that each
+// .terraform.lock.hcl beside the scanned .tf files locks every declared
+// provider at a version its constraints allow, writing one line per
+// difference to w. Directories without a lock file are skipped.
+func (f *Flags) VerifyProviderLocks(w io.Writer) error {
+	terraform, err := f.GetTF()
+	if err != nil {
+		return err
+	}
+
+	declared := map[string][]ProviderInfo{}
+	var dirs []string
+	for _, file := range terraform {
+		providers, err := extractProvidersFromFile(file)
+		if err != nil {
+			log.Warn().Err(err).Str("file", file).Msg("Failed to extract providers")
+			continue
+		}
+		dir := filepath.Dir(file)
+		if _, ok := declared[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		declared[dir] = append(declared[dir], providers...)
+	}
+	sort.Strings(dirs)
+
+	var mismatches, checked int
+	for _, dir := range dirs {
+		path := filepath.Join(dir, TerraformLockFile)
+		locks, err := readTerraformLock(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, p := range declared[dir] {
+			checked++
+			lock, ok := locks[providerAddress(p.Source)]
+			switch {
+			case !ok:
+				mismatches++
+				_, _ = fmt.Fprintf(w, "%s: %s %s is not locked\n", path, SourceProvider, p.Source)
+			case p.CurrentVersion == "":
+			default:
+				ok, err := satisfiesConstraints(lock.Version, p.CurrentVersion)
+				if err != nil || !ok {
+					mismatches++
+					_, _ = fmt.Fprintf(w, "%s: %s %s is locked at %s, which %q does not allow\n",
+						path, SourceProvider, p.Source, lock.Version, p.CurrentVersion)
+				}
+			}
+		}
+	}
+
+	if mismatches > 0 {
+		return &providerLockMismatchError{count: mismatches}
+	}
+	_, _ = fmt.Fprintf(w, "%d provider lock entries match their constraints\n", checked)
+	return nil
+}
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// fakeProviderRegistry serves the provider ghattest/widget, whose latest
+// version is 2.1.0 and which only has a linux_amd64 package, and the module
+// ghattest/network/aws, whose latest version is 3.0.1.
+func fakeProviderRegistry(t *testing.T) {
+	t.Helper()
+	var pkg bytes.Buffer
+	zw := zip.NewWriter(&pkg)
+	w, _ := zw.Create("terraform-provider-widget_v2.1.0")
+	_, _ = w.Write([]byte("binary"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(pkg.Bytes())
+	shasum := hex.EncodeToString(sum[:])
+	shasums := fmt.Sprintf("%s  terraform-provider-widget_2.1.0_linux_amd64.zip\n"+
+		"%s  terraform-provider-widget_2.1.0_darwin_arm64.zip\n", shasum, strings.Repeat("ab", 32))
+	signer := signingKey(t)
+	var signature bytes.Buffer
+	if err := openpgp.DetachSign(&signature, signer, strings.NewReader(shasums), nil); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := json.Marshal(map[string]interface{}{"gpg_public_keys": []map[string]string{
+		{"key_id": signer.PrimaryKey.KeyIdString(), "ascii_armor": armoredPublicKey(t, signer)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/providers/ghattest/widget/versions", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.4.0"},{"version":"2.1.0"},{"version":"2.2.0-beta1"}]}`))
+	})
+	mux.HandleFunc("/v2/providers/ghattest/widget", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"included":[` +
+			`{"type":"provider-versions","attributes":{"version":"1.4.0","published-at":"2024-01-01T00:00:00Z"}},` +
+			`{"type":"provider-versions","attributes":{"version":"2.1.0","published-at":"2024-06-01T00:00:00Z"}}]}`))
+	})
+	mux.HandleFunc("/v1/modules/ghattest/network/aws/versions", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"modules":[{"versions":[{"version":"1.2.0"},{"version":"3.0.1"},{"version":"1.10.3"}]}]}`))
+	})
+	mux.HandleFunc("/v1/providers/ghattest/widget/2.1.0/download/linux/amd64", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"filename":"terraform-provider-widget_2.1.0_linux_amd64.zip",`+
+			`"download_url":"/files/linux_amd64.zip","shasums_url":"/files/SHA256SUMS",`+
+			`"shasums_signature_url":"/files/SHA256SUMS.sig","shasum":%q,"signing_keys":%s}`, shasum, keys)
+	})
+	mux.HandleFunc("/files/linux_amd64.zip", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(pkg.Bytes())
+	})
+	mux.HandleFunc("/files/SHA256SUMS", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(shasums))
+	})
+	mux.HandleFunc("/files/SHA256SUMS.sig", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(signature.Bytes())
+	})
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"modules.v1":"/v1/modules/","providers.v1":"/v1/providers/"}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	root := registryRoot
+	t.Cleanup(func() { registryRoot = root })
+	registryRoot = func(string) string { return srv.URL }
+}
+
+// signingKey returns a new key to sign a fake provider release with.
+func signingKey(t *testing.T) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity("ghattest", "", "release@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// armoredPublicKey returns e's public key as the registry's ascii_armor.
+func armoredPublicKey(t *testing.T, e *openpgp.Entity) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestVerifyShasums(t *testing.T) {
+	t.Parallel()
+
+	shasums := []byte(strings.Repeat("ab", 32) + "  terraform-provider-widget_2.1.0_linux_amd64.zip\n")
+	signer, other := signingKey(t), signingKey(t)
+	var signature bytes.Buffer
+	if err := openpgp.DetachSign(&signature, signer, bytes.NewReader(shasums), nil); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(signature.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+
+	pkg := func(keys ...*openpgp.Entity) providerPackage {
+		p := providerPackage{ShasumsURL: srv.URL + "/SHA256SUMS", ShasumsSignatureURL: srv.URL + "/SHA256SUMS.sig"}
+		for _, k := range keys {
+			p.SigningKeys.GPGPublicKeys = append(p.SigningKeys.GPGPublicKeys, struct {
+				KeyID      string `json:"key_id"`
+				ASCIIArmor string `json:"ascii_armor"`
+			}{k.PrimaryKey.KeyIdString(), armoredPublicKey(t, k)})
+		}
+		return p
+	}
+	tampered := append([]byte(strings.Repeat("cd", 32)), shasums[64:]...)
+
+	tests := []struct {
+		name    string
+		pkg     providerPackage
+		shasums []byte
+		wantErr bool
+	}{
+		{"signed", pkg(signer), shasums, false},
+		{"other key", pkg(other), shasums, true},
+		{"no keys", pkg(), shasums, true},
+		{"tampered", pkg(other, signer), tampered, true},
+	}
+	for _, tt := range tests {
+		err := verifyShasums(tt.pkg, tt.shasums)
+		var sigErr *shasumsSignatureError
+		if (err != nil) != tt.wantErr || (err != nil && !errors.As(err, &sigErr)) {
+			t.Errorf("%s: verifyShasums() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFlags_UpdateProvider_Lock(t *testing.T) {
+	fakeProviderRegistry(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "versions.tf")
+	content := "terraform {\n  required_providers {\n    widget = {\n      source  = \"ghattest/widget\"\n" +
+		"      version = \"1.4.0\"\n    }\n  }\n}\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	lock := "provider \"registry.terraform.io/hashicorp/null\" {\n  version = \"3.2.0\"\n  hashes = [\"h1:keep\"]\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, TerraformLockFile), []byte(lock), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Flags{Silent: true, LockPlatforms: []string{"linux_amd64", "plan9_386"}}
+	if err := f.UpdateProvider(file); err != nil {
+		t.Fatalf("UpdateProvider() error = %v", err)
+	}
+
+	locks, err := readTerraformLock(filepath.Join(dir, TerraformLockFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	widget := locks["registry.terraform.io/ghattest/widget"]
+	if widget == nil || widget.Version != "2.1.0" || widget.Constraints != "2.1.0" || len(widget.Hashes) != 3 ||
+		!strings.HasPrefix(widget.Hashes[0], "h1:") || widget.Hashes[1] != "zh:"+strings.Repeat("ab", 32) {
+		t.Errorf("widget lock = %+v", widget)
+	}
+	if null := locks["registry.terraform.io/hashicorp/null"]; null == nil || null.Hashes[0] != "h1:keep" {
+		t.Errorf("null lock = %+v, want it kept", null)
+	}
+}
+
+func TestRenderTerraformLock(t *testing.T) {
+	t.Parallel()
+
+	want := `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.80.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:abc=",
+    "zh:0123",
+  ]
+}
+`
+	path := filepath.Join(t.TempDir(), TerraformLockFile)
+	if err := os.WriteFile(path, []byte(want), 0600); err != nil {
+		t.Fatal(err)
+	}
+	locks, err := readTerraformLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderTerraformLock(locks); got != want {
+		t.Errorf("renderTerraformLock() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFlags_VerifyProviderLocks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tf := "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 5.0\"\n    }\n" +
+		"    random = {\n      source = \"hashicorp/random\"\n    }\n    null = {\n      source = \"hashicorp/null\"\n    }\n  }\n}\n"
+	lock := "provider \"registry.terraform.io/hashicorp/aws\" {\n  version = \"4.67.0\"\n  hashes = []\n}\n" +
+		"provider \"registry.terraform.io/hashicorp/random\" {\n  version = \"3.6.0\"\n  hashes = []\n}\n"
+	file := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(file, []byte(tf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, TerraformLockFile), []byte(lock), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	f := &Flags{Entries: []string{file}}
+	err := f.VerifyProviderLocks(&out)
+	if err == nil {
+		t.Fatal("VerifyProviderLocks() should fail")
+	}
+	got := out.String()
+	if !strings.Contains(got, "hashicorp/aws is locked at 4.67.0") || !strings.Contains(got, "hashicorp/null is not locked") ||
+		strings.Contains(got, "random") {
+		t.Errorf("VerifyProviderLocks() wrote\n%s", got)
+	}
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Service IDs from Terraform's remote service discovery protocol, which
+// registry.terraform.io, registry.opentofu.org, Terraform Cloud/Enterprise
+// and private registries all answer at /.well-known/terraform.json.
+const (
+	serviceModules   = "modules.v1"
+	serviceProviders = "providers.v1"
+)
+
+// registryRoot returns the root URL of a registry host. Tests point it at an
+// httptest server.
+var registryRoot = func(host string) string { return "https://" + host }
+
+type registryServiceError struct {
+	host    string
+	service string
+}
+
+func (e *registryServiceError) Error() string {
+	return fmt.Sprintf("%s does not offer %s in /.well-known/terraform.json", e.host, e.service)
+}
+
+type registrySourceError struct {
+	source string
+}
+
+func (e *registrySourceError) Error() string {
+	return fmt.Sprintf("%s is not a registry source: want [host/]namespace/name/provider", e.source)
+}
+
+// registryService returns the base URL, ending in /, of a service a registry
+// host offers, found through its /.well-known/terraform.json.
+func registryService(host, service string) (string, error) {
+	services, err := resolve("terraform-discovery "+host, func() (map[string]any, error) {
+		var services map[string]any
+		err := registryGetJSON(host, registryRoot(host)+"/.well-known/terraform.json", &services)
+		return services, err
+	})
+	if err != nil {
+		return "", err
+	}
+	raw, ok := services[service].(string)
+	if !ok {
+		return "", &registryServiceError{host: host, service: service}
+	}
+	base, err := url.Parse(registryRoot(host) + "/")
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	u := base.ResolveReference(ref).String()
+	if !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	return u, nil
+}
+
+// registryToken returns the API token for a registry host, from
+// TF_TOKEN_<host> (dots as _, hyphens as __) or, failing that, the
+// credentials terraform login writes. It returns "" if there is none.
+func registryToken(host string) string {
+	env := "TF_TOKEN_" + strings.NewReplacer("-", "__", ".", "_").Replace(host)
+	if token := os.Getenv(env); token != "" {
+		return token
+	}
+
+	data, err := os.ReadFile(terraformCredentialsFile()) // #nosec G304 — the user's own CLI credentials
+	if err != nil {
+		return ""
+	}
+	var creds struct {
+		Credentials map[string]struct {
+			Token string `json:"token"`
+		} `json:"credentials"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return ""
+	}
+	return creds.Credentials[host].Token
+}
+
+// terraformCredentialsFile is where terraform login and tofu login keep
+// registry tokens.
+func terraformCredentialsFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "terraform.d", "credentials.tfrc.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".terraform.d", "credentials.tfrc.json")
+}
+
+// registryGetJSON fetches a registry API URL on host, sending the host's
+// token if it has one, and decodes the JSON response into out.
+func registryGetJSON(host, u string, out any) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := registryToken(host); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := lookupClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query registry: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// registrySource splits a registry module source, such as
+// app.terraform.io/org/vpc/aws//modules/nat, into its host, its
+// namespace/name/provider and its subdirectory. A source without a host is
+// on registry.terraform.io.
+func registrySource(source string) (host, module, subdir string, err error) {
+	module, subdir, _ = strings.Cut(source, "//")
+	parts := strings.Split(module, "/")
+	switch {
+	case len(parts) == 3:
+		return defaultProviderHost, module, subdir, nil
+	case len(parts) == 4 && isRegistryHost(parts[0]):
+		return strings.ToLower(parts[0]), strings.Join(parts[1:], "/"), subdir, nil
+	}
+	return "", "", "", &registrySourceError{source: source}
+}
+
+// isRegistryHost reports whether the first part of a module source is a
+// hostname rather than a namespace.
+func isRegistryHost(s string) bool {
+	return strings.Contains(s, ".") && !strings.Contains(s, ":")
+}
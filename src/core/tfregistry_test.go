@@ -0,0 +1,124 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakePrivateRegistries serves tfe.example.com, whose module registry needs
+// the token "s3cret", and registry.opentofu.org, each with version 1.3.0 of
+// acme/vpc/aws or acme/widget.
+func fakePrivateRegistries(t *testing.T) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tfe.example.com/.well-known/terraform.json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"modules.v1":"/api/registry/v1/modules/"}`))
+	})
+	mux.HandleFunc("/api/registry/v1/modules/acme/vpc/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"modules":[{"versions":[{"version":"1.0.0"},{"version":"1.3.0"}]}]}`))
+	})
+	mux.HandleFunc("/registry.opentofu.org/.well-known/terraform.json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"providers.v1":"providers/"}`))
+	})
+	mux.HandleFunc("/registry.opentofu.org/providers/acme/widget/versions", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.3.0"},{"version":"1.0.0"}]}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	root := registryRoot
+	t.Cleanup(func() { registryRoot = root })
+	registryRoot = func(host string) string { return srv.URL + "/" + host }
+}
+
+func TestRegistryToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TF_TOKEN_tfe_my__org_example_com", "from-env")
+	creds := `{"credentials":{"app.terraform.io":{"token":"from-file"}}}`
+	if err := os.MkdirAll(filepath.Join(home, ".terraform.d"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".terraform.d", "credentials.tfrc.json"), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"tfe.my-org.example.com": "from-env",
+		"app.terraform.io":       "from-file",
+		"registry.terraform.io":  "",
+	}
+	for host, want := range tests {
+		if got := registryToken(host); got != want {
+			t.Errorf("registryToken(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestRegistrySource(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		source, host, module, subdir string
+	}{
+		{"terraform-aws-modules/vpc/aws", defaultProviderHost, "terraform-aws-modules/vpc/aws", ""},
+		{"app.terraform.io/acme/vpc/aws//modules/nat", "app.terraform.io", "acme/vpc/aws", "modules/nat"},
+	}
+	for _, tt := range tests {
+		host, module, subdir, err := registrySource(tt.source)
+		if err != nil || host != tt.host || module != tt.module || subdir != tt.subdir {
+			t.Errorf("registrySource(%q) = %q, %q, %q, %v", tt.source, host, module, subdir, err)
+		}
+	}
+	if _, _, _, err := registrySource("acme/vpc/aws/extra"); err == nil {
+		t.Error("registrySource() should reject four parts without a host")
+	}
+}
+
+func TestFlags_UpdateModule_PrivateRegistry(t *testing.T) {
+	fakePrivateRegistries(t)
+	t.Setenv("TF_TOKEN_tfe_example_com", "s3cret")
+
+	file := filepath.Join(t.TempDir(), "main.tf")
+	content := "module \"vpc\" {\n  source  = \"tfe.example.com/acme/vpc/aws\"\n  version = \"1.0.0\"\n}\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var days uint
+	f := &Flags{Silent: true, Days: &days}
+	if err := f.UpdateModule(file); err != nil {
+		t.Fatalf("UpdateModule() error = %v", err)
+	}
+	got, _ := os.ReadFile(file)
+	if !strings.Contains(string(got), "source  = \"tfe.example.com/acme/vpc/aws\"") || !strings.Contains(string(got), "version = \"1.3.0\"") {
+		t.Errorf("UpdateModule() wrote\n%s", got)
+	}
+}
+
+func TestFlags_UpdateProvider_OpenTofu(t *testing.T) {
+	fakePrivateRegistries(t)
+
+	file := filepath.Join(t.TempDir(), "versions.tf")
+	content := "terraform {\n  required_providers {\n    widget = {\n      source  = \"registry.opentofu.org/acme/widget\"\n" +
+		"      version = \"1.0.0\"\n    }\n  }\n}\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Flags{Silent: true, Cooldown: map[string]uint{SourceProvider: 5}}
+	if err := f.UpdateProvider(file); err != nil {
+		t.Fatalf("UpdateProvider() error = %v", err)
+	}
+	got, _ := os.ReadFile(file)
+	if !strings.Contains(string(got), "version = \"1.3.0\"") {
+		t.Errorf("UpdateProvider() wrote\n%s", got)
+	}
+}
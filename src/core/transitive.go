@@ -0,0 +1,156 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// maxTransitiveDepth bounds how far --transitive follows composite actions
+// that call other composite actions.
+const maxTransitiveDepth = 5
+
+// transitiveRef is an action or image pulled in through the action.yml of an
+// action the repository uses, rather than named in its own files.
+type transitiveRef struct {
+	chain  []string // the uses: refs that led here, outermost first
+	ref    string   // owner/repo[/path]@ref or docker://image
+	pinned bool
+}
+
+// String renders the ref with the chain that reached it.
+func (t transitiveRef) String() string {
+	return strings.Join(append(slices.Clone(t.chain), t.ref), " → ")
+}
+
+// actionUses returns the uses: refs an action.yml's composite steps call,
+// skipping suppressed lines, plus a docker:// runs.image.
+func actionUses(body []byte) []string {
+	var refs []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if m := usesRe.FindStringSubmatch(line); m != nil && !isSuppressed(line) {
+			refs = append(refs, strings.TrimSpace(m[1]))
+		}
+	}
+	var action struct {
+		Runs struct {
+			Image string `yaml:"image"`
+		} `yaml:"runs"`
+	}
+	if yaml.Unmarshal(body, &action) == nil && strings.HasPrefix(action.Runs.Image, "docker://") {
+		refs = append(refs, action.Runs.Image)
+	}
+	return refs
+}
+
+// usesRefs returns the external action refs in a workflow or action.yml —
+// the roots a transitive walk starts from.
+func usesRefs(body []byte) []string {
+	var refs []string
+	for _, ref := range actionUses(body) {
+		if !strings.HasPrefix(ref, "./") && !strings.HasPrefix(ref, "docker://") {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// fetchActionManifest fetches the action.yml (or action.yaml) of the action
+// at ref, as of the version the ref names. A nil cache fetches it afresh.
+func fetchActionManifest(token, ref string, cache *Cache) ([]byte, error) {
+	return resolve("action.yml "+ref, func() ([]byte, error) {
+		action, version, _ := strings.Cut(ref, "@")
+		_, path := splitUsesHost(action)
+		dir := ""
+		if parts := strings.SplitN(path, "/", 3); len(parts) == 3 {
+			dir = parts[2] + "/"
+		}
+
+		var lastErr error
+		for _, name := range []string{"action.yml", "action.yaml"} {
+			body, err := GetGithubBodyWithCache(token, repoAPI(action)+"/contents/"+dir+name+"?ref="+url.QueryEscape(version), cache)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			m, _ := body.(map[string]interface{})
+			enc, _ := m["content"].(string)
+			return base64.StdEncoding.DecodeString(strings.ReplaceAll(enc, "\n", ""))
+		}
+		return nil, lastErr
+	})
+}
+
+// walkTransitive returns every action and image that ref pulls in through
+// its action.yml and theirs, depth first. seen holds the refs already walked
+// so each is reported once however many chains reach it. Refs to ./ paths in
+// an upstream action.yml point into the caller's checkout and are not
+// followed.
+func walkTransitive(ref, token string, seen map[string]bool) []transitiveRef {
+	return walkActionRefs([]string{ref}, ref, token, seen)
+}
+
+func walkActionRefs(chain []string, ref, token string, seen map[string]bool) []transitiveRef {
+	action, version, _ := strings.Cut(ref, "@")
+	if len(chain) > maxTransitiveDepth || version == "" || strings.HasPrefix(version, "$") || isReusableWorkflow(action) {
+		return nil
+	}
+	body, err := fetchActionManifest(token, ref, nil)
+	if err != nil {
+		log.Debug().Str("action", ref).Err(err).Msg("no action.yml to walk")
+		return nil
+	}
+
+	var out []transitiveRef
+	for _, sub := range actionUses(body) {
+		if strings.HasPrefix(sub, "./") || seen[sub] {
+			continue
+		}
+		seen[sub] = true
+		if image, ok := strings.CutPrefix(sub, "docker://"); ok {
+			out = append(out, transitiveRef{chain: chain, ref: sub, pinned: strings.Contains(image, "@sha256:")})
+			continue
+		}
+		_, subVersion, _ := strings.Cut(sub, "@")
+		out = append(out, transitiveRef{chain: chain, ref: sub, pinned: shaRe.MatchString(subVersion)})
+		out = append(out, walkActionRefs(append(slices.Clone(chain), sub), sub, token, seen)...)
+	}
+	return out
+}
+
+// checkTransitivePinned fails when anything an action pulls in through its
+// action.yml is not pinned to a SHA or digest.
+func checkTransitivePinned(refs []transitiveRef) checkResult {
+	if len(refs) == 0 {
+		return checkResult{"transitive-pinned", checkSkip, "no composite steps"}
+	}
+	pinned := 0
+	for _, t := range refs {
+		if t.pinned {
+			pinned++
+		}
+	}
+	if pinned == len(refs) {
+		return checkResult{"transitive-pinned", checkPass, fmt.Sprintf("%d/%d", pinned, len(refs))}
+	}
+	return checkResult{"transitive-pinned", checkFail, fmt.Sprintf("%d/%d", pinned, len(refs))}
+}
+
+// warnTransitive logs every unpinned action or image that the actions in a
+// workflow pull in through their own action.yml. swot can only pin the
+// workflow's own refs; these need the upstream action to pin its steps.
+func (f *Flags) warnTransitive(file, content string) {
+	seen := map[string]bool{}
+	for _, ref := range usesRefs([]byte(content)) {
+		for _, t := range walkTransitive(ref, f.GitHubToken, seen) {
+			if !t.pinned {
+				log.Warn().Str("file", file).Msgf("transitive ref is not pinned: %s", t)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeComposite serves org/composite@v1, whose action.yml calls
+// actions/cache@v3 and org/inner, whose own action.yml pins actions/checkout
+// but runs an unpinned docker:// image.
+func fakeComposite(t *testing.T) {
+	t.Helper()
+	files := map[string]string{
+		"/api/v3/repos/org/composite/contents/action.yml": "runs:\n  using: composite\n  steps:\n" +
+			"    - uses: actions/cache@v3\n" +
+			"    - uses: ./local\n" +
+			"    - uses: org/inner/sub@" + includeSHA + " # v2.0.0\n" +
+			"    - uses: actions/setup-go@v5 # ghat:suppress\n",
+		"/api/v3/repos/org/inner/contents/sub/action.yml": "runs:\n  using: composite\n  steps:\n" +
+			"    - uses: actions/checkout@" + includeSHA + " # v4.2.2\n" +
+			"    - uses: docker://alpine:3.20\n",
+		"/api/v3/repos/actions/cache/contents/action.yaml": "runs:\n  using: docker\n  image: docker://node:20\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"content":%q,"encoding":"base64"}`, base64.StdEncoding.EncodeToString([]byte(body)))
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL + "/api/v3")
+}
+
+func TestWalkTransitive(t *testing.T) {
+	fakeComposite(t)
+
+	var got []string
+	for _, ref := range walkTransitive("org/composite@v1", "", map[string]bool{}) {
+		got = append(got, fmt.Sprintf("%s %v", ref, ref.pinned))
+	}
+	want := []string{
+		"org/composite@v1 → actions/cache@v3 false",
+		"org/composite@v1 → actions/cache@v3 → docker://node:20 false",
+		"org/composite@v1 → org/inner/sub@" + includeSHA + " true",
+		"org/composite@v1 → org/inner/sub@" + includeSHA + " → actions/checkout@" + includeSHA + " true",
+		"org/composite@v1 → org/inner/sub@" + includeSHA + " → docker://alpine:3.20 false",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walkTransitive() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCheckTransitivePinned(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		refs []transitiveRef
+		want checkResult
+	}{
+		{name: "none", want: checkResult{"transitive-pinned", checkSkip, "no composite steps"}},
+		{name: "pinned", refs: []transitiveRef{{pinned: true}}, want: checkResult{"transitive-pinned", checkPass, "1/1"}},
+		{name: "unpinned", refs: []transitiveRef{{pinned: true}, {}}, want: checkResult{"transitive-pinned", checkFail, "1/2"}},
+	}
+	for _, tt := range tests {
+		if got := checkTransitivePinned(tt.refs); got != tt.want {
+			t.Errorf("%s: checkTransitivePinned() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTransitiveDep(t *testing.T) {
+	t.Parallel()
+
+	d, ok := transitiveDep(transitiveRef{chain: []string{"org/composite@v1"}, ref: "org/inner/sub@" + includeSHA})
+	if !ok || d.slug() != "org/inner" || d.pinnedSHA != includeSHA || d.label != "org/composite@v1 → org/inner/sub@"+includeSHA {
+		t.Errorf("transitiveDep() = %+v, %v", d, ok)
+	}
+	if _, ok := transitiveDep(transitiveRef{ref: "docker://alpine:3.20"}); ok {
+		t.Error("transitiveDep() should not audit an image as a repository")
+	}
+}
+
+func TestCollectGHADeps_LocalActions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	action := filepath.Join(dir, ".github", "actions", "build", "action.yml")
+	if err := os.MkdirAll(filepath.Dir(action), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(action, []byte("runs:\n  using: composite\n  steps:\n    - uses: org/composite@v1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Flags{Entries: []string{action}}
+	if deps := f.collectGHADeps(); len(deps) != 0 {
+		t.Errorf("collectGHADeps() without --transitive = %+v, want in-repo actions ignored", deps)
+	}
+	f.Transitive = true
+	if deps := f.collectGHADeps(); len(deps) != 1 || deps[0].uses != "org/composite@v1" {
+		t.Errorf("collectGHADeps() with --transitive = %+v, want org/composite@v1", deps)
+	}
+}
+
+func TestAuditTransitive_SharedAction(t *testing.T) {
+	fakeComposite(t)
+
+	// org/composite reaches org/inner/sub too; auditing it first must not
+	// leave org/inner/sub with an empty walk.
+	composite := dep{owner: "org", repo: "composite", uses: "org/composite@v1", file: "ci.yml", line: 3}
+	inner := dep{owner: "org", repo: "inner", uses: "org/inner/sub@" + includeSHA}
+	if check, _, found := auditTransitive(composite, ""); check.outcome != checkFail || len(found) == 0 {
+		t.Fatalf("auditTransitive(composite) = %+v, %v", check, found)
+	} else if found[0].file != "ci.yml" || found[0].line != 3 {
+		t.Errorf("found dep %+v, want the location of org/composite", found[0])
+	}
+
+	check, unpinned, _ := auditTransitive(inner, "")
+	want := []string{"transitive: org/inner/sub@" + includeSHA + " → docker://alpine:3.20"}
+	if check.outcome != checkFail || !reflect.DeepEqual(unpinned, want) {
+		t.Errorf("auditTransitive(inner) = %+v, %q, want a failure for %q", check, unpinned, want)
+	}
+}
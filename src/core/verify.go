@@ -0,0 +1,258 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+type verifyFailedError struct {
+	count int
+}
+
+func (e *verifyFailedError) Error() string {
+	return fmt.Sprintf("%d pinned SHAs failed verification", e.count)
+}
+
+// githubRef is a branch or tag and the commit it points at.
+type githubRef struct {
+	name string
+	sha  string
+}
+
+// listGithubRefs reads every page of a /branches or /tags listing.
+func listGithubRefs(token, apiURL string) ([]githubRef, error) {
+	var refs []githubRef
+	for next := apiURL; next != ""; {
+		body, n, err := getPagedGithubBody(token, next)
+		if err != nil {
+			return nil, err
+		}
+		items, _ := body.([]interface{})
+		for _, item := range items {
+			m, _ := item.(map[string]interface{})
+			name, _ := m["name"].(string)
+			commit, _ := m["commit"].(map[string]interface{})
+			sha, _ := commit["sha"].(string)
+			if name != "" && sha != "" {
+				refs = append(refs, githubRef{name: name, sha: sha})
+			}
+		}
+		next = n
+	}
+	return refs, nil
+}
+
+// commitReachable reports which branch or tag of repo contains sha, or ""
+// when none does. GitHub serves any commit in a fork network under the
+// parent repo's name, so a SHA that resolves is not necessarily the repo's
+// own: an "impostor" commit pushed to a fork is reachable from none of the
+// parent's refs.
+func commitReachable(repo, sha, token string) (string, error) {
+	tags, err := listGithubRefs(token, repoAPI(repo)+"/tags?per_page=100")
+	if err != nil {
+		return "", err
+	}
+	branches, err := listGithubRefs(token, repoAPI(repo)+"/branches?per_page=100")
+	if err != nil {
+		return "", err
+	}
+	for _, r := range append(tags, branches...) {
+		if r.sha == sha {
+			return r.name, nil
+		}
+	}
+
+	// Not the tip of anything: ask whether each ref is at or ahead of sha,
+	// default branch first since that is where nearly every pin lives.
+	if body, err := GetGithubBody(token, repoAPI(repo)); err == nil {
+		m, _ := body.(map[string]interface{})
+		if def, _ := m["default_branch"].(string); def != "" {
+			ordered := []githubRef{{name: def}}
+			for _, b := range branches {
+				if b.name != def {
+					ordered = append(ordered, b)
+				}
+			}
+			branches = ordered
+		}
+	}
+	for _, r := range append(branches, tags...) {
+		body, err := GetGithubBody(token, repoAPI(repo)+"/compare/"+url.PathEscape(r.name)+"..."+sha)
+		if err != nil {
+			continue
+		}
+		m, _ := body.(map[string]interface{})
+		if status, _ := m["status"].(string); status == "behind" || status == "identical" {
+			return r.name, nil
+		}
+	}
+	return "", nil
+}
+
+// pinCheck is the outcome of verifying one repo@sha # tag pin.
+type pinCheck struct {
+	reachableFrom string // branch or tag containing sha; "" for an impostor
+	tagSHA        string // what the # tag comment resolves to; "" without a comment
+	err           error
+}
+
+// pinVerifier memoises verifyPin across the files of a run.
+type pinVerifier struct {
+	token string
+	mu    sync.Mutex
+	seen  map[string]pinCheck
+}
+
+func newPinVerifier(token string) *pinVerifier {
+	return &pinVerifier{token: token, seen: map[string]pinCheck{}}
+}
+
+// verifyPin checks that sha belongs to repo and that tag, when given,
+// resolves to sha.
+func (v *pinVerifier) verifyPin(repo, sha, tag string) pinCheck {
+	key := repo + "@" + sha + "#" + tag
+	v.mu.Lock()
+	if c, ok := v.seen[key]; ok {
+		v.mu.Unlock()
+		return c
+	}
+	v.mu.Unlock()
+
+	var c pinCheck
+	c.reachableFrom, c.err = commitReachable(repo, sha, v.token)
+	if c.err == nil && tag != "" {
+		c.tagSHA, c.err = resolveTagSHA(repo, tag, v.token)
+	}
+
+	v.mu.Lock()
+	v.seen[key] = c
+	v.mu.Unlock()
+	return c
+}
+
+// pinFindings turns a pinCheck into impostor and comment-mismatch findings
+// at line.
+func pinFindings(c pinCheck, repo, sha, tag string, line int) []Finding {
+	var findings []Finding
+	if c.err != nil {
+		return []Finding{{Rule: RuleImpostorCommit, Level: LevelNote, Line: line,
+			Message: fmt.Sprintf("could not verify %s@%s: %s", repo, sha, c.err)}}
+	}
+	if c.reachableFrom == "" {
+		findings = append(findings, Finding{Rule: RuleImpostorCommit, Level: LevelError, Line: line,
+			Message: fmt.Sprintf("%s@%s is not on any branch or tag of %s; it may be a commit from a fork", repo, sha, repo)})
+	}
+	if tag != "" && c.tagSHA != sha {
+		findings = append(findings, Finding{Rule: RulePinComment, Level: LevelError, Line: line,
+			Message: fmt.Sprintf("%s is pinned to %s but its comment says %s, which resolves to %s", repo, sha, tag, c.tagSHA)})
+	}
+	return findings
+}
+
+// VerifyFindings checks every SHA-pinned uses: step and pre-commit rev hosted
+// on GitHub: that the commit is on a branch or tag of the named repository,
+// and that any "# tag" comment resolves to the pinned SHA.
+func (f *Flags) VerifyFindings() []Finding {
+	v := newPinVerifier(f.GitHubToken)
+	var findings []Finding
+
+	for _, file := range f.Entries {
+		kind, ok := ClassifyManifest(file)
+		if !ok || (kind != ManifestGHA && kind != ManifestPreCommit) {
+			continue
+		}
+		content, err := os.ReadFile(file) // #nosec G304
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(content), "\n")
+
+		var fileFindings []Finding
+		if kind == ManifestGHA {
+			for i, line := range lines {
+				m := usesRe.FindStringSubmatch(line)
+				if m == nil {
+					continue
+				}
+				path, sha, _ := strings.Cut(strings.TrimSpace(m[1]), "@")
+				if !shaRe.MatchString(sha) || strings.HasPrefix(path, "./") || strings.HasPrefix(path, "docker://") {
+					continue
+				}
+				repo := ownerRepo(path)
+				tag := refTagComment(line)
+				fileFindings = append(fileFindings, pinFindings(v.verifyPin(repo, sha, tag), repo, sha, tag, i+1)...)
+			}
+		} else {
+			for _, ref := range ParseManifest(kind, content) {
+				owner, name, ok := githubOwnerRepo(ref.Name)
+				if !ok || !shaRe.MatchString(ref.Version) {
+					continue
+				}
+				line := ref.VersionLine
+				if line == 0 {
+					line = ref.Line
+				}
+				repo := owner + "/" + name
+				tag := refTagComment(lineAt(lines, line))
+				fileFindings = append(fileFindings, pinFindings(v.verifyPin(repo, ref.Version, tag), repo, ref.Version, tag, line)...)
+			}
+		}
+
+		for i := range fileFindings {
+			fileFindings[i].File = file
+		}
+		findings = append(findings, markSuppressed(content, fileFindings)...)
+	}
+	return findings
+}
+
+// Verify reports pinned SHAs that are impostor commits or disagree with
+// their # tag comment, as text or SARIF, and fails if there are any.
+func (f *Flags) Verify() error {
+	if err := validateFormat(f.Format); err != nil {
+		return err
+	}
+
+	findings := f.VerifyFindings()
+
+	var w io.Writer = os.Stdout
+	if f.Output != "" {
+		file, err := os.Create(f.Output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", f.Output, err)
+		}
+		defer file.Close() //nolint:errcheck
+		w = file
+	}
+
+	if f.Format == FormatSARIF {
+		if err := WriteSARIF(w, f.Directory, findings); err != nil {
+			return err
+		}
+	} else {
+		writeLintText(w, f.Directory, findings)
+	}
+
+	var failing int
+	for _, finding := range findings {
+		if !finding.Suppressed && finding.Level == LevelError {
+			failing++
+		}
+	}
+	if failing > 0 {
+		return &verifyFailedError{count: failing}
+	}
+	return nil
+}
+
+// shortSHA abbreviates a commit SHA the way git does.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	verifyTagSHA      = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	verifyMainSHA     = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	verifyAncestorSHA = "cccccccccccccccccccccccccccccccccccccccc"
+	verifyForkSHA     = "dddddddddddddddddddddddddddddddddddddddd"
+)
+
+// fakeForkNetwork serves actions/checkout with tag v4.2.2 at verifyTagSHA,
+// main at verifyMainSHA with verifyAncestorSHA in its history, and
+// verifyForkSHA reachable from neither.
+func fakeForkNetwork(t *testing.T) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/actions/checkout/tags", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `[{"name":"v4.2.2","commit":{"sha":"%s"}}]`, verifyTagSHA)
+	})
+	mux.HandleFunc("/api/v3/repos/actions/checkout/branches", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `[{"name":"main","commit":{"sha":"%s"}}]`, verifyMainSHA)
+	})
+	mux.HandleFunc("/api/v3/repos/actions/checkout", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"default_branch":"main"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/actions/checkout/compare/", func(w http.ResponseWriter, r *http.Request) {
+		status := "diverged"
+		if strings.HasSuffix(r.URL.Path, "main..."+verifyAncestorSHA) {
+			status = "behind"
+		}
+		_, _ = fmt.Fprintf(w, `{"status":"%s"}`, status)
+	})
+	mux.HandleFunc("/api/v3/repos/actions/checkout/git/ref/tags/v4.2.2", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"object":{"sha":"%s","type":"commit"}}`, verifyTagSHA)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { SetGitHubAPIURL("") })
+	SetGitHubAPIURL(srv.URL + "/api/v3")
+}
+
+func TestCommitReachable(t *testing.T) {
+	fakeForkNetwork(t)
+
+	tests := []struct {
+		sha  string
+		want string
+	}{
+		{verifyTagSHA, "v4.2.2"},
+		{verifyMainSHA, "main"},
+		{verifyAncestorSHA, "main"},
+		{verifyForkSHA, ""},
+	}
+	for _, tt := range tests {
+		got, err := commitReachable("actions/checkout", tt.sha, "")
+		if err != nil || got != tt.want {
+			t.Errorf("commitReachable(%s) = (%q, %v), want %q", tt.sha, got, err, tt.want)
+		}
+	}
+}
+
+func TestFlags_Verify(t *testing.T) {
+	fakeForkNetwork(t)
+
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	if err := os.MkdirAll(filepath.Dir(workflow), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := "jobs:\n  b:\n    steps:\n" +
+		"      - uses: actions/checkout@" + verifyTagSHA + " # v4.2.2\n" +
+		"      - uses: actions/checkout@" + verifyAncestorSHA + "\n" +
+		"      - uses: actions/checkout@" + verifyForkSHA + " # v4.2.2\n" +
+		"      - uses: actions/checkout@" + verifyForkSHA + " # ghat:suppress\n" +
+		"      - uses: actions/checkout@v4\n"
+	if err := os.WriteFile(workflow, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "verify.txt")
+	f := &Flags{Directory: dir, Output: out}
+	f.Entries, _ = GetFiles(dir)
+
+	err := f.Verify()
+	if got := fmt.Sprintf("%T", err); got != "*core.verifyFailedError" {
+		t.Fatalf("Verify() error = %v (%s), want *core.verifyFailedError", err, got)
+	}
+	got, _ := os.ReadFile(out)
+	want := ".github/workflows/ci.yml:6: error: actions/checkout@" + verifyForkSHA +
+		" is not on any branch or tag of actions/checkout; it may be a commit from a fork [ghat-impostor-commit]\n" +
+		".github/workflows/ci.yml:6: error: actions/checkout is pinned to " + verifyForkSHA +
+		" but its comment says v4.2.2, which resolves to " + verifyTagSHA + " [ghat-pin-comment]\n" +
+		"1 suppressed\n"
+	if string(got) != want {
+		t.Errorf("Verify() output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCheckReachablePin(t *testing.T) {
+	fakeForkNetwork(t)
+
+	tests := []struct {
+		name    string
+		sha     string
+		tag     string
+		outcome checkOutcome
+	}{
+		{name: "no pin", outcome: checkSkip},
+		{name: "tagged", sha: verifyTagSHA, tag: "v4.2.2", outcome: checkPass},
+		{name: "impostor", sha: verifyForkSHA, outcome: checkFail},
+		{name: "comment mismatch", sha: verifyMainSHA, tag: "v4.2.2", outcome: checkFail},
+	}
+	for _, tt := range tests {
+		d := dep{source: SourceGHA, owner: "actions", repo: "checkout", pinnedSHA: tt.sha, pinnedTag: tt.tag}
+		if got := checkReachablePin(d, ""); got.outcome != tt.outcome {
+			t.Errorf("%s: checkReachablePin() = %+v, want outcome %v", tt.name, got, tt.outcome)
+		}
+	}
+}
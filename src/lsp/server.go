@@ -7,34 +7,38 @@ package lsp
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/jameswoolfenden/ghat/src/core"
 )
 
-// moduleRefSHARe matches a git module source already pinned to an immutable
-// commit SHA via ?ref=<40-hex>, the format ghat itself writes (with the tag
-// recorded as a trailing "# vX.Y.Z" comment rather than a version attribute).
-var moduleRefSHARe = regexp.MustCompile(`\?ref=[0-9a-f]{40}\b`)
+// inputDebounce is how long checkInputs waits for edits to stop before it
+// fetches action.yml files.
+var inputDebounce = 500 * time.Millisecond
 
 // Server is a stateful stdio LSP server.
 type Server struct {
-	token string
-	cache *core.Cache
+	token     string
+	cache     *core.Cache
+	manifests *core.ActionManifests // action.yml lookups shared by every input check
 
 	mu         sync.Mutex
 	docs       map[string][]byte        // uri → current content
 	deps       map[string][]core.DepRef // uri → dep refs from ParseManifest
 	auditDiags map[string][]diagnostic  // uri → diagnostics appended by executeCommand
+	inputDiags map[string][]diagnostic  // uri → action.yml input checks, fetched in the background
+	inputRuns  map[string]*inputRun     // uri → the pending or running input check
 
 	wmu sync.Mutex // serialises all writes to the stdout writer
 }
@@ -45,9 +49,12 @@ func New(token string, cache *core.Cache) *Server {
 	return &Server{
 		token:      token,
 		cache:      cache,
+		manifests:  core.NewActionManifests(token, cache),
 		docs:       make(map[string][]byte),
 		deps:       make(map[string][]core.DepRef),
 		auditDiags: make(map[string][]diagnostic),
+		inputDiags: make(map[string][]diagnostic),
+		inputRuns:  make(map[string]*inputRun),
 	}
 }
 
@@ -199,7 +206,7 @@ func (s *Server) handleInitialize(w io.Writer, msg rpcMsg) error {
 				"resolveProvider": false,
 			},
 			"executeCommandProvider": map[string]interface{}{
-				"commands": []string{"ghat.audit", "ghat.auditFile", "ghat.pin", "ghat.update", "ghat.suppress"},
+				"commands": []string{"ghat.audit", "ghat.auditFile", "ghat.pin", "ghat.update", "ghat.suppress", "ghat.fixInjection"},
 			},
 		},
 		"serverInfo": map[string]string{"name": "ghat-lsp", "version": "0.1"},
@@ -224,7 +231,11 @@ func (s *Server) handleDidOpen(w io.Writer, msg rpcMsg) error {
 	s.mu.Lock()
 	s.docs[p.TextDocument.URI] = content
 	s.mu.Unlock()
-	return s.analyze(w, p.TextDocument.URI, content)
+	if err := s.analyze(w, p.TextDocument.URI, content); err != nil {
+		return err
+	}
+	s.checkInputs(w, p.TextDocument.URI, content)
+	return nil
 }
 
 // ---- didChange --------------------------------------------------------------
@@ -250,8 +261,13 @@ func (s *Server) handleDidChange(w io.Writer, msg rpcMsg) error {
 	s.mu.Lock()
 	s.docs[p.TextDocument.URI] = content
 	delete(s.auditDiags, p.TextDocument.URI) // clear stale audit results on edit
+	delete(s.inputDiags, p.TextDocument.URI) // and input checks, whose lines may have moved
 	s.mu.Unlock()
-	return s.analyze(w, p.TextDocument.URI, content)
+	if err := s.analyze(w, p.TextDocument.URI, content); err != nil {
+		return err
+	}
+	s.checkInputs(w, p.TextDocument.URI, content)
+	return nil
 }
 
 // ---- didClose ---------------------------------------------------------------
@@ -269,6 +285,8 @@ func (s *Server) handleDidClose(msg rpcMsg) error {
 	delete(s.docs, p.TextDocument.URI)
 	delete(s.deps, p.TextDocument.URI)
 	delete(s.auditDiags, p.TextDocument.URI)
+	delete(s.inputDiags, p.TextDocument.URI)
+	s.stopInputRun(p.TextDocument.URI)
 	s.mu.Unlock()
 	return nil
 }
@@ -277,7 +295,8 @@ func (s *Server) handleDidClose(msg rpcMsg) error {
 
 type diagnostic struct {
 	Range    diagRange `json:"range"`
-	Severity int       `json:"severity"` // 1=Error 2=Warning 3=Info 4=Hint
+	Severity int       `json:"severity"`       // 1=Error 2=Warning 3=Info 4=Hint
+	Code     string    `json:"code,omitempty"` // stable ghat rule id, shared with SARIF output
 	Message  string    `json:"message"`
 	Source   string    `json:"source"`
 }
@@ -331,153 +350,113 @@ func (s *Server) analyze(w io.Writer, uri string, content []byte) error {
 	}
 }
 
-// ghaStaticDiags runs AnalyzeWorkflow and converts findings to LSP diagnostics.
+// ghaStaticDiags runs the core workflow checks and converts their findings
+// to LSP diagnostics.
 func ghaStaticDiags(filename string, content []byte) []diagnostic {
-	wa := core.AnalyzeWorkflow(filename, content)
-	var diags []diagnostic
+	return findingDiags(core.WorkflowFindings(filename, content))
+}
 
-	if !wa.HasPermissions {
-		diags = append(diags, fileDiag(2, "workflow missing top-level permissions: block (default GITHUB_TOKEN is write-all)"))
-	} else if wa.IsWriteAll {
-		diags = append(diags, fileDiag(1, "permissions: write-all grants the GITHUB_TOKEN full repository write access"))
-	}
-	if wa.HasDangerousTrigger {
-		diags = append(diags, fileDiag(1, wa.DangerousTriggerDesc))
+// actionInputDiags checks each action a workflow calls against the
+// action.yml of the version it pins. It fetches over the network, through
+// manifests, and returns nil once ctx is cancelled.
+func actionInputDiags(ctx context.Context, content []byte, manifests *core.ActionManifests) []diagnostic {
+	return findingDiags(manifests.Findings(ctx, content))
+}
+
+// inputRun is a scheduled input check for one document.
+type inputRun struct {
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+// checkInputs runs actionInputDiags for a workflow in the background once
+// edits have paused for inputDebounce, and republishes its diagnostics. A
+// newer edit cancels the run it supersedes, pending or in flight.
+func (s *Server) checkInputs(w io.Writer, uri string, content []byte) {
+	bw, ok := w.(*bufio.Writer)
+	if kind, _ := classifyURI(uri); !ok || kind != core.ManifestGHA {
+		return
 	}
-	for _, step := range wa.Steps {
-		if step.Suppressed || step.IsSHAPinned {
-			continue
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &inputRun{cancel: cancel}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopInputRun(uri)
+	s.inputRuns[uri] = run
+	run.timer = time.AfterFunc(inputDebounce, func() {
+		diags := actionInputDiags(ctx, content, s.manifests)
+		s.mu.Lock()
+		current := s.inputRuns[uri] == run && ctx.Err() == nil && bytes.Equal(s.docs[uri], content)
+		if current {
+			s.inputDiags[uri] = diags
+			delete(s.inputRuns, uri)
 		}
-		ref := step.Action
-		if step.Ref != "" {
-			ref = step.Action + "@" + step.Ref
+		s.mu.Unlock()
+		if current {
+			_ = s.lockedWrite(bw, func(w io.Writer) error { return s.refreshDiags(w, uri) })
 		}
-		line := findUsesLine(content, step.Action)
-		diags = append(diags, lineDiag(line, 2, ref+" is not pinned to an immutable SHA"))
+	})
+}
+
+// stopInputRun cancels the input check scheduled for uri, if any. The
+// caller holds s.mu.
+func (s *Server) stopInputRun(uri string) {
+	if run := s.inputRuns[uri]; run != nil {
+		run.timer.Stop()
+		run.cancel()
+		delete(s.inputRuns, uri)
 	}
-	return diags
 }
 
 // dockerfileStaticDiags warns on FROM lines not pinned to a digest.
 func dockerfileStaticDiags(refs []core.DepRef) []diagnostic {
-	var diags []diagnostic
-	for _, ref := range refs {
-		if strings.HasPrefix(ref.Version, "sha256:") {
-			continue
-		}
-		tag := ref.Version
-		if tag == "" {
-			tag = "latest"
-		}
-		diags = append(diags, lineDiag(ref.Line, 2,
-			ref.Name+":"+tag+" is not pinned to an immutable digest (@sha256:...)"))
-	}
-	return diags
+	return findingDiags(core.ManifestFindings(core.ManifestDockerfile, refs))
 }
 
 // gitlabStaticDiags warns on GitLab CI refs that are not immutably pinned.
 // Component/project include refs should be commit-SHA pinned; image refs should be digest-pinned.
 func gitlabStaticDiags(refs []core.DepRef) []diagnostic {
-	var diags []diagnostic
-	for _, ref := range refs {
-		switch ref.Ecosystem {
-		case core.SourceGitLabComponent:
-			if core.IsSHAPinnedRef(ref.Version) {
-				continue
-			}
-			diags = append(diags, lineDiag(ref.Line, 2,
-				ref.Name+"@"+ref.Version+" is not pinned to an immutable commit SHA"))
-		case core.SourceGitLab:
-			if strings.HasPrefix(ref.Version, "sha256:") {
-				continue
-			}
-			tag := ref.Version
-			if tag == "" {
-				tag = "latest"
-			}
-			diags = append(diags, lineDiag(ref.Line, 2,
-				ref.Name+":"+tag+" is not pinned to an immutable digest (@sha256:...)"))
-		}
-	}
-	return diags
+	return findingDiags(core.ManifestFindings(core.ManifestGitLab, refs))
 }
 
 // preCommitStaticDiags warns on any repo whose rev is not a SHA pin.
 func preCommitStaticDiags(refs []core.DepRef) []diagnostic {
-	var diags []diagnostic
-	for _, ref := range refs {
-		if core.IsSHAPinnedRef(ref.Version) {
-			continue
-		}
-		diags = append(diags, lineDiag(ref.Line, 2,
-			ref.Name+" rev "+ref.Version+" is not pinned to an immutable SHA"))
-	}
-	return diags
+	return findingDiags(core.ManifestFindings(core.ManifestPreCommit, refs))
 }
 
 // imageStaticDiags warns on container images not pinned to an immutable digest.
 // Used for both Kubernetes manifests and Docker Compose files.
 func imageStaticDiags(refs []core.DepRef) []diagnostic {
-	var diags []diagnostic
-	for _, ref := range refs {
-		if strings.HasPrefix(ref.Version, "sha256:") {
-			continue
-		}
-		tag := ref.Version
-		if tag == "" {
-			tag = "latest"
-		}
-		diags = append(diags, lineDiag(ref.Line, 2,
-			ref.Name+":"+tag+" is not pinned to an immutable digest (@sha256:...)"))
-	}
-	return diags
+	return findingDiags(core.ManifestFindings(core.ManifestKube, refs))
 }
 
 // terraformStaticDiags warns on providers/modules using version constraints
 // instead of exact pins.
 func terraformStaticDiags(refs []core.DepRef) []diagnostic {
-	var diags []diagnostic
-	for _, ref := range refs {
-		if ref.Version == "" {
-			if moduleRefSHARe.MatchString(ref.Name) {
-				// Already pinned to an immutable SHA (ghat writes the tag as a
-				// trailing comment, not a version attribute) — nothing to flag.
-				continue
-			}
-			diags = append(diags, lineDiag(ref.Line, 2,
-				ref.Name+" has no version constraint — run ghat to pin"))
-			continue
-		}
-		if hasVersionConstraintOperator(ref.Version) {
-			diags = append(diags, lineDiag(ref.Line, 2,
-				ref.Name+" uses version constraint "+ref.Version+" instead of an exact pin"))
-		}
-	}
-	return diags
-}
-
-func hasVersionConstraintOperator(v string) bool {
-	for _, op := range []string{"~>", ">=", "<=", ">", "<", "!="} {
-		if strings.Contains(v, op) {
-			return true
-		}
-	}
-	return false
+	return findingDiags(core.ManifestFindings(core.ManifestTerraform, refs))
 }
 
 // cpanfileStaticDiags warns on CPAN modules not pinned to an exact version with ==.
 func cpanfileStaticDiags(refs []core.DepRef) []diagnostic {
+	return findingDiags(core.ManifestFindings(core.ManifestCpanfile, refs))
+}
+
+// findingDiags converts core findings to diagnostics, dropping suppressed ones.
+func findingDiags(findings []core.Finding) []diagnostic {
 	var diags []diagnostic
-	for _, ref := range refs {
-		if strings.Contains(ref.Version, "==") {
+	for _, f := range findings {
+		if f.Suppressed {
 			continue
 		}
-		desc := ref.Name
-		if ref.Version != "" {
-			desc += " (" + ref.Version + ")"
+		sev := 2
+		switch f.Level {
+		case core.LevelError:
+			sev = 1
+		case core.LevelNote:
+			sev = 3
 		}
-		diags = append(diags, lineDiag(ref.Line, 2,
-			desc+" is not pinned to an exact version (use == <ver>)"))
+		diags = append(diags, ruleDiag(f.Line, sev, f.Rule, f.Message))
 	}
 	return diags
 }
@@ -485,9 +464,10 @@ func cpanfileStaticDiags(refs []core.DepRef) []diagnostic {
 func (s *Server) publishDiags(w io.Writer, uri string, staticDiags []diagnostic) error {
 	s.mu.Lock()
 	audit := s.auditDiags[uri]
+	inputs := s.inputDiags[uri]
 	s.mu.Unlock()
 
-	all := append(staticDiags, audit...) //nolint:gocritic
+	all := append(append(staticDiags, inputs...), audit...) //nolint:gocritic
 	if all == nil {
 		all = []diagnostic{}
 	}
@@ -555,7 +535,7 @@ func (s *Server) handleCodeAction(w io.Writer, msg rpcMsg) error {
 
 			// Pin to SHA — only for GHA and pre-commit where SHA pinning applies.
 			if isKnown && (kind == core.ManifestGHA || kind == core.ManifestPreCommit) &&
-				ref.Version != "" && !core.IsSHAPinnedRef(ref.Version) {
+				ref.Ecosystem != core.SourceImage && ref.Version != "" && !core.IsSHAPinnedRef(ref.Version) {
 				pinTitle := "Pin " + ref.Name + "@" + ref.Version + " to SHA"
 				actions = append(actions, codeAction{
 					Title: pinTitle,
@@ -571,7 +551,10 @@ func (s *Server) handleCodeAction(w io.Writer, msg rpcMsg) error {
 			// Update to latest / Pin to digest — for ecosystems we can resolve server-side.
 			if canUpdate(ref.Ecosystem) && ref.Version != "" {
 				switch ref.Ecosystem {
-				case core.SourceGitLab, core.SourceKube, core.SourceCompose, core.SourceDockerfile:
+				case core.SourceGitLab, core.SourceKube, core.SourceCompose, core.SourceDockerfile, core.SourceImage:
+					if strings.HasPrefix(ref.Version, "sha256:") {
+						break // already pinned to a digest
+					}
 					// Two actions for image refs: pin current tag, or fetch latest + pin.
 					pinTitle := "Pin " + ref.Name + ":" + ref.Version + " to digest"
 					actions = append(actions, codeAction{
@@ -662,6 +645,27 @@ func (s *Server) handleCodeAction(w io.Writer, msg rpcMsg) error {
 			})
 		}
 	}
+	// Fix script injection — move github.event expressions out of run: scripts.
+	if isKnown && kind == core.ManifestGHA && cursorLine >= 1 {
+		s.mu.Lock()
+		content := s.docs[p.TextDocument.URI]
+		s.mu.Unlock()
+		lines := strings.Split(string(content), "\n")
+		if cursorLine <= len(lines) && strings.Contains(lines[cursorLine-1], "github.event.") {
+			if fixed, _, err := core.FixInjection(content); err == nil && fixed != string(content) {
+				fixTitle := "Move github.event expressions in run: steps into env:"
+				actions = append(actions, codeAction{
+					Title: fixTitle,
+					Kind:  "source.ghat",
+					Command: &lspCommand{
+						Title:     fixTitle,
+						Command:   "ghat.fixInjection",
+						Arguments: []interface{}{p.TextDocument.URI},
+					},
+				})
+			}
+		}
+	}
 	if len(refs) > 0 {
 		actions = append(actions, codeAction{
 			Title: "Audit all dependencies in this file",
@@ -697,6 +701,8 @@ func (s *Server) handleExecuteCommand(w io.Writer, msg rpcMsg) error {
 		return s.execUpdate(w, msg.ID, p.Arguments)
 	case "ghat.suppress":
 		return s.execSuppress(w, msg.ID, p.Arguments)
+	case "ghat.fixInjection":
+		return s.execFixInjection(w, msg.ID, p.Arguments)
 	}
 	return writeResult(w, msg.ID, nil)
 }
@@ -842,12 +848,36 @@ func (s *Server) execSuppress(w io.Writer, id json.RawMessage, args json.RawMess
 	return applyEdit(w, uri, line-1, new)
 }
 
+// execFixInjection moves the github.event expressions interpolated into the
+// document's run: steps into env: entries, rewriting the whole document.
+func (s *Server) execFixInjection(w io.Writer, id json.RawMessage, args json.RawMessage) error {
+	var argv []interface{}
+	if err := json.Unmarshal(args, &argv); err != nil || len(argv) < 1 {
+		return writeResult(w, id, nil)
+	}
+	uri, _ := argv[0].(string)
+	_ = writeResult(w, id, nil)
+
+	s.mu.Lock()
+	content := s.docs[uri]
+	s.mu.Unlock()
+	if content == nil {
+		return nil
+	}
+	fixed, _, err := core.FixInjection(content)
+	if err != nil || fixed == string(content) {
+		return nil
+	}
+	lastLine := strings.Count(string(content), "\n")
+	return applyRangeEdit(w, uri, diagRange{End: diagPos{Line: lastLine, Character: 9999}}, fixed)
+}
+
 // canUpdate reports whether the LSP can resolve the latest version server-side.
 func canUpdate(eco string) bool {
 	switch eco {
 	case core.SourceGHA, core.SourcePreCommit, core.SourceTerraform,
 		core.SourceGitLab, core.SourceKube, core.SourceCompose,
-		core.SourceDockerfile, core.SourceGitLabComponent,
+		core.SourceDockerfile, core.SourceGitLabComponent, core.SourceImage,
 		core.SourceNpm, core.SourcePypi, core.SourceCargo, core.SourceGem, core.SourceGo,
 		core.SourceCpanfile:
 		return true
@@ -906,7 +936,7 @@ func (s *Server) execUpdate(w io.Writer, id json.RawMessage, args json.RawMessag
 			oldText = currentVersion
 			newText = latest
 
-		case core.SourceGitLab, core.SourceKube, core.SourceCompose, core.SourceDockerfile:
+		case core.SourceGitLab, core.SourceKube, core.SourceCompose, core.SourceDockerfile, core.SourceImage:
 			// 6th arg (optional): the tag to fetch. Defaults to currentVersion (pin as-is).
 			fetchTag := currentVersion
 			if len(argv) >= 6 {
@@ -1024,6 +1054,14 @@ func findVersionLine(lines []string, startIdx int, version string) int {
 
 // applyEdit sends a workspace/applyEdit request to replace a single line.
 func applyEdit(w io.Writer, uri string, zeroLine int, newText string) error {
+	return applyRangeEdit(w, uri, diagRange{
+		Start: diagPos{Line: zeroLine, Character: 0},
+		End:   diagPos{Line: zeroLine, Character: 9999},
+	}, newText)
+}
+
+// applyRangeEdit asks the client to replace a range of the document.
+func applyRangeEdit(w io.Writer, uri string, r diagRange, newText string) error {
 	return writeMessage(w, map[string]any{
 		"jsonrpc": "2.0",
 		"id":      99,
@@ -1033,10 +1071,7 @@ func applyEdit(w io.Writer, uri string, zeroLine int, newText string) error {
 				"changes": map[string]any{
 					uri: []map[string]any{
 						{
-							"range": diagRange{
-								Start: diagPos{Line: zeroLine, Character: 0},
-								End:   diagPos{Line: zeroLine, Character: 9999},
-							},
+							"range":   r,
 							"newText": newText,
 						},
 					},
@@ -1073,7 +1108,7 @@ func registryURL(eco, name string) string {
 		return "https://rubygems.org/gems/" + name
 	case core.SourcePreCommit:
 		return name // already a full URL
-	case core.SourceKube, core.SourceCompose:
+	case core.SourceKube, core.SourceCompose, core.SourceImage:
 		if !strings.Contains(name, ".") {
 			parts := strings.Split(name, "/")
 			if len(parts) == 1 {
@@ -1112,7 +1147,7 @@ func ecosystemLabel(eco string) string {
 		return "RubyGems"
 	case core.SourcePreCommit:
 		return "GitHub"
-	case core.SourceKube, core.SourceCompose:
+	case core.SourceKube, core.SourceCompose, core.SourceImage:
 		return "Docker Hub"
 	case core.SourceTerraform:
 		return "Terraform Registry"
@@ -1204,10 +1239,6 @@ func formatScore(name string, score core.AuditScore) string {
 	return b.String()
 }
 
-func fileDiag(sev int, msg string) diagnostic {
-	return lineDiag(1, sev, msg)
-}
-
 func lineDiag(line, sev int, msg string) diagnostic {
 	if line < 1 {
 		line = 1
@@ -1221,6 +1252,13 @@ func lineDiag(line, sev int, msg string) diagnostic {
 	}
 }
 
+// ruleDiag is lineDiag tagged with a ghat rule id.
+func ruleDiag(line, sev int, rule, msg string) diagnostic {
+	d := lineDiag(line, sev, msg)
+	d.Code = rule
+	return d
+}
+
 // findUsesLine returns the 1-indexed line number of the first uses: line that
 // contains the given action name.
 func findUsesLine(content []byte, action string) int {
@@ -1235,39 +1273,7 @@ func findUsesLine(content []byte, action string) int {
 // classifyURI maps a document URI to a ManifestKind, returning false for
 // files ghat does not handle.
 func classifyURI(uri string) (core.ManifestKind, bool) {
-	path := uriToPath(uri)
-	base := filepath.Base(path)
-	slashed := filepath.ToSlash(path)
-
-	switch {
-	case (strings.HasSuffix(base, ".yml") || strings.HasSuffix(base, ".yaml")) &&
-		strings.Contains(slashed, ".github/workflows/"):
-		return core.ManifestGHA, true
-	case base == "go.mod":
-		return core.ManifestGoMod, true
-	case base == "package.json":
-		return core.ManifestNPM, true
-	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
-		return core.ManifestPyPI, true
-	case base == "Cargo.toml":
-		return core.ManifestCargo, true
-	case base == "Gemfile":
-		return core.ManifestGem, true
-	case base == ".pre-commit-config.yaml" || base == ".pre-commit-config.yml":
-		return core.ManifestPreCommit, true
-	case base == "cpanfile":
-		return core.ManifestCpanfile, true
-	case base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile."):
-		return core.ManifestDockerfile, true
-	case base == ".gitlab-ci.yml" || strings.HasSuffix(base, ".gitlab-ci.yml"):
-		return core.ManifestGitLab, true
-	case base == "docker-compose.yml" || base == "docker-compose.yaml" ||
-		base == "compose.yml" || base == "compose.yaml":
-		return core.ManifestCompose, true
-	case strings.HasSuffix(base, ".tf"):
-		return core.ManifestTerraform, true
-	}
-	return 0, false
+	return core.ClassifyManifest(uriToPath(uri))
 }
 
 // uriToPath converts a file:// URI to a local filesystem path.
@@ -3,10 +3,17 @@ package lsp
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jameswoolfenden/ghat/src/core"
 )
@@ -124,7 +131,7 @@ func TestCanUpdate(t *testing.T) {
 	for _, eco := range []string{
 		core.SourceGHA, core.SourcePreCommit, core.SourceTerraform,
 		core.SourceGitLab, core.SourceKube, core.SourceCompose,
-		core.SourceDockerfile, core.SourceGitLabComponent,
+		core.SourceDockerfile, core.SourceGitLabComponent, core.SourceImage,
 		core.SourceNpm, core.SourcePypi, core.SourceCargo, core.SourceGem, core.SourceGo,
 		core.SourceCpanfile,
 	} {
@@ -162,6 +169,170 @@ jobs:
 	}
 }
 
+func TestGHAStaticDiagsEscalation(t *testing.T) {
+	content := "permissions: {}\non: workflow_run\njobs:\n  report:\n    runs-on: [self-hosted]\n    steps:\n" +
+		"      - uses: dawidd6/action-download-artifact@" + strings.Repeat("a", 40) + "\n"
+	var codes []string
+	for _, d := range ghaStaticDiags("report.yml", []byte(content)) {
+		codes = append(codes, fmt.Sprintf("%d %s", d.Range.Start.Line, d.Code))
+	}
+	want := []string{"4 ghat-self-hosted-runner", "6 ghat-artifact-poisoning"}
+	slices.Sort(codes)
+	if !slices.Equal(codes, want) {
+		t.Errorf("ghaStaticDiags() codes = %q, want %q", codes, want)
+	}
+}
+
+func TestActionInputDiags(t *testing.T) {
+	manifest := "inputs:\n  path:\n    required: false\nruns:\n  using: node16\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/org/act/contents/action.yml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"content":%q,"encoding":"base64"}`, base64.StdEncoding.EncodeToString([]byte(manifest)))
+	}))
+	defer srv.Close()
+	t.Cleanup(func() { core.SetGitHubAPIURL("") })
+	core.SetGitHubAPIURL(srv.URL)
+
+	content := "jobs:\n  a:\n    steps:\n      - uses: org/act@v1\n        with:\n          paths: dist\n"
+	var got []string
+	for _, d := range actionInputDiags(context.Background(), []byte(content), core.NewActionManifests("", nil)) {
+		got = append(got, fmt.Sprintf("%d %s", d.Range.Start.Line, d.Code))
+	}
+	want := []string{"3 ghat-retired-runtime", "5 ghat-action-inputs"}
+	if !slices.Equal(got, want) {
+		t.Errorf("actionInputDiags() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckInputs_Debounced(t *testing.T) {
+	var mu sync.Mutex
+	fetched := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path != "/repos/org/act/contents/action.yml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"content":%q,"encoding":"base64"}`, base64.StdEncoding.EncodeToString([]byte("runs:\n  using: node16\n")))
+	}))
+	defer srv.Close()
+	t.Cleanup(func() { core.SetGitHubAPIURL("") })
+	core.SetGitHubAPIURL(srv.URL)
+	defer func(d time.Duration) { inputDebounce = d }(inputDebounce)
+	inputDebounce = 50 * time.Millisecond
+
+	uri := "file:///repo/.github/workflows/ci.yml"
+	s := New("", nil)
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	var content []byte
+	for i := 0; i < 5; i++ {
+		content = []byte(fmt.Sprintf("jobs:\n  a:\n    steps:\n      - uses: org/step%d@v1\n", i))
+		s.mu.Lock()
+		s.docs[uri] = content
+		s.inputDiags[uri] = []diagnostic{lineDiag(0, 2, "stale")}
+		s.mu.Unlock()
+		s.checkInputs(w, uri, content)
+	}
+	content = append(content, "      - uses: org/act@v1\n"...)
+	s.mu.Lock()
+	s.docs[uri] = content
+	s.mu.Unlock()
+	s.checkInputs(w, uri, content)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		diags, pending := s.inputDiags[uri], s.inputRuns[uri] != nil
+		s.mu.Unlock()
+		if !pending {
+			if len(diags) != 1 || diags[0].Range.Start.Line != 4 {
+				t.Fatalf("inputDiags = %+v, want the retired runtime on line 4", diags)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("input check never finished")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	for i := 0; i < 4; i++ {
+		if n := fetched[fmt.Sprintf("/repos/org/step%d/contents/action.yml", i)]; n != 0 {
+			t.Errorf("superseded check fetched org/step%d %d times, want none", i, n)
+		}
+	}
+	before := fetched["/repos/org/step4/contents/action.yml"]
+	mu.Unlock()
+
+	// A failed lookup is remembered, so checking again does not refetch it.
+	if diags := actionInputDiags(context.Background(), content, s.manifests); len(diags) != 1 {
+		t.Errorf("actionInputDiags() = %+v, want the retired runtime", diags)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if after := fetched["/repos/org/step4/contents/action.yml"]; after != before {
+		t.Errorf("failed lookup refetched %d times, want none", after-before)
+	}
+}
+
+func TestCodeActionDockerStep(t *testing.T) {
+	uri := "file:///repo/.github/workflows/ci.yml"
+	content := "permissions: read-all\njobs:\n  build:\n    steps:\n      - uses: docker://alpine:3.20\n"
+	s := New("", nil)
+	s.deps[uri] = core.ParseManifest(core.ManifestGHA, []byte(content))
+
+	if diags := ghaStaticDiags("ci.yml", []byte(content)); len(diags) != 1 || !strings.Contains(diags[0].Message, "docker://alpine:3.20") {
+		t.Errorf("ghaStaticDiags() = %+v, want the unpinned docker:// step", diags)
+	}
+
+	resp := roundTrip(t, s, "textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"range":        map[string]interface{}{"start": map[string]int{"line": 4, "character": 0}, "end": map[string]int{"line": 4, "character": 0}},
+	})
+	result, _ := resp["result"].([]interface{})
+	var titles []string
+	for _, a := range result {
+		if m, ok := a.(map[string]interface{}); ok {
+			titles = append(titles, m["title"].(string))
+		}
+	}
+	if !slices.Contains(titles, "Pin alpine:3.20 to digest") || slices.Contains(titles, "Pin alpine@3.20 to SHA") {
+		t.Errorf("code actions = %q, want a digest pin and no SHA pin", titles)
+	}
+}
+
+func TestCodeActionFixInjection(t *testing.T) {
+	uri := "file:///repo/.github/workflows/ci.yml"
+	content := "jobs:\n  a:\n    steps:\n      - run: echo ${{ github.event.issue.title }}\n"
+	s := New("", nil)
+	s.docs[uri] = []byte(content)
+
+	resp := roundTrip(t, s, "textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"range":        map[string]interface{}{"start": map[string]int{"line": 3, "character": 0}, "end": map[string]int{"line": 3, "character": 0}},
+	})
+	result, _ := resp["result"].([]interface{})
+	if len(result) != 1 || result[0].(map[string]interface{})["title"] != "Move github.event expressions in run: steps into env:" {
+		t.Fatalf("code actions = %+v, want the injection fix", result)
+	}
+
+	var out bytes.Buffer
+	if err := s.execFixInjection(&out, json.RawMessage("1"), json.RawMessage(`["`+uri+`"]`)); err != nil {
+		t.Fatal(err)
+	}
+	want := `"newText":"jobs:\n  a:\n    steps:\n      - run: echo \"$ISSUE_TITLE\"\n        env:\n          ISSUE_TITLE: ${{ github.event.issue.title }}\n"`
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("execFixInjection() wrote %s, want an edit with %s", out.String(), want)
+	}
+}
+
 func TestTerraformStaticDiagsSHAPinnedModule(t *testing.T) {
 	refs := []core.DepRef{
 		{